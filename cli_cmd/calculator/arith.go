@@ -0,0 +1,298 @@
+package calculator
+
+import (
+	"math"
+	"math/big"
+)
+
+// maxPowerBits 精确计算整数次幂时结果分子或分母允许的最大位数
+const maxPowerBits = 1 << 20
+
+// maxShift 高精度模式下允许的最大移位位数
+const maxShift = 1 << 14
+
+// arith 计算非短路的二元运算，操作数会先提升为同一种类型
+func arith(op TokenType, x, y Value, prec uint) (Value, error) {
+	switch op {
+	case EQ, NEQ, LT, LE, GT, GE:
+		return compareOp(op, x, y, prec)
+	case BITAND, BITOR, BITXOR, SHL, SHR:
+		return bitwiseValue(op, x, y, prec)
+	case POWER:
+		return power(x, y, prec)
+	}
+
+	x, y, err := promote(x, y, prec)
+	if err != nil {
+		return nil, err
+	}
+	switch a := x.(type) {
+	case BigFloat:
+		return bigArith(op, a, y.(BigFloat), prec)
+	case Rat:
+		return ratArith(op, a, y.(Rat))
+	}
+	f, err := binary(op, x.Float64(), y.Float64())
+	if err != nil {
+		return nil, err
+	}
+	return Float(f), nil
+}
+
+// compareOp 计算比较运算，结果为 1 或 0
+func compareOp(op TokenType, x, y Value, prec uint) (Value, error) {
+	c, err := compare(x, y, prec)
+	if err != nil {
+		return nil, err
+	}
+	var b bool
+	switch op {
+	case EQ:
+		b = c == 0
+	case NEQ:
+		b = c != 0
+	case LT:
+		b = c < 0
+	case LE:
+		b = c <= 0
+	case GT:
+		b = c > 0
+	case GE:
+		b = c >= 0
+	}
+	like := x
+	if y.rank() > x.rank() {
+		like = y
+	}
+	return boolValue(b, like, prec), nil
+}
+
+// bigArith 计算 big.Float 的四则运算和取模
+func bigArith(op TokenType, x, y BigFloat, prec uint) (Value, error) {
+	prec = resultPrec(prec, x, y)
+	z := new(big.Float).SetPrec(prec)
+	switch op {
+	case PLUS:
+		return BigFloat{z.Add(x.Float, y.Float)}, nil
+	case MINUS:
+		return BigFloat{z.Sub(x.Float, y.Float)}, nil
+	case MULTIPLY:
+		return BigFloat{z.Mul(x.Float, y.Float)}, nil
+	case DIVIDE:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return BigFloat{z.Quo(x.Float, y.Float)}, nil
+	case MODULO:
+		// big.Float 的值都是精确的二进制小数，转换为有理数取模不会损失精度
+		a, _ := toRat(x)
+		b, _ := toRat(y)
+		r, err := ratArith(MODULO, a, b)
+		if err != nil {
+			return nil, err
+		}
+		return toBigFloat(r, prec)
+	}
+	return nil, errorf("internal.binary_operator", operatorSymbol(op))
+}
+
+// ratArith 计算 big.Rat 的四则运算和取模
+func ratArith(op TokenType, x, y Rat) (Value, error) {
+	z := new(big.Rat)
+	switch op {
+	case PLUS:
+		return Rat{z.Add(x.Rat, y.Rat)}, nil
+	case MINUS:
+		return Rat{z.Sub(x.Rat, y.Rat)}, nil
+	case MULTIPLY:
+		return Rat{z.Mul(x.Rat, y.Rat)}, nil
+	case DIVIDE:
+		if y.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return Rat{z.Quo(x.Rat, y.Rat)}, nil
+	case MODULO:
+		if y.Sign() == 0 {
+			return nil, errorf("division_by_zero.modulo")
+		}
+		// x - y*trunc(x/y)，结果与被除数同号，与 math.Mod 一致
+		q := z.Quo(x.Rat, y.Rat)
+		t := new(big.Int).Quo(q.Num(), q.Denom())
+		q.SetInt(t)
+		q.Mul(q, y.Rat)
+		return Rat{q.Sub(x.Rat, q)}, nil
+	}
+	return nil, errorf("internal.binary_operator", operatorSymbol(op))
+}
+
+// power 计算幂运算，高精度模式下整数指数精确计算
+func power(x, y Value, prec uint) (Value, error) {
+	x, y, err := promote(x, y, prec)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := x.(Float); ok {
+		f, err := binary(POWER, x.Float64(), y.Float64())
+		if err != nil {
+			return nil, err
+		}
+		return Float(f), nil
+	}
+
+	if n, ok := toBigInt(y); ok {
+		return intPower(x, n, prec)
+	}
+
+	// 非整数指数退回 float64 计算
+	f, err := binary(POWER, x.Float64(), y.Float64())
+	if err != nil {
+		return nil, err
+	}
+	if math.IsInf(f, 0) {
+		return nil, errorf("out_of_range.power")
+	}
+	if _, ok := x.(Rat); ok {
+		return floatToRat(f)
+	}
+	return NewBigFloat(f, min(prec, float64Prec)), nil
+}
+
+// intPower 精确计算整数次幂，结果超过 maxPowerBits 或指数超出 int64 时返回 ErrOutOfRange
+func intPower(x Value, n *big.Int, prec uint) (Value, error) {
+	switch s := sign(x); {
+	case s == 0 && n.Sign() < 0:
+		return nil, ErrDivisionByZero
+	case s == 0 && n.Sign() > 0:
+		return x, nil
+	case n.Sign() == 0:
+		return sameKindInt(x, 1, prec), nil
+	}
+	if isUnit(x) {
+		// ±1 的任意次幂只取决于指数的奇偶
+		if sign(x) < 0 && n.Bit(0) == 1 {
+			return x, nil
+		}
+		return sameKindInt(x, 1, prec), nil
+	}
+	if !n.IsInt64() {
+		return nil, errorf("out_of_range.power")
+	}
+	e := n.Int64()
+	neg := e < 0
+	if neg {
+		e = -e
+	}
+
+	switch b := x.(type) {
+	case Rat:
+		bits := max(b.Num().BitLen(), b.Denom().BitLen())
+		if e > maxPowerBits/int64(bits) {
+			return nil, errorf("out_of_range.power")
+		}
+		exp := big.NewInt(e)
+		num := new(big.Int).Exp(b.Num(), exp, nil)
+		den := new(big.Int).Exp(b.Denom(), exp, nil)
+		if neg {
+			num, den = den, num
+		}
+		return Rat{new(big.Rat).SetFrac(num, den)}, nil
+	case BigFloat:
+		prec = resultPrec(prec, b)
+		result := new(big.Float).SetPrec(prec).SetInt64(1)
+		base := new(big.Float).SetPrec(prec).Set(b.Float)
+		for ; e > 0; e >>= 1 {
+			if e&1 == 1 {
+				result.Mul(result, base)
+			}
+			base.Mul(base, base)
+		}
+		if neg {
+			result.Quo(new(big.Float).SetPrec(prec).SetInt64(1), result)
+		}
+		if result.IsInf() {
+			return nil, errorf("out_of_range.power")
+		}
+		return BigFloat{result}, nil
+	}
+	return nil, errorf("internal.power", x)
+}
+
+// isUnit 判断高精度数值是否为 1 或 -1
+func isUnit(x Value) bool {
+	switch v := x.(type) {
+	case Rat:
+		return v.IsInt() && v.Num().CmpAbs(big.NewInt(1)) == 0
+	case BigFloat:
+		return new(big.Float).Abs(v.Float).Cmp(big.NewFloat(1)) == 0
+	}
+	return false
+}
+
+// sameKindInt 构造与 x 同一表示的整数
+func sameKindInt(x Value, n int64, prec uint) Value {
+	if _, ok := x.(Rat); ok {
+		return Rat{new(big.Rat).SetInt64(n)}
+	}
+	return BigFloat{new(big.Float).SetPrec(prec).SetInt64(n)}
+}
+
+// bitwiseValue 计算位运算，float64 按 int64 计算，高精度模式按 big.Int 计算
+func bitwiseValue(op TokenType, x, y Value, prec uint) (Value, error) {
+	x, y, err := promote(x, y, prec)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := x.(Float); ok {
+		f, err := bitwise(op, x.Float64(), y.Float64())
+		if err != nil {
+			return nil, err
+		}
+		return Float(f), nil
+	}
+
+	a, ok := toBigInt(x)
+	if !ok {
+		return nil, errorf("not_integer.bitwise", operatorSymbol(op), FormatValue(x))
+	}
+	b, ok := toBigInt(y)
+	if !ok {
+		return nil, errorf("not_integer.bitwise", operatorSymbol(op), FormatValue(y))
+	}
+
+	z := new(big.Int)
+	switch op {
+	case BITAND:
+		z.And(a, b)
+	case BITOR:
+		z.Or(a, b)
+	case BITXOR:
+		z.Xor(a, b)
+	case SHL, SHR:
+		if b.Sign() < 0 || b.Cmp(big.NewInt(maxShift)) > 0 {
+			return nil, errorf("invalid_argument.shift", maxShift, b)
+		}
+		if op == SHL {
+			z.Lsh(a, uint(b.Int64()))
+		} else {
+			z.Rsh(a, uint(b.Int64()))
+		}
+	}
+
+	if _, ok := x.(Rat); ok {
+		return Rat{new(big.Rat).SetInt(z)}, nil
+	}
+	return BigFloat{new(big.Float).SetPrec(prec).SetInt(z)}, nil
+}
+
+// negate 计算相反数
+func negate(v Value) Value {
+	switch x := v.(type) {
+	case Quantity:
+		return Quantity{Amount: negate(x.Amount), Unit: x.Unit}
+	case BigFloat:
+		return BigFloat{new(big.Float).SetPrec(x.Prec()).Neg(x.Float)}
+	case Rat:
+		return Rat{new(big.Rat).Neg(x.Rat)}
+	}
+	return Float(-v.Float64())
+}
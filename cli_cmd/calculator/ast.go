@@ -0,0 +1,341 @@
+package calculator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Node 表示语法树中的一个节点
+type Node interface {
+	// Pos 返回节点在源表达式中的起始偏移（字节）
+	Pos() int
+	// End 返回节点在源表达式中结束位置之后的偏移（字节）
+	End() int
+	// String 按源码形式输出节点
+	String() string
+}
+
+// NumberLit 数字字面量
+type NumberLit struct {
+	ValuePos int     // 字面量起始位置
+	Literal  string  // 源码中的原始文本
+	Value    float64 // 解析后的数值
+}
+
+// UnitLit 带单位的字面量，例如 1.5ether、30 gwei、5 km、60 km/h
+type UnitLit struct {
+	Value   *NumberLit // 数值部分
+	UnitPos int        // 单位位置
+	Unit    string     // 单位的原始文本，可以是 km/h 这样的复合单位
+}
+
+// Ident 标识符（变量名）
+type Ident struct {
+	NamePos int    // 标识符位置
+	Name    string // 名称
+}
+
+// UnaryExpr 一元表达式，例如 -x、+x
+type UnaryExpr struct {
+	OpPos int       // 运算符位置
+	Op    TokenType // 运算符
+	X     Node      // 操作数
+}
+
+// BinaryExpr 二元表达式，例如 x+y
+type BinaryExpr struct {
+	X     Node      // 左操作数
+	OpPos int       // 运算符位置
+	Op    TokenType // 运算符
+	Y     Node      // 右操作数
+}
+
+// ParenExpr 括号表达式
+type ParenExpr struct {
+	Lparen int  // "(" 的位置
+	X      Node // 括号内的表达式
+	Rparen int  // ")" 的位置
+}
+
+// TernaryExpr 条件表达式 cond ? a : b
+type TernaryExpr struct {
+	Cond     Node // 条件
+	Question int  // "?" 的位置
+	Then     Node // 条件非零时的值
+	Colon    int  // ":" 的位置
+	Else     Node // 条件为零时的值
+}
+
+// ConvertExpr 单位换算，例如 2 ether in gwei、5 km/h in m/s
+type ConvertExpr struct {
+	X     Node   // 被换算的数量；目标为以太坊单位时是以 wei 计的金额
+	InPos int    // "in" 的位置
+	Unit  *Ident // 目标单位，Name 是单位的原始文本，可以是 m/s 这样的复合单位
+}
+
+// CallExpr 函数调用，例如 max(1, 2, 3)
+type CallExpr struct {
+	Fun    *Ident // 函数名
+	Lparen int    // "(" 的位置
+	Args   []Node // 参数列表
+	Rparen int    // ")" 的位置
+}
+
+// ListExpr 列表字面量，例如 [1, 2, 3]
+type ListExpr struct {
+	Lbrack int    // "[" 的位置
+	Elems  []Node // 元素
+	Rbrack int    // "]" 的位置
+}
+
+// Lambda 匿名函数，例如 x => x * 2，只能作为 map、filter 的参数
+type Lambda struct {
+	Param *Ident // 参数
+	Arrow int    // "=>" 的位置
+	Body  Node   // 函数体
+}
+
+// AssignExpr 赋值语句，例如 x = 3*4
+type AssignExpr struct {
+	Name  *Ident // 被赋值的变量
+	EqPos int    // "=" 的位置
+	Value Node   // 右侧表达式
+}
+
+// FuncDef 函数定义语句，例如 f(x, y) = x^2 + y
+type FuncDef struct {
+	Name   *Ident   // 函数名
+	Lparen int      // "(" 的位置
+	Params []*Ident // 参数列表
+	Rparen int      // ")" 的位置
+	EqPos  int      // "=" 的位置
+	Body   Node     // 函数体
+}
+
+func (n *NumberLit) Pos() int   { return n.ValuePos }
+func (n *UnitLit) Pos() int     { return n.Value.Pos() }
+func (n *Ident) Pos() int       { return n.NamePos }
+func (n *UnaryExpr) Pos() int   { return n.OpPos }
+func (n *BinaryExpr) Pos() int  { return n.X.Pos() }
+func (n *ParenExpr) Pos() int   { return n.Lparen }
+func (n *TernaryExpr) Pos() int { return n.Cond.Pos() }
+func (n *ConvertExpr) Pos() int { return n.X.Pos() }
+func (n *CallExpr) Pos() int    { return n.Fun.Pos() }
+func (n *ListExpr) Pos() int    { return n.Lbrack }
+func (n *Lambda) Pos() int      { return n.Param.Pos() }
+func (n *AssignExpr) Pos() int  { return n.Name.Pos() }
+func (n *FuncDef) Pos() int     { return n.Name.Pos() }
+
+func (n *NumberLit) End() int   { return n.ValuePos + len(n.Literal) }
+func (n *UnitLit) End() int     { return n.UnitPos + len(n.Unit) }
+func (n *Ident) End() int       { return n.NamePos + len(n.Name) }
+func (n *UnaryExpr) End() int   { return n.X.End() }
+func (n *BinaryExpr) End() int  { return n.Y.End() }
+func (n *ParenExpr) End() int   { return n.Rparen + 1 }
+func (n *TernaryExpr) End() int { return n.Else.End() }
+func (n *ConvertExpr) End() int { return n.Unit.End() }
+func (n *CallExpr) End() int    { return n.Rparen + 1 }
+func (n *ListExpr) End() int    { return n.Rbrack + 1 }
+func (n *Lambda) End() int      { return n.Body.End() }
+func (n *AssignExpr) End() int  { return n.Value.End() }
+func (n *FuncDef) End() int     { return n.Body.End() }
+
+func (n *NumberLit) String() string { return n.Literal }
+
+func (n *UnitLit) String() string { return n.Value.String() + " " + n.Unit }
+
+func (n *Ident) String() string { return n.Name }
+
+func (n *TernaryExpr) String() string {
+	return n.Cond.String() + " ? " + n.Then.String() + " : " + n.Else.String()
+}
+
+func (n *ConvertExpr) String() string {
+	return n.X.String() + " in " + n.Unit.String()
+}
+
+func (n *CallExpr) String() string {
+	args := make([]string, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = arg.String()
+	}
+	return n.Fun.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+func (n *ListExpr) String() string {
+	elems := make([]string, len(n.Elems))
+	for i, elem := range n.Elems {
+		elems[i] = elem.String()
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (n *Lambda) String() string {
+	return n.Param.String() + " => " + n.Body.String()
+}
+
+func (n *AssignExpr) String() string {
+	return n.Name.String() + " = " + n.Value.String()
+}
+
+func (n *FuncDef) String() string {
+	params := make([]string, len(n.Params))
+	for i, param := range n.Params {
+		params[i] = param.String()
+	}
+	return n.Name.String() + "(" + strings.Join(params, ", ") + ") = " + n.Body.String()
+}
+
+func (n *UnaryExpr) String() string {
+	return operatorSymbol(n.Op) + n.X.String()
+}
+
+func (n *BinaryExpr) String() string {
+	return n.X.String() + " " + operatorSymbol(n.Op) + " " + n.Y.String()
+}
+
+func (n *ParenExpr) String() string {
+	return "(" + n.X.String() + ")"
+}
+
+// opSymbols 运算符标记的源码形式
+var opSymbols = map[TokenType]string{
+	PLUS:     "+",
+	MINUS:    "-",
+	MULTIPLY: "*",
+	DIVIDE:   "/",
+	MODULO:   "%",
+	POWER:    "^",
+	BITAND:   "&",
+	BITOR:    "|",
+	BITXOR:   "xor",
+	SHL:      "<<",
+	SHR:      ">>",
+	EQ:       "==",
+	NEQ:      "!=",
+	LT:       "<",
+	LE:       "<=",
+	GT:       ">",
+	GE:       ">=",
+	AND:      "&&",
+	OR:       "||",
+	NOT:      "!",
+	DOTDOT:   "..",
+	ASSIGN:   "=",
+}
+
+// operatorSymbol 返回运算符标记的源码形式
+func operatorSymbol(t TokenType) string {
+	if s, ok := opSymbols[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("<op %d>", t)
+}
+
+// Walk 以深度优先顺序遍历语法树，fn 返回 false 时不再进入该节点的子节点
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	switch n := node.(type) {
+	case *UnaryExpr:
+		Walk(n.X, fn)
+	case *BinaryExpr:
+		Walk(n.X, fn)
+		Walk(n.Y, fn)
+	case *ParenExpr:
+		Walk(n.X, fn)
+	case *UnitLit:
+		Walk(n.Value, fn)
+	case *ConvertExpr:
+		Walk(n.X, fn)
+		Walk(n.Unit, fn)
+	case *TernaryExpr:
+		Walk(n.Cond, fn)
+		Walk(n.Then, fn)
+		Walk(n.Else, fn)
+	case *CallExpr:
+		Walk(n.Fun, fn)
+		for _, arg := range n.Args {
+			Walk(arg, fn)
+		}
+	case *ListExpr:
+		for _, elem := range n.Elems {
+			Walk(elem, fn)
+		}
+	case *Lambda:
+		Walk(n.Param, fn)
+		Walk(n.Body, fn)
+	case *AssignExpr:
+		Walk(n.Name, fn)
+		Walk(n.Value, fn)
+	case *FuncDef:
+		Walk(n.Name, fn)
+		for _, param := range n.Params {
+			Walk(param, fn)
+		}
+		Walk(n.Body, fn)
+	}
+}
+
+// Dump 以缩进形式输出语法树结构，便于调试
+func Dump(node Node) string {
+	var sb strings.Builder
+	dump(&sb, node, 0)
+	return sb.String()
+}
+
+func dump(sb *strings.Builder, node Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch n := node.(type) {
+	case *NumberLit:
+		fmt.Fprintf(sb, "%sNumber %s @%d\n", indent, n.Literal, n.ValuePos)
+	case *UnitLit:
+		fmt.Fprintf(sb, "%sUnit %s %s @%d\n", indent, n.Value.Literal, n.Unit, n.Value.ValuePos)
+	case *ConvertExpr:
+		fmt.Fprintf(sb, "%sConvert in %s @%d\n", indent, n.Unit.Name, n.InPos)
+		dump(sb, n.X, depth+1)
+	case *Ident:
+		fmt.Fprintf(sb, "%sIdent %s @%d\n", indent, n.Name, n.NamePos)
+	case *TernaryExpr:
+		fmt.Fprintf(sb, "%sTernary @%d\n", indent, n.Question)
+		dump(sb, n.Cond, depth+1)
+		dump(sb, n.Then, depth+1)
+		dump(sb, n.Else, depth+1)
+	case *CallExpr:
+		fmt.Fprintf(sb, "%sCall %s @%d\n", indent, n.Fun.Name, n.Fun.NamePos)
+		for _, arg := range n.Args {
+			dump(sb, arg, depth+1)
+		}
+	case *ListExpr:
+		fmt.Fprintf(sb, "%sList @%d\n", indent, n.Lbrack)
+		for _, elem := range n.Elems {
+			dump(sb, elem, depth+1)
+		}
+	case *Lambda:
+		fmt.Fprintf(sb, "%sLambda %s @%d\n", indent, n.Param.Name, n.Arrow)
+		dump(sb, n.Body, depth+1)
+	case *AssignExpr:
+		fmt.Fprintf(sb, "%sAssign %s @%d\n", indent, n.Name.Name, n.EqPos)
+		dump(sb, n.Value, depth+1)
+	case *FuncDef:
+		params := make([]string, len(n.Params))
+		for i, param := range n.Params {
+			params[i] = param.Name
+		}
+		fmt.Fprintf(sb, "%sFunc %s(%s) @%d\n", indent, n.Name.Name, strings.Join(params, ", "), n.EqPos)
+		dump(sb, n.Body, depth+1)
+	case *UnaryExpr:
+		fmt.Fprintf(sb, "%sUnary %s @%d\n", indent, operatorSymbol(n.Op), n.OpPos)
+		dump(sb, n.X, depth+1)
+	case *BinaryExpr:
+		fmt.Fprintf(sb, "%sBinary %s @%d\n", indent, operatorSymbol(n.Op), n.OpPos)
+		dump(sb, n.X, depth+1)
+		dump(sb, n.Y, depth+1)
+	case *ParenExpr:
+		fmt.Fprintf(sb, "%sParen @%d\n", indent, n.Lparen)
+		dump(sb, n.X, depth+1)
+	default:
+		fmt.Fprintf(sb, "%s%T\n", indent, node)
+	}
+}
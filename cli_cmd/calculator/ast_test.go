@@ -0,0 +1,135 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseTree 测试语法树结构
+func TestParseTree(t *testing.T) {
+	node, err := Parse("1 + 2 * 3")
+	require.NoError(t, err)
+
+	bin, ok := node.(*BinaryExpr)
+	require.True(t, ok, "根节点应该是二元表达式")
+	assert.Equal(t, PLUS, bin.Op)
+	assert.Equal(t, 2, bin.OpPos)
+
+	right, ok := bin.Y.(*BinaryExpr)
+	require.True(t, ok, "右侧应该是乘法表达式")
+	assert.Equal(t, MULTIPLY, right.Op)
+	assert.Equal(t, 6, right.OpPos)
+
+	lit, ok := right.Y.(*NumberLit)
+	require.True(t, ok)
+	assert.Equal(t, 8, lit.Pos())
+	assert.Equal(t, 9, lit.End())
+	assert.Equal(t, 3.0, lit.Value)
+}
+
+// TestParsePositions 测试节点位置
+func TestParsePositions(t *testing.T) {
+	tests := []struct {
+		expression string
+		pos, end   int
+		desc       string
+	}{
+		{"42", 0, 2, "数字"},
+		{"  -7", 2, 4, "一元表达式"},
+		{"(1+2)", 0, 5, "括号"},
+		{"10 - 3.5", 0, 8, "二元表达式"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.pos, node.Pos())
+			assert.Equal(t, test.end, node.End())
+		})
+	}
+}
+
+// TestNodeString 测试语法树输出
+func TestNodeString(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"1+2*3", "1 + 2 * 3"},
+		{"(1+2)*3", "(1 + 2) * 3"},
+		{"-5+ +3", "-5 + +3"},
+		{"10%3", "10 % 3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, node.String())
+
+			// 输出的文本重新解析后结果不变
+			again, err := Parse(node.String())
+			require.NoError(t, err)
+			want, err := Eval(node)
+			require.NoError(t, err)
+			got, err := Eval(again)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+// TestWalk 测试语法树遍历
+func TestWalk(t *testing.T) {
+	node, err := Parse("(1+2)*-3")
+	require.NoError(t, err)
+
+	var numbers []string
+	Walk(node, func(n Node) bool {
+		if lit, ok := n.(*NumberLit); ok {
+			numbers = append(numbers, lit.Literal)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"1", "2", "3"}, numbers)
+}
+
+// TestEvalReuse 测试同一棵语法树可以重复求值
+func TestEvalReuse(t *testing.T) {
+	node, err := Parse("((1+2)*3+4)/5")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		result, err := Eval(node)
+		require.NoError(t, err)
+		assert.Equal(t, 2.6, result)
+	}
+}
+
+// TestModuloFractionalDivisor 测试小数除数按 math.Mod 取模，结果与被除数同号
+func TestModuloFractionalDivisor(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+		desc       string
+	}{
+		{"1%0.5", 0, "整除的小数除数"},
+		{"5.5%2.5", 0.5, "小数除数"},
+		{"-7.5%2", -1.5, "负的被除数"},
+		{"7.5%-2", 1.5, "负的除数"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result, err := Calculate(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	_, err := Calculate("1%0")
+	assert.Error(t, err, "除数为零")
+}
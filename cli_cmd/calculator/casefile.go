@@ -0,0 +1,277 @@
+package calculator
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cli_cmd/i18n"
+)
+
+// CaseExt 用例文件的扩展名
+const CaseExt = ".calc"
+
+// CaseFile 用例文件，其中的语句在同一个环境中依次执行：
+//
+//	# 注释
+//	@mode rat          文件开头的设置：计算模式（默认 float）、big 模式的精度和错误信息的语言（默认 zh）
+//	x = 3
+//	x * 2
+//	=> 6               上一条语句的期望结果
+//	1 / 0
+//	!! eval: 除零      上一条语句的期望错误：错误代码和错误信息中包含的文字
+//	!! division_by_zero  错误代码也可以是 ErrorID 返回的错误标识，与输出语言无关
+//
+// 没有期望的语句只要求执行成功，期望和设置行也可以带 # 注释。结果与命令行的 --mode、--lang 等设置无关。
+type CaseFile struct {
+	Name      string
+	Mode      Mode
+	Precision uint
+	Lang      i18n.Lang // 与期望的错误信息比较时使用的语言，由 @lang 设置
+	Cases     []Case
+}
+
+// Case 用例文件中的一条语句及其期望
+type Case struct {
+	Line    int    // 语句所在的行号
+	Text    string // 语句原文
+	Expect  bool   // 是否有期望，没有期望时只要求执行成功
+	Want    string // 期望的结果，按 FormatValue 输出；函数定义为函数的签名
+	WantErr bool   // 期望出错
+	Code    string // 期望的错误代码，见 ErrorCode 和 ErrorID
+	Msg     string // 错误信息中应包含的文字，为空时只比较错误代码
+}
+
+// Expected 按用例文件的写法返回期望
+func (c Case) Expected() string {
+	switch {
+	case !c.Expect:
+		return "=> " + messages.Sprintf(i18n.Current(), "case.any_result")
+	case c.WantErr && c.Msg != "":
+		return "!! " + c.Code + ": " + c.Msg
+	case c.WantErr:
+		return "!! " + c.Code
+	}
+	return "=> " + c.Want
+}
+
+// ParseCaseFile 解析用例文件
+func ParseCaseFile(name string, r io.Reader) (*CaseFile, error) {
+	f := &CaseFile{Name: name, Mode: ModeFloat, Precision: DefaultPrecision, Lang: i18n.Default}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		// 期望和设置行的 # 之后是注释，语句中的注释由解析器处理
+		trimmed, _, _ := strings.Cut(strings.TrimSpace(text), "#")
+		trimmed = strings.TrimSpace(trimmed)
+		var err error
+		switch {
+		case IsBlank(text):
+		case strings.HasPrefix(trimmed, "=>"):
+			err = f.expect(strings.TrimSpace(trimmed[2:]), false)
+		case strings.HasPrefix(trimmed, "!!"):
+			err = f.expect(strings.TrimSpace(trimmed[2:]), true)
+		case strings.HasPrefix(trimmed, "@"):
+			err = f.setting(trimmed[1:])
+		default:
+			f.Cases = append(f.Cases, Case{Line: line, Text: text})
+		}
+		if err != nil {
+			return nil, errorf("case_file.position", name, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errorf("read.case_file", name, err)
+	}
+	return f, nil
+}
+
+// expect 记录上一条语句的期望结果或期望错误
+func (f *CaseFile) expect(text string, isErr bool) error {
+	if len(f.Cases) == 0 {
+		return errorf("case_file.no_statement")
+	}
+	c := &f.Cases[len(f.Cases)-1]
+	if c.Expect {
+		return errorf("case_file.duplicate", c.Line)
+	}
+	c.Expect, c.WantErr = true, isErr
+	if !isErr {
+		if text == "" {
+			return errorf("case_file.missing_result")
+		}
+		c.Want = text
+		return nil
+	}
+
+	code, msg, _ := strings.Cut(text, ":")
+	c.Code, c.Msg = strings.TrimSpace(code), strings.TrimSpace(msg)
+	switch {
+	case c.Code == "":
+		return errorf("case_file.missing_code")
+	case isCategory(c.Code), isErrorID(c.Code):
+		return nil
+	}
+	return errorf("case_file.unknown_code", c.Code)
+}
+
+// isCategory 判断 code 是否为 ErrorCode 返回的错误代码
+func isCategory(code string) bool {
+	switch code {
+	case CodeSyntax, CodeEval, CodeType, CodeLimit, CodeTimeout:
+		return true
+	}
+	return false
+}
+
+// setting 解析文件开头的 @mode、@precision 和 @lang 设置
+func (f *CaseFile) setting(text string) error {
+	if len(f.Cases) > 0 {
+		return errorf("case_file.setting_position")
+	}
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return errorf("case_file.setting_format", text)
+	}
+	switch fields[0] {
+	case "mode":
+		mode, err := ParseMode(fields[1])
+		if err != nil {
+			return err
+		}
+		f.Mode = mode
+	case "precision":
+		prec, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil || prec == 0 || uint(prec) > MaxPrecision {
+			return errorf("case_file.precision", fields[1])
+		}
+		f.Precision = uint(prec)
+	case "lang":
+		lang, ok := i18n.Parse(fields[1])
+		if !ok {
+			return errorf("case_file.lang", fields[1])
+		}
+		f.Lang = lang
+	default:
+		return errorf("case_file.setting", fields[0])
+	}
+	return nil
+}
+
+// LoadCaseFiles 按路径顺序加载 fsys 中 dir 目录及其子目录下的所有用例文件，文件名相对于 dir
+func LoadCaseFiles(fsys fs.FS, dir string) ([]*CaseFile, error) {
+	var files []*CaseFile
+	err := fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != CaseExt {
+			return err
+		}
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		f, err := ParseCaseFile(strings.TrimPrefix(name, dir+"/"), file)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// CaseReport 一个用例文件的执行结果
+type CaseReport struct {
+	File     *CaseFile
+	Failures []CaseFailure
+	Elapsed  time.Duration
+}
+
+// Passed 返回文件中的用例是否全部通过
+func (r CaseReport) Passed() bool { return len(r.Failures) == 0 }
+
+// CaseFailure 未通过的用例
+type CaseFailure struct {
+	Case
+	Got string // 实际结果，写法与 Case.Expected 相同
+}
+
+// Run 在使用注册表 r 的新环境中依次执行文件中的语句并与期望比较
+func (f *CaseFile) Run(r *Registry) CaseReport {
+	start := time.Now()
+	report := CaseReport{File: f}
+	env := NewEnvWithRegistry(r)
+	if err := env.SetPrecision(f.Precision); err != nil {
+		return f.failSetting(report, fmt.Sprintf("@precision %d", f.Precision), err)
+	}
+	if err := env.SetMode(f.Mode); err != nil {
+		return f.failSetting(report, "@mode "+f.Mode.String(), err)
+	}
+	for _, c := range f.Cases {
+		if got, ok := c.run(env, f.Lang); !ok {
+			report.Failures = append(report.Failures, CaseFailure{Case: c, Got: got})
+		}
+	}
+	report.Elapsed = time.Since(start)
+	return report
+}
+
+// failSetting 文件的设置无效时，整个文件记为一个未通过的用例
+func (f *CaseFile) failSetting(report CaseReport, setting string, err error) CaseReport {
+	failure := CaseFailure{Case: Case{Text: setting, Expect: true, Want: messages.Sprintf(i18n.Current(), "case.valid_setting")}, Got: "!! " + err.Error()}
+	report.Failures = append(report.Failures, failure)
+	return report
+}
+
+// run 执行一条语句，返回实际结果及其是否符合期望，错误信息按 lang 输出
+func (c Case) run(env *Env, lang i18n.Lang) (string, bool) {
+	res := env.Exec(Statement{Text: c.Text})
+	if res.Err != nil {
+		code := ErrorCode(res.Err)
+		if c.WantErr && !isCategory(c.Code) {
+			code = ErrorID(res.Err)
+		}
+		msg := i18n.Text(lang, res.Err)
+		return "!! " + code + ": " + msg, c.WantErr && c.Code == code && strings.Contains(msg, c.Msg)
+	}
+
+	got := "=> "
+	if res.Func != nil {
+		got += res.Func.String()
+	} else {
+		got += FormatValue(res.Value)
+	}
+	return got, !c.WantErr && (!c.Expect || got == "=> "+c.Want)
+}
+
+// RunCaseFiles 并行执行多个用例文件，每个文件使用独立的环境，函数、常量和单位来自 r
+//
+// 返回的结果与 files 的顺序相同。
+func RunCaseFiles(files []*CaseFile, r *Registry) []CaseReport {
+	reports := make([]CaseReport, len(files))
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, f := range files {
+		wg.Add(1)
+		go func(i int, f *CaseFile) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			reports[i] = f.Run(r)
+		}(i, f)
+	}
+	wg.Wait()
+	return reports
+}
@@ -0,0 +1,145 @@
+package calculator
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"cli_cmd/i18n"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCaseFiles 运行 testdata/cases 中的用例文件，calc test 默认运行同一组用例
+func TestCaseFiles(t *testing.T) {
+	files, err := LoadCaseFiles(os.DirFS("testdata/cases"), ".")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, report := range RunCaseFiles(files, DefaultRegistry) {
+		t.Run(report.File.Name, func(t *testing.T) {
+			for _, f := range report.Failures {
+				t.Errorf("第 %d 行 %s\n期望: %s\n实际: %s", f.Line, f.Text, f.Expected(), f.Got)
+			}
+		})
+	}
+}
+
+// TestParseCaseFile 测试用例文件的解析
+func TestParseCaseFile(t *testing.T) {
+	src := `# 注释
+@mode big       # 设置行的注释
+@precision 128
+
+x = 2
+x * 3   # 行内注释属于语句
+=> 6    # 期望行的注释
+1 / 0
+!! eval: 除零
+1 +
+!! syntax
+`
+	f, err := ParseCaseFile("a.calc", strings.NewReader(src))
+	require.NoError(t, err)
+	assert.Equal(t, ModeBig, f.Mode)
+	assert.Equal(t, uint(128), f.Precision)
+	require.Len(t, f.Cases, 4)
+
+	assert.Equal(t, Case{Line: 5, Text: "x = 2"}, f.Cases[0])
+	assert.Equal(t, Case{Line: 6, Text: "x * 3   # 行内注释属于语句", Expect: true, Want: "6"}, f.Cases[1])
+	assert.Equal(t, Case{Line: 8, Text: "1 / 0", Expect: true, WantErr: true, Code: CodeEval, Msg: "除零"}, f.Cases[2])
+	assert.Equal(t, "!! syntax", f.Cases[3].Expected())
+	assert.Equal(t, "=> (任意结果)", f.Cases[0].Expected())
+
+	f, err = ParseCaseFile("b.calc", strings.NewReader("1 + 1\n=> 2\n"))
+	require.NoError(t, err)
+	assert.Equal(t, ModeFloat, f.Mode, "默认使用 float 模式")
+	assert.Equal(t, DefaultPrecision, f.Precision)
+	assert.Equal(t, i18n.Chinese, f.Lang, "默认按中文比较错误信息")
+
+	f, err = ParseCaseFile("c.calc", strings.NewReader("@lang en_US\n1 / 0\n!! eval: division by zero\n"))
+	require.NoError(t, err)
+	assert.Equal(t, i18n.English, f.Lang)
+}
+
+// TestParseCaseFileErrors 测试用例文件的格式错误
+func TestParseCaseFileErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+		desc     string
+	}{
+		{"=> 1", "a.calc:1: 期望之前没有语句", "期望之前没有语句"},
+		{"1\n=> 1\n=> 1", "a.calc:3: 第 1 行的语句已经有期望", "重复的期望"},
+		{"1\n=>", "a.calc:2: 缺少期望的结果", "缺少期望的结果"},
+		{"1\n!!", "a.calc:2: 缺少期望的错误代码", "缺少错误代码"},
+		{"1\n!! oops: x", "a.calc:2: 未知的错误代码: oops", "未知的错误代码"},
+		{"1\n@mode rat", "a.calc:2: 设置必须写在所有语句之前", "设置在语句之后"},
+		{"@mode", "a.calc:1: 设置的格式为 @名称 值: @mode", "缺少设置的值"},
+		{"@mode fast", "a.calc:1: 未知的计算模式: fast（可选 float、big、rat）", "未知的计算模式"},
+		{"@precision 0", "a.calc:1: 无效的精度: 0", "无效的精度"},
+		{"@lang fr", "a.calc:1: 不支持的语言: fr", "不支持的语言"},
+		{"@color red", "a.calc:1: 未知的设置: @color", "未知的设置"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := ParseCaseFile("a.calc", strings.NewReader(test.src))
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+// TestRunCaseFiles 测试未通过的用例及其实际结果
+func TestRunCaseFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pass.calc":       {Data: []byte("x = 1\nx + 1\n=> 2\n")},
+		"sub/fail.calc":   {Data: []byte("1 + 1\n=> 3\n1 / 0\n!! eval: 溢出\n1 / 0\n!! syntax\ny\n0.1 + 0.2\n!! eval\n")},
+		"sub/ignore.txt":  {Data: []byte("=> 不是用例文件")},
+		"rat/units.calc":  {Data: []byte("@mode rat\n1 km / 3 h\n=> 1/3 km/h\n")},
+		"other/file.calc": {Data: []byte("1\n")},
+	}
+	files, err := LoadCaseFiles(fsys, ".")
+	require.NoError(t, err)
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+	}
+	assert.Equal(t, []string{"other/file.calc", "pass.calc", "rat/units.calc", "sub/fail.calc"}, names, "按路径顺序加载")
+
+	files, err = LoadCaseFiles(fsys, "sub")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "fail.calc", files[0].Name, "文件名相对于目录")
+
+	files, err = LoadCaseFiles(fsys, ".")
+	require.NoError(t, err)
+	reports := RunCaseFiles(files, DefaultRegistry)
+	require.Len(t, reports, 4)
+	assert.True(t, reports[0].Passed())
+	assert.True(t, reports[1].Passed())
+	assert.True(t, reports[2].Passed(), "文件设置的计算模式")
+	require.False(t, reports[3].Passed())
+
+	failures := reports[3].Failures
+	require.Len(t, failures, 5)
+	got := make([]string, len(failures))
+	for i, f := range failures {
+		got[i] = f.Expected() + " | " + f.Got
+	}
+	assert.Equal(t, []string{
+		"=> 3 | => 2",
+		"!! eval: 溢出 | !! eval: 除零错误",
+		"!! syntax | !! eval: 除零错误",
+		"=> (任意结果) | !! eval: 未定义的变量: y",
+		"!! eval | => 0.3",
+	}, got)
+	assert.Equal(t, []int{1, 3, 5, 7, 8}, []int{failures[0].Line, failures[1].Line, failures[2].Line, failures[3].Line, failures[4].Line})
+
+	_, err = LoadCaseFiles(fsys, "missing")
+	assert.Error(t, err, "目录不存在")
+	_, err = LoadCaseFiles(fstest.MapFS{"bad.calc": {Data: []byte("=> 1")}}, ".")
+	assert.EqualError(t, err, "bad.calc:1: 期望之前没有语句")
+}
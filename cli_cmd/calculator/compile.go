@@ -0,0 +1,374 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// opcode 栈式虚拟机的指令
+type opcode uint8
+
+const (
+	opConst       opcode = iota // 压入 consts[arg]
+	opLoad                      // 压入变量 names[arg]，变量不存在时压入 consts[n]，n < 0 表示报错
+	opNeg                       // 栈顶取负
+	opNot                       // 栈顶逻辑非
+	opBool                      // 栈顶转换为 0 或 1
+	opBinary                    // 弹出两个操作数，按运算符 arg 计算
+	opCall                      // 以栈顶 n 个值为参数调用 funcs[arg]
+	opJump                      // 跳转到 arg
+	opJumpIfFalse               // 弹出栈顶，为假时跳转到 arg
+	opJumpIfTrue                // 弹出栈顶，为真时跳转到 arg
+)
+
+var opcodeNames = [...]string{
+	opConst:       "CONST",
+	opLoad:        "LOAD",
+	opNeg:         "NEG",
+	opNot:         "NOT",
+	opBool:        "BOOL",
+	opBinary:      "BINARY",
+	opCall:        "CALL",
+	opJump:        "JUMP",
+	opJumpIfFalse: "JUMP_IF_FALSE",
+	opJumpIfTrue:  "JUMP_IF_TRUE",
+}
+
+// instr 一条指令
+type instr struct {
+	op  opcode
+	arg int
+	n   int
+}
+
+// Program 编译后的表达式，可以用不同的变量值反复求值
+//
+// Program 总是按 float64 计算，不受环境的计算模式影响；
+// 变量在求值时从环境中读取，常量和内置函数在编译时解析。
+// Program 可以被多个 goroutine 并发使用。
+type Program struct {
+	src      string
+	code     []instr
+	consts   []float64
+	names    []string
+	funcs    []*Function
+	maxStack int
+	stacks   sync.Pool
+}
+
+// Compile 把表达式编译为 Program，函数和常量来自 DefaultRegistry
+func Compile(expr string) (*Program, error) {
+	return NewEnv().Compile(expr)
+}
+
+// Compile 按环境的规模限制和函数注册表编译表达式
+//
+// 赋值、函数定义、用户函数、列表和单位不支持编译。
+func (e *Env) Compile(expr string) (*Program, error) {
+	node, err := e.parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	c := compiler{registry: e.registry, prog: &Program{src: expr}}
+	if err := c.compile(node); err != nil {
+		return nil, err
+	}
+	p := c.prog
+	size := p.maxStack
+	p.stacks.New = func() interface{} {
+		stack := make([]float64, size)
+		return &stack
+	}
+	return p, nil
+}
+
+// String 返回编译前的表达式
+func (p *Program) String() string {
+	return p.src
+}
+
+// Vars 返回程序引用的变量名（包括可以被变量覆盖的常量）
+func (p *Program) Vars() []string {
+	return append([]string(nil), p.names...)
+}
+
+// Disasm 返回程序的指令列表，用于调试
+func (p *Program) Disasm() string {
+	var sb strings.Builder
+	for pc, in := range p.code {
+		fmt.Fprintf(&sb, "%04d %s", pc, opcodeNames[in.op])
+		switch in.op {
+		case opConst:
+			fmt.Fprintf(&sb, " %g", p.consts[in.arg])
+		case opLoad:
+			fmt.Fprintf(&sb, " %s", p.names[in.arg])
+		case opBinary:
+			fmt.Fprintf(&sb, " %s", operatorSymbol(TokenType(in.arg)))
+		case opCall:
+			fmt.Fprintf(&sb, " %s/%d", p.funcs[in.arg].Name, in.n)
+		case opJump, opJumpIfFalse, opJumpIfTrue:
+			fmt.Fprintf(&sb, " %04d", in.arg)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Eval 在环境中对程序求值，env 为 nil 时表达式不能引用变量
+//
+// 求值成功时不分配内存。
+func (p *Program) Eval(env *Env) (float64, error) {
+	sp := p.stacks.Get().(*[]float64)
+	result, err := p.run(env, *sp)
+	p.stacks.Put(sp)
+	return result, err
+}
+
+// run 执行指令，stack 的长度不小于 maxStack
+func (p *Program) run(env *Env, stack []float64) (float64, error) {
+	sp := 0
+	for pc := 0; pc < len(p.code); pc++ {
+		in := p.code[pc]
+		switch in.op {
+		case opConst:
+			stack[sp] = p.consts[in.arg]
+			sp++
+		case opLoad:
+			f, err := p.load(env, in)
+			if err != nil {
+				return 0, err
+			}
+			stack[sp] = f
+			sp++
+		case opNeg:
+			stack[sp-1] = -stack[sp-1]
+		case opNot:
+			stack[sp-1] = boolToFloat(!truthyFloat(stack[sp-1]))
+		case opBool:
+			stack[sp-1] = boolToFloat(truthyFloat(stack[sp-1]))
+		case opBinary:
+			sp--
+			r, err := binary(TokenType(in.arg), stack[sp-1], stack[sp])
+			if err != nil {
+				return 0, err
+			}
+			stack[sp-1] = r
+		case opCall:
+			fn := p.funcs[in.arg]
+			r, err := fn.Call(stack[sp-in.n : sp])
+			if err != nil {
+				return 0, err
+			}
+			if math.IsNaN(r) {
+				return 0, errorf("domain.function", fn.Name)
+			}
+			sp -= in.n
+			stack[sp] = r
+			sp++
+		case opJump:
+			pc = in.arg - 1
+		case opJumpIfFalse:
+			sp--
+			if !truthyFloat(stack[sp]) {
+				pc = in.arg - 1
+			}
+		case opJumpIfTrue:
+			sp--
+			if truthyFloat(stack[sp]) {
+				pc = in.arg - 1
+			}
+		}
+	}
+	return stack[0], nil
+}
+
+// load 读取变量，变量优先于同名常量
+func (p *Program) load(env *Env, in instr) (float64, error) {
+	name := p.names[in.arg]
+	if env != nil {
+		if v, ok := env.GetValue(name); ok {
+			if f, ok := v.(Float); ok {
+				return float64(f), nil
+			}
+			return scalar(v)
+		}
+	}
+	if in.n >= 0 {
+		return p.consts[in.n], nil
+	}
+	return 0, errorf("undefined_variable.name", name)
+}
+
+// truthyFloat 与 truthy 相同，NaN 视为假
+func truthyFloat(f float64) bool {
+	return f > 0 || f < 0
+}
+
+// compiler 把语法树编译为指令
+type compiler struct {
+	registry *Registry
+	prog     *Program
+	depth    int // 当前的栈深度
+}
+
+// emit 追加一条指令并返回它的位置，delta 是指令对栈深度的影响
+func (c *compiler) emit(op opcode, arg, n, delta int) int {
+	c.prog.code = append(c.prog.code, instr{op: op, arg: arg, n: n})
+	c.depth += delta
+	if c.depth > c.prog.maxStack {
+		c.prog.maxStack = c.depth
+	}
+	return len(c.prog.code) - 1
+}
+
+// patch 把跳转指令的目标设置为下一条指令
+func (c *compiler) patch(pc int) {
+	c.prog.code[pc].arg = len(c.prog.code)
+}
+
+// constant 返回常量在常量表中的位置
+func (c *compiler) constant(f float64) int {
+	for i, v := range c.prog.consts {
+		if math.Float64bits(v) == math.Float64bits(f) {
+			return i
+		}
+	}
+	c.prog.consts = append(c.prog.consts, f)
+	return len(c.prog.consts) - 1
+}
+
+// name 返回变量名在名称表中的位置
+func (c *compiler) name(name string) int {
+	for i, v := range c.prog.names {
+		if v == name {
+			return i
+		}
+	}
+	c.prog.names = append(c.prog.names, name)
+	return len(c.prog.names) - 1
+}
+
+func (c *compiler) compile(node Node) error {
+	switch n := node.(type) {
+	case *NumberLit:
+		f := n.Value
+		if isPrefixedLiteral(n.Literal) {
+			v, err := parseNumber(n.Literal, ModeFloat, 0)
+			if err != nil {
+				return err
+			}
+			f = v.Float64()
+		}
+		if math.IsInf(f, 0) {
+			return errorf("out_of_range.literal", n.Literal)
+		}
+		c.emit(opConst, c.constant(f), 0, 1)
+	case *Ident:
+		fallback := -1
+		if k, ok := c.registry.constant(n.Name); ok {
+			fallback = c.constant(k.f)
+		}
+		c.emit(opLoad, c.name(n.Name), fallback, 1)
+	case *ParenExpr:
+		return c.compile(n.X)
+	case *UnaryExpr:
+		if err := c.compile(n.X); err != nil {
+			return err
+		}
+		switch n.Op {
+		case MINUS:
+			c.emit(opNeg, 0, 0, 0)
+		case PLUS:
+		case NOT:
+			c.emit(opNot, 0, 0, 0)
+		default:
+			return errorf("internal.unary_operator", operatorSymbol(n.Op))
+		}
+	case *BinaryExpr:
+		return c.compileBinary(n)
+	case *TernaryExpr:
+		if err := c.compile(n.Cond); err != nil {
+			return err
+		}
+		jumpElse := c.emit(opJumpIfFalse, 0, 0, -1)
+		if err := c.compile(n.Then); err != nil {
+			return err
+		}
+		jumpEnd := c.emit(opJump, 0, 0, -1) // 两个分支只有一个压栈
+		c.patch(jumpElse)
+		if err := c.compile(n.Else); err != nil {
+			return err
+		}
+		c.patch(jumpEnd)
+	case *CallExpr:
+		return c.compileCall(n)
+	case *UnitLit, *ConvertExpr:
+		return errorf("not_compilable.unit", n)
+	case *ListExpr, *Lambda:
+		return errorf("not_compilable.list", n)
+	case *AssignExpr:
+		return errorf("not_compilable.assign", n)
+	case *FuncDef:
+		return errorf("not_compilable.func_def", n)
+	default:
+		return errorf("internal.compile_node", node)
+	}
+	return nil
+}
+
+// compileBinary 编译二元表达式，逻辑运算按短路求值编译
+func (c *compiler) compileBinary(n *BinaryExpr) error {
+	if err := c.compile(n.X); err != nil {
+		return err
+	}
+	if n.Op == DOTDOT {
+		return errorf("not_compilable.list", n)
+	}
+	if n.Op != AND && n.Op != OR {
+		if err := c.compile(n.Y); err != nil {
+			return err
+		}
+		c.emit(opBinary, int(n.Op), 0, -1)
+		return nil
+	}
+
+	// x && y: x 为假时结果为 0，否则为 bool(y)；x || y 同理
+	jump, short := opJumpIfFalse, 0.0
+	if n.Op == OR {
+		jump, short = opJumpIfTrue, 1.0
+	}
+	jumpShort := c.emit(jump, 0, 0, -1)
+	if err := c.compile(n.Y); err != nil {
+		return err
+	}
+	c.emit(opBool, 0, 0, 0)
+	jumpEnd := c.emit(opJump, 0, 0, -1)
+	c.patch(jumpShort)
+	c.emit(opConst, c.constant(short), 0, 1)
+	c.patch(jumpEnd)
+	return nil
+}
+
+// compileCall 编译内置函数调用
+func (c *compiler) compileCall(n *CallExpr) error {
+	fn, ok := c.registry.Func(n.Fun.Name)
+	if !ok {
+		return errorf("undefined_function.compiled", n.Fun.Name)
+	}
+	if fn.form != nil {
+		return errorf("not_compilable.list_function", n.Fun.Name)
+	}
+	if err := fn.checkArgs(len(n.Args)); err != nil {
+		return err
+	}
+	for _, arg := range n.Args {
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+	}
+	c.prog.funcs = append(c.prog.funcs, fn)
+	c.emit(opCall, len(c.prog.funcs)-1, len(n.Args), 1-len(n.Args))
+	return nil
+}
@@ -0,0 +1,190 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompile 测试编译后的结果与遍历语法树一致
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expression string
+		desc       string
+	}{
+		{"1 + 2 * 3", "运算符优先级"},
+		{"2 ^ 3 ^ 2", "右结合的乘方"},
+		{"-(x - 10) / 4", "一元负号和括号"},
+		{"!x + !0", "逻辑非"},
+		{"x > 2 && y < 1", "逻辑与"},
+		{"x < 2 || y", "逻辑或"},
+		{"0 && 1 / 0", "逻辑与短路"},
+		{"x ? y * 2 : y / 2", "条件表达式"},
+		{"x > 5 ? 1 : x > 2 ? 2 : 3", "嵌套的条件表达式"},
+		{"sqrt(x) + max(x, y, 10) + pi", "函数和常量"},
+		{"(x & 6) | 1 << 4", "位运算"},
+		{"0xff + x % 2", "十六进制和取模"},
+		{"e * 2", "变量覆盖常量"},
+	}
+
+	env := NewEnv()
+	env.Set("x", 3)
+	env.Set("y", 0.5)
+	env.Set("e", 10)
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			prog, err := Compile(test.expression)
+			require.NoError(t, err)
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+
+			expected, err := env.Eval(node)
+			require.NoError(t, err)
+			actual, err := prog.Eval(env)
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+// TestCompileErrors 测试编译和求值错误
+func TestCompileErrors(t *testing.T) {
+	compileErrors := []struct {
+		expression string
+		desc       string
+	}{
+		{"1 +", "语法错误"},
+		{"x = 1", "赋值"},
+		{"f(x) = x", "函数定义"},
+		{"1 ether", "以太坊单位"},
+		{"foo(1)", "未定义的函数"},
+		{"sqrt(1, 2)", "参数个数错误"},
+	}
+	for _, test := range compileErrors {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Compile(test.expression)
+			assert.Error(t, err)
+		})
+	}
+
+	prog, err := Compile("x + 1")
+	require.NoError(t, err)
+	_, err = prog.Eval(NewEnv())
+	assert.EqualError(t, err, "未定义的变量: x")
+	_, err = prog.Eval(nil)
+	assert.Error(t, err)
+
+	prog, err = Compile("sqrt(x)")
+	require.NoError(t, err)
+	env := NewEnv()
+	env.Set("x", -1)
+	_, err = prog.Eval(env)
+	assert.EqualError(t, err, "sqrt 的参数不能为负数")
+
+	env = NewEnv()
+	env.SetLimits(Limits{MaxDepth: 2})
+	_, err = env.Compile("1 + 2 * 3")
+	assert.ErrorIs(t, err, ErrTooDeep)
+}
+
+// TestProgram 测试程序的复用和辅助方法
+func TestProgram(t *testing.T) {
+	prog, err := Compile("a * x + b")
+	require.NoError(t, err)
+	assert.Equal(t, "a * x + b", prog.String())
+	assert.Equal(t, []string{"a", "x", "b"}, prog.Vars())
+	assert.Equal(t, "0000 LOAD a\n0001 LOAD x\n0002 BINARY *\n0003 LOAD b\n0004 BINARY +\n", prog.Disasm())
+
+	v, err := prog.Eval(nil)
+	assert.Error(t, err)
+	assert.Zero(t, v)
+
+	env := NewEnv()
+	env.Set("a", 2)
+	env.Set("b", 1)
+	for i := 0; i < 10; i++ {
+		env.Set("x", float64(i))
+		v, err := prog.Eval(env)
+		require.NoError(t, err)
+		assert.Equal(t, float64(2*i+1), v)
+	}
+
+	v, err = mustCompile(t, "pi").Eval(nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 3.14159, v, 1e-5, "不引用变量时 env 可以为 nil")
+}
+
+// TestProgramAllocs 测试求值不分配内存
+func TestProgramAllocs(t *testing.T) {
+	prog := mustCompile(t, "x > 0 ? sqrt(x) * max(x, y, 1) + pi : -x")
+	env := NewEnv()
+	env.Set("x", 4)
+	env.Set("y", 2)
+	allocs := testing.AllocsPerRun(1000, func() {
+		if _, err := prog.Eval(env); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+// mustCompile 编译表达式，失败时终止测试
+func mustCompile(t testing.TB, expr string) *Program {
+	t.Helper()
+	prog, err := Compile(expr)
+	require.NoError(t, err)
+	return prog
+}
+
+// benchExpr 基准测试使用的表达式
+const benchExpr = "x > 0 ? sqrt(x) * max(x, y, 1) + pi : -x"
+
+// benchEnv 创建基准测试使用的环境
+func benchEnv() *Env {
+	env := NewEnv()
+	env.Set("x", 4)
+	env.Set("y", 2)
+	return env
+}
+
+// BenchmarkParseEval 每次都重新解析表达式
+func BenchmarkParseEval(b *testing.B) {
+	env := benchEnv()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		env.Set("x", float64(i%100))
+		if _, err := env.Calculate(benchExpr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkTreeEval 解析一次，每次遍历语法树求值
+func BenchmarkTreeEval(b *testing.B) {
+	env := benchEnv()
+	node, err := Parse(benchExpr)
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env.Set("x", float64(i%100))
+		if _, err := env.Eval(node); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkProgramEval 编译一次，每次执行指令求值
+func BenchmarkProgramEval(b *testing.B) {
+	env := benchEnv()
+	prog := mustCompile(b, benchExpr)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		env.Set("x", float64(i%100))
+		if _, err := prog.Eval(env); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package calculator
+
+// Diff 对表达式关于变量 name 求导，返回化简后的导数
+//
+// 支持四则运算、乘方、条件表达式（分段求导）以及常用的初等函数，
+// 其他名称（包括 pi、e 等常量）视为与 name 无关的常数。
+func Diff(node Node, name string) (Node, error) {
+	d, err := derive(node, name)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(d), nil
+}
+
+// derive 按求导法则生成未化简的导数
+func derive(node Node, v string) (Node, error) {
+	if !dependsOn(node, v) {
+		switch node.(type) {
+		case *AssignExpr, *FuncDef:
+		default:
+			return number(0), nil
+		}
+	}
+
+	switch n := node.(type) {
+	case *Ident:
+		return number(1), nil
+	case *ParenExpr:
+		return derive(n.X, v)
+	case *UnaryExpr:
+		if n.Op == NOT {
+			break
+		}
+		dx, err := derive(n.X, v)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: n.Op, X: dx}, nil
+	case *BinaryExpr:
+		return deriveBinary(n, v)
+	case *TernaryExpr:
+		then, err := derive(n.Then, v)
+		if err != nil {
+			return nil, err
+		}
+		els, err := derive(n.Else, v)
+		if err != nil {
+			return nil, err
+		}
+		return &TernaryExpr{Cond: n.Cond, Then: then, Else: els}, nil
+	case *CallExpr:
+		return deriveCall(n, v)
+	case *AssignExpr, *FuncDef:
+		return nil, errorf("not_differentiable.statement", FormatNode(node))
+	}
+	return nil, errorf("not_differentiable.node", FormatNode(node))
+}
+
+// deriveBinary 二元运算的求导法则
+func deriveBinary(n *BinaryExpr, v string) (Node, error) {
+	switch n.Op {
+	case PLUS, MINUS, MULTIPLY, DIVIDE, POWER:
+	default:
+		return nil, errorf("not_differentiable.operator", FormatNode(n), operatorSymbol(n.Op))
+	}
+	u, w := n.X, n.Y
+	du, err := derive(u, v)
+	if err != nil {
+		return nil, err
+	}
+	dw, err := derive(w, v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case PLUS, MINUS:
+		return bin(n.Op, du, dw), nil
+	case MULTIPLY:
+		// (uw)' = u'w + uw'
+		return bin(PLUS, bin(MULTIPLY, du, w), bin(MULTIPLY, u, dw)), nil
+	case DIVIDE:
+		if !dependsOn(w, v) {
+			return bin(DIVIDE, du, w), nil
+		}
+		// (u/w)' = (u'w - uw') / w^2
+		return bin(DIVIDE, bin(MINUS, bin(MULTIPLY, du, w), bin(MULTIPLY, u, dw)), bin(POWER, w, number(2))), nil
+	}
+
+	// 乘方
+	switch {
+	case !dependsOn(w, v):
+		// (u^c)' = c * u^(c-1) * u'
+		return bin(MULTIPLY, bin(MULTIPLY, w, bin(POWER, u, bin(MINUS, w, number(1)))), du), nil
+	case !dependsOn(u, v):
+		// (c^w)' = c^w * ln(c) * w'，e^w 的导数为 e^w * w'
+		if id, ok := u.(*Ident); ok && id.Name == "e" {
+			return bin(MULTIPLY, n, dw), nil
+		}
+		return bin(MULTIPLY, bin(MULTIPLY, n, call("ln", u)), dw), nil
+	}
+	// (u^w)' = u^w * (w' * ln(u) + w * u' / u)
+	return bin(MULTIPLY, n, bin(PLUS, bin(MULTIPLY, dw, call("ln", u)), bin(DIVIDE, bin(MULTIPLY, w, du), u))), nil
+}
+
+// deriveCall 初等函数的求导法则，结果乘以内层函数的导数
+func deriveCall(n *CallExpr, v string) (Node, error) {
+	name := n.Fun.Name
+	if name == "pow" && len(n.Args) == 2 {
+		return derive(bin(POWER, n.Args[0], n.Args[1]), v)
+	}
+	if name == "log" && len(n.Args) == 2 {
+		// log(u, b) = ln(u) / ln(b)
+		return derive(bin(DIVIDE, call("ln", n.Args[0]), call("ln", n.Args[1])), v)
+	}
+	if len(n.Args) != 1 {
+		return nil, errorf("not_differentiable.function", FormatNode(n), name)
+	}
+
+	u := n.Args[0]
+	du, err := derive(u, v)
+	if err != nil {
+		return nil, err
+	}
+	one := number(1)
+	var outer Node // 外层函数在 u 处的导数
+	switch name {
+	case "sin":
+		outer = call("cos", u)
+	case "cos":
+		outer = &UnaryExpr{Op: MINUS, X: call("sin", u)}
+	case "tan":
+		outer = bin(DIVIDE, one, bin(POWER, call("cos", u), number(2)))
+	case "exp":
+		outer = n
+	case "ln":
+		outer = bin(DIVIDE, one, u)
+	case "log":
+		outer = bin(DIVIDE, one, bin(MULTIPLY, u, call("ln", number(10))))
+	case "sqrt":
+		outer = bin(DIVIDE, one, bin(MULTIPLY, number(2), n))
+	case "abs":
+		outer = bin(DIVIDE, u, n)
+	case "asin":
+		outer = bin(DIVIDE, one, call("sqrt", bin(MINUS, one, bin(POWER, u, number(2)))))
+	case "acos":
+		outer = &UnaryExpr{Op: MINUS, X: bin(DIVIDE, one, call("sqrt", bin(MINUS, one, bin(POWER, u, number(2)))))}
+	case "atan":
+		outer = bin(DIVIDE, one, bin(PLUS, one, bin(POWER, u, number(2))))
+	default:
+		return nil, errorf("not_differentiable.function", FormatNode(n), name)
+	}
+	return bin(MULTIPLY, outer, du), nil
+}
+
+// dependsOn 判断表达式是否引用了变量 name，函数名不算引用
+func dependsOn(node Node, name string) bool {
+	found := false
+	Walk(node, func(n Node) bool {
+		switch x := n.(type) {
+		case *Ident:
+			found = found || x.Name == name
+		case *CallExpr:
+			for _, arg := range x.Args {
+				found = found || dependsOn(arg, name)
+			}
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// bin 创建二元表达式节点
+func bin(op TokenType, x, y Node) Node {
+	return &BinaryExpr{X: x, Op: op, Y: y}
+}
+
+// call 创建单参数的函数调用节点
+func call(name string, arg Node) Node {
+	return &CallExpr{Fun: &Ident{Name: name}, Args: []Node{arg}}
+}
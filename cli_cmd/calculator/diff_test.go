@@ -0,0 +1,97 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiff 测试求导
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		expression string
+		variable   string
+		expected   string
+		desc       string
+	}{
+		{"x^2*sin(x)", "x", "2 * x * sin(x) + x ^ 2 * cos(x)", "乘积法则"},
+		{"x^3 + 2*x + 1", "x", "3 * x ^ 2 + 2", "多项式"},
+		{"a*x^2 + b*x + c", "x", "2 * a * x + b", "其他变量视为常数"},
+		{"rate*t^2/2", "t", "rate * t", "指定变量"},
+		{"1/x", "x", "-1 / x ^ 2", "商法则"},
+		{"sin(x)/x", "x", "(cos(x) * x - sin(x)) / x ^ 2", "商法则化简"},
+		{"ln(x^2 + 1)", "x", "2 * x / (x ^ 2 + 1)", "链式法则"},
+		{"e^(2*x)", "x", "2 * e ^ (2 * x)", "以 e 为底的指数"},
+		{"2^x", "x", "2 ^ x * ln(2)", "指数函数"},
+		{"x^x", "x", "x ^ x * (ln(x) + 1)", "幂指函数"},
+		{"cos(x)^2", "x", "-2 * cos(x) * sin(x)", "复合函数"},
+		{"sqrt(x)", "x", "1 / (2 * sqrt(x))", "平方根"},
+		{"pow(x, 3)", "x", "3 * x ^ 2", "pow 函数"},
+		{"x > 0 ? x^2 : -x", "x", "x > 0 ? 2 * x : -1", "分段求导"},
+		{"pi * r^2", "x", "0", "与变量无关"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+			d, err := Diff(node, test.variable)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, FormatNode(d))
+		})
+	}
+}
+
+// TestDiffValue 用差商检验导数的值
+func TestDiffValue(t *testing.T) {
+	expressions := []string{
+		"x^2*sin(x)", "exp(-x^2)", "tan(x) + atan(x)", "log(x) + log(x, 2)",
+		"asin(x / 2) - acos(x / 3)", "abs(x) * x", "x^x", "(x + 1) / (x - 3)",
+	}
+	const h = 1e-6
+	env := NewEnv()
+	for _, expr := range expressions {
+		node, err := Parse(expr)
+		require.NoError(t, err)
+		d, err := Diff(node, "x")
+		require.NoError(t, err)
+
+		for _, x := range []float64{0.5, 1.25} {
+			env.Set("x", x+h)
+			hi, err := env.Eval(node)
+			require.NoError(t, err)
+			env.Set("x", x-h)
+			lo, err := env.Eval(node)
+			require.NoError(t, err)
+			env.Set("x", x)
+			actual, err := env.Eval(d)
+			require.NoError(t, err)
+			assert.InDelta(t, (hi-lo)/(2*h), actual, 1e-5, "d/dx %s = %s, x = %v", expr, FormatNode(d), x)
+		}
+	}
+}
+
+// TestDiffErrors 测试无法求导的表达式
+func TestDiffErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		desc       string
+	}{
+		{"x & 1", "位运算"},
+		{"x > 1", "比较运算"},
+		{"floor(x)", "不支持的函数"},
+		{"max(x, 1)", "多个参数的函数"},
+		{"f(x) = x^2", "函数定义"},
+		{"y = x", "赋值"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+			_, err = Diff(node, "x")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package calculator
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// 保存上一次计算结果的变量名
+const (
+	AnsVar        = "ans"
+	LastResultVar = "_"
+)
+
+// Env 变量环境，保存一次会话中的变量绑定和计算模式
+//
+// Env 可以被多个 goroutine 并发使用。
+type Env struct {
+	mu       sync.RWMutex
+	vars     map[string]Value
+	funcs    map[string]*UserFunc
+	registry *Registry
+	mode     Mode
+	prec     uint
+	limits   Limits
+}
+
+// NewEnv 创建空的变量环境，函数和常量来自 DefaultRegistry
+func NewEnv() *Env {
+	return NewEnvWithRegistry(DefaultRegistry)
+}
+
+// NewEnvWithRegistry 创建使用指定函数注册表的变量环境
+func NewEnvWithRegistry(r *Registry) *Env {
+	return &Env{
+		vars:     make(map[string]Value),
+		funcs:    make(map[string]*UserFunc),
+		registry: r,
+		mode:     ModeFloat,
+		prec:     DefaultPrecision,
+	}
+}
+
+// Registry 返回环境使用的函数注册表
+func (e *Env) Registry() *Registry {
+	return e.registry
+}
+
+// Mode 返回当前计算模式
+func (e *Env) Mode() Mode {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.mode
+}
+
+// SetMode 设置计算模式，已有变量保持原来的表示，参与运算时自动提升
+func (e *Env) SetMode(mode Mode) error {
+	if _, ok := modeNames[mode]; !ok {
+		return errorf("setting.mode_number", int(mode))
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.mode = mode
+	return nil
+}
+
+// Precision 返回 big 模式的精度（二进制位数）
+func (e *Env) Precision() uint {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.prec
+}
+
+// SetPrecision 设置 big 模式的精度（二进制位数）
+func (e *Env) SetPrecision(prec uint) error {
+	if prec == 0 || prec > MaxPrecision {
+		return errorf("setting.precision", MaxPrecision, prec)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prec = prec
+	return nil
+}
+
+// Get 获取变量的值，变量不存在、是列表或带单位时返回 false
+func (e *Env) Get(name string) (float64, bool) {
+	v, ok := e.GetValue(name)
+	if !ok {
+		return 0, false
+	}
+	f, err := scalar(v)
+	return f, err == nil
+}
+
+// GetValue 获取变量的原始数值
+func (e *Env) GetValue(name string) (Value, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	v, ok := e.vars[name]
+	return v, ok
+}
+
+// Set 设置变量的值
+func (e *Env) Set(name string, value float64) {
+	e.SetValue(name, Float(value))
+}
+
+// SetValue 设置变量的原始数值
+func (e *Env) SetValue(name string, value Value) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.vars[name] = value
+}
+
+// Unset 删除变量，变量不存在时返回 false
+func (e *Env) Unset(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.vars[name]; !ok {
+		return false
+	}
+	delete(e.vars, name)
+	return true
+}
+
+// Names 返回按字母顺序排列的变量名
+func (e *Env) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.vars))
+	for name := range e.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval 在当前环境中对语法树求值，赋值语句会修改环境；结果是列表或带单位时返回错误
+func (e *Env) Eval(node Node) (float64, error) {
+	v, err := e.EvalValue(node)
+	if err != nil {
+		return 0, err
+	}
+	return scalar(v)
+}
+
+// EvalValue 与 Eval 相同，但按当前计算模式返回原始数值
+func (e *Env) EvalValue(node Node) (Value, error) {
+	return e.evalContext(context.Background(), node)
+}
+
+// evalContext 对语法树求值，ctx 取消后停止调用用户函数
+func (e *Env) evalContext(ctx context.Context, node Node) (Value, error) {
+	ev := evaluator{env: e, mode: e.Mode(), prec: e.Precision(), ctx: ctx}
+	return ev.eval(node)
+}
+
+// Calculate 在当前环境中计算表达式，并把结果记录到 ans 和 _ 变量中；结果是列表或带单位时返回错误
+func (e *Env) Calculate(expression string) (float64, error) {
+	v, err := e.Evaluate(expression)
+	if err != nil {
+		return 0, err
+	}
+	return scalar(v)
+}
+
+// Evaluate 与 Calculate 相同，但按当前计算模式返回原始数值
+func (e *Env) Evaluate(expression string) (Value, error) {
+	node, err := e.parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.evaluate(context.Background(), node)
+}
+
+// evaluate 求值语法树，并把结果记录到 ans 和 _ 变量中
+func (e *Env) evaluate(ctx context.Context, node Node) (Value, error) {
+	result, err := e.evalContext(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.Limits().checkDigits(result); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.vars[AnsVar] = result
+	e.vars[LastResultVar] = result
+	e.mu.Unlock()
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAssignment 测试赋值与变量引用
+func TestAssignment(t *testing.T) {
+	env := NewEnv()
+
+	result, err := env.Calculate("x = 3*4")
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, result)
+
+	result, err = env.Calculate("x + 1")
+	require.NoError(t, err)
+	assert.Equal(t, 13.0, result)
+
+	result, err = env.Calculate("a = b = 2")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, result)
+	a, _ := env.Get("a")
+	b, _ := env.Get("b")
+	assert.Equal(t, 2.0, a)
+	assert.Equal(t, 2.0, b)
+}
+
+// TestLastResult 测试 ans 和 _ 记录上一次结果
+func TestLastResult(t *testing.T) {
+	env := NewEnv()
+
+	_, err := env.Calculate("2*5")
+	require.NoError(t, err)
+
+	result, err := env.Calculate("ans + 1")
+	require.NoError(t, err)
+	assert.Equal(t, 11.0, result)
+
+	result, err = env.Calculate("_ * 2")
+	require.NoError(t, err)
+	assert.Equal(t, 22.0, result)
+
+	// 出错时不覆盖上一次结果
+	_, err = env.Calculate("1/0")
+	require.Error(t, err)
+	ans, ok := env.Get(AnsVar)
+	require.True(t, ok)
+	assert.Equal(t, 22.0, ans)
+}
+
+// TestUnsetAndNames 测试删除变量和列出变量
+func TestUnsetAndNames(t *testing.T) {
+	env := NewEnv()
+	env.Set("y", 1)
+	env.Set("x", 2)
+	assert.Equal(t, []string{"x", "y"}, env.Names())
+
+	assert.True(t, env.Unset("x"))
+	assert.False(t, env.Unset("x"))
+	assert.Equal(t, []string{"y"}, env.Names())
+
+	_, err := env.Calculate("x")
+	assert.Error(t, err, "删除后的变量不应该可用")
+}
+
+// TestLibraryBindings 测试调用方预先设置的变量
+func TestLibraryBindings(t *testing.T) {
+	env := NewEnv()
+	env.Set("price", 2.5)
+	env.Set("qty", 4)
+
+	node, err := Parse("price * qty")
+	require.NoError(t, err)
+	result, err := env.Eval(node)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, result)
+}
+
+// TestAssignmentErrors 测试非法赋值
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		desc       string
+	}{
+		{"1 = 2", "左侧是数字"},
+		{"x + 1 = 2", "左侧是表达式"},
+		{"x =", "缺少右侧"},
+		{"= 1", "缺少左侧"},
+		{"undefined_var * 2", "未定义变量"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := NewEnv().Calculate(test.expression)
+			assert.Error(t, err, "表达式 %s 应该产生错误", test.expression)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"cli_cmd/i18n"
+)
+
+// 错误代码，供机器可读的输出使用
+const (
+	CodeSyntax  = "syntax"  // 语法错误
+	CodeEval    = "eval"    // 求值错误，例如除零、未定义的变量
+	CodeType    = "type"    // 类型错误，例如量纲不匹配的 1 m + 1 s
+	CodeFormat  = "format"  // 结果无法按指定的单位或进制输出
+	CodeLimit   = "limit"   // 语句超出长度或嵌套深度限制
+	CodeTimeout = "timeout" // 执行超时或被取消
+)
+
+// Error 求值等阶段的错误
+//
+// ID 是稳定的错误标识，与输出语言无关，可以用 errors.Is 与同一 ID 的 ErrDivisionByZero 等变量比较；
+// 错误信息在输出时才按 i18n.Current() 的语言格式化。
+type Error struct {
+	ID  string
+	msg message
+}
+
+// errorf 创建错误，key 是消息目录中的键，错误的 ID 是 key 中第一个 '.' 之前的部分
+func errorf(key string, args ...interface{}) *Error {
+	id, _, _ := strings.Cut(key, ".")
+	return &Error{ID: id, msg: message{key: key, args: args}}
+}
+
+func (e *Error) Error() string { return e.msg.Localize(i18n.Current()) }
+
+// Localize 按指定的语言返回错误信息
+func (e *Error) Localize(lang i18n.Lang) string { return e.msg.Localize(lang) }
+
+// Is 判断 target 是否为 ID 相同的 *Error
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.ID == e.ID
+}
+
+// Unwrap 返回错误信息中引用的错误，例如被取消时 context 的错误
+func (e *Error) Unwrap() []error {
+	var errs []error
+	for _, arg := range e.msg.args {
+		if err, ok := arg.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// 各 ID 的错误，用于 errors.Is 判断；ErrDimension、ErrTooLong、ErrTooDeep、ErrTooLarge 分别在单位和规模限制中定义
+var (
+	ErrDivisionByZero    = errorf("division_by_zero")   // 除数或模运算的除数为零
+	ErrUndefinedVariable = errorf("undefined_variable") // 未定义的变量
+	ErrUndefinedFunction = errorf("undefined_function") // 未定义的函数
+	ErrArgumentCount     = errorf("argument_count")     // 函数的参数个数不对
+	ErrInvalidArgument   = errorf("invalid_argument")   // 函数或运算符的参数无效，例如百分位超出 0 到 100
+	ErrDomain            = errorf("domain")             // 参数超出函数的定义域，例如 sqrt(-1)
+	ErrOutOfRange        = errorf("out_of_range")       // 结果超出数值类型的范围
+	ErrNotInteger        = errorf("not_integer")        // 需要整数的地方不是整数，例如位运算的操作数
+	ErrNotNumber         = errorf("not_number")         // 需要数值的地方是列表或带单位的数量
+	ErrListTooLong       = errorf("list_too_long")      // 列表超过 MaxListLength 个元素
+	ErrConstant          = errorf("constant")           // 给常量赋值
+	ErrCallDepth         = errorf("call_depth")         // 用户函数的调用深度超过 MaxCallDepth
+	ErrStopped           = errorf("stopped")            // 计算被取消或超时
+	ErrInvalidNumber     = errorf("invalid_number")     // 无效的数字字面量或无法转换的数值
+	ErrUnknownUnit       = errorf("unknown_unit")       // 未知的单位
+	ErrUnit              = errorf("unit")               // 单位的写法或用法无效
+	ErrSetting           = errorf("setting")            // 无效的计算模式或精度
+	ErrFormat            = errorf("format")             // 结果无法按指定的方式输出
+	ErrNotCompilable     = errorf("not_compilable")     // 表达式不能编译
+	ErrNotDifferentiable = errorf("not_differentiable") // 表达式不能求导
+	ErrDefinition        = errorf("definition")         // 无效的函数、常量或单位定义
+	ErrUnitFile          = errorf("unit_file")          // 单位文件的格式错误
+	ErrCaseFile          = errorf("case_file")          // 用例文件的格式错误
+	ErrRead              = errorf("read")               // 读取输入失败
+	ErrInternal          = errorf("internal")           // 内部错误
+
+	// 以下为语法错误的 ID
+	ErrUnexpectedToken   = errorf("unexpected_token")   // 意外的标记，或者缺少期望的标记
+	ErrIllegalCharacter  = errorf("illegal_character")  // 无法识别的字符
+	ErrEmptyExpression   = errorf("empty_expression")   // 表达式为空
+	ErrInvalidAssignment = errorf("invalid_assignment") // 赋值语句的左侧无效
+	ErrInvalidParameter  = errorf("invalid_parameter")  // 函数定义的参数无效或重复
+)
+
+// ErrorID 返回错误链中第一个 *Error 或 *SyntaxError 的 ID，没有时返回空字符串
+func ErrorID(err error) string {
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return syntaxErr.ID
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.ID
+	}
+	return ""
+}
+
+// ErrorCode 返回错误对应的错误代码，err 为 nil 时返回空字符串
+//
+// 错误代码是 syntax、eval 等大类，ErrorID 返回更细的错误标识。
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	var syntaxErr *SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return CodeSyntax
+	case errors.Is(err, ErrTooLong), errors.Is(err, ErrTooDeep), errors.Is(err, ErrTooLarge):
+		return CodeLimit
+	case errors.Is(err, ErrDimension):
+		return CodeType
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return CodeTimeout
+	}
+	return CodeEval
+}
+
+// SyntaxError 语法错误，记录出错位置和期望的标记
+type SyntaxError struct {
+	Input    string      // 完整的输入
+	Offset   int         // 出错位置（字节偏移）
+	Line     int         // 出错行号，从 1 开始
+	Column   int         // 出错列号（字符），从 1 开始
+	Expected []TokenType // 此处期望出现的标记，可能为空
+	Lexeme   string      // 出错位置的原始文本，输入结束时为空
+	ID       string      // 稳定的错误标识，见 ErrUnexpectedToken 等
+	msg      message
+}
+
+// newSyntaxError 创建语法错误并计算行列号，key 是消息目录中的键，ID 的规则与 errorf 相同
+func newSyntaxError(input string, offset int, lexeme string, expected []TokenType, key string, args ...interface{}) *SyntaxError {
+	if offset > len(input) {
+		offset = len(input)
+	}
+	line := 1 + strings.Count(input[:offset], "\n")
+	lineStart := strings.LastIndex(input[:offset], "\n") + 1
+	return &SyntaxError{
+		Input:    input,
+		Offset:   offset,
+		Line:     line,
+		Column:   utf8.RuneCountInString(input[lineStart:offset]) + 1,
+		Expected: expected,
+		Lexeme:   lexeme,
+		ID:       errorf(key).ID,
+		msg:      message{key: key, args: args},
+	}
+}
+
+func (e *SyntaxError) Error() string { return e.Localize(i18n.Current()) }
+
+// Localize 按指定的语言返回带行列号的错误信息
+func (e *SyntaxError) Localize(lang i18n.Lang) string {
+	if strings.Contains(e.Input, "\n") {
+		return messages.Sprintf(lang, "position.line_column", e.Line, e.Column, e.msg)
+	}
+	return messages.Sprintf(lang, "position.column", e.Column, e.msg)
+}
+
+// Msg 返回不带行列号的错误描述
+func (e *SyntaxError) Msg() string { return e.msg.Localize(i18n.Current()) }
+
+// Is 判断 target 是否为 ID 相同的 *Error，例如 errors.Is(err, ErrUnexpectedToken)
+func (e *SyntaxError) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.ID == e.ID
+}
+
+// Snippet 返回出错的那一行输入，以及在出错位置下方标出 ^ 的第二行
+func (e *SyntaxError) Snippet() string {
+	lines := strings.Split(e.Input, "\n")
+	line := ""
+	if e.Line-1 < len(lines) {
+		line = strings.TrimRight(lines[e.Line-1], "\r")
+	}
+
+	// 制表符原样保留，保证 ^ 与出错字符对齐
+	var marker strings.Builder
+	col := 0
+	for _, ch := range line {
+		if col >= e.Column-1 {
+			break
+		}
+		if ch == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+		col++
+	}
+	for ; col < e.Column-1; col++ {
+		marker.WriteRune(' ')
+	}
+	marker.WriteRune('^')
+
+	width := utf8.RuneCountInString(e.Lexeme)
+	if width > 1 {
+		marker.WriteString(strings.Repeat("~", width-1))
+	}
+	return line + "\n" + marker.String()
+}
+
+// tokenNames 标记类型的可读名称，以 token. 开头的是消息目录中的键
+var tokenNames = map[TokenType]string{
+	NUMBER:   "token.number",
+	IDENT:    "token.ident",
+	LPAREN:   "'('",
+	RPAREN:   "')'",
+	COMMA:    "','",
+	ASSIGN:   "'='",
+	QUESTION: "'?'",
+	COLON:    "':'",
+	IN:       "'in'",
+	LBRACKET: "'['",
+	RBRACKET: "']'",
+	ARROW:    "'=>'",
+	EOF:      "token.eof",
+	ILLEGAL:  "token.illegal",
+}
+
+func (t TokenType) String() string { return t.Localize(i18n.Current()) }
+
+// Localize 按指定的语言返回标记类型的名称
+func (t TokenType) Localize(lang i18n.Lang) string {
+	if name, ok := tokenNames[t]; ok {
+		return messages.Sprintf(lang, name)
+	}
+	if s, ok := opSymbols[t]; ok {
+		return "'" + s + "'"
+	}
+	return fmt.Sprintf("TokenType(%d)", int(t))
+}
+
+// describeToken 描述实际遇到的标记
+func describeToken(tok Token) i18n.Localizer {
+	switch tok.Type {
+	case EOF:
+		return tok.Type
+	case NUMBER:
+		return msg("token.number_value", tok.Value)
+	case IDENT:
+		return msg("token.ident_value", tok.Value)
+	}
+	return msg("token.quoted", tok.Value)
+}
+
+// expectedTokens 期望的标记列表，输出为可读文本
+type expectedTokens []TokenType
+
+func (ts expectedTokens) Localize(lang i18n.Lang) string {
+	names := make([]string, len(ts))
+	for i, t := range ts {
+		names[i] = t.Localize(lang)
+	}
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+	return messages.Sprintf(lang, "list.or", strings.Join(names[:len(names)-1], messages.Format(lang, "list.separator")), names[len(names)-1])
+}
@@ -0,0 +1,122 @@
+package calculator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSyntaxErrorPosition 测试语法错误的位置和期望的标记
+func TestSyntaxErrorPosition(t *testing.T) {
+	tests := []struct {
+		expression string
+		line       int
+		column     int
+		expected   []TokenType
+		message    string
+		desc       string
+	}{
+		{"(1+2", 1, 5, []TokenType{RPAREN}, "第 5 列: 期望 ')'，但得到 表达式结尾", "缺少右括号"},
+		{"2*", 1, 3, operandStart, "第 3 列: 期望 数字、标识符、'(' 或 '['，但得到 表达式结尾", "缺少操作数"},
+		{"1 2", 1, 3, []TokenType{EOF}, "第 3 列: 表达式解析不完整，多余的 数字 2", "多余的标记"},
+		{"1 ether in 5", 1, 12, []TokenType{IDENT}, "第 12 列: 期望 标识符，但得到 数字 5", "换算目标不是名称"},
+		{"1 + 2 = 3", 1, 1, []TokenType{IDENT}, "第 1 列: 赋值语句左侧必须是变量名或函数声明: 1 + 2", "赋值左侧不是变量"},
+		{"1 @ 2", 1, 3, []TokenType{EOF}, "第 3 列: 无法识别的字符 '@'", "未知字符"},
+		{"√4 + 1", 1, 1, operandStart, "第 1 列: 无法识别的字符 '√'", "多字节未知字符"},
+		{"π × 2", 1, 3, []TokenType{EOF}, "第 3 列: 无法识别的字符 '×'", "列号按字符计算"},
+		{"1 +\n  * 2", 2, 3, operandStart, "第 2 行第 3 列: 期望 数字、标识符、'(' 或 '['，但得到 '*'", "多行输入"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Parse(test.expression)
+			var syntaxErr *SyntaxError
+			require.True(t, errors.As(err, &syntaxErr), "表达式 %s 应该产生语法错误，实际为 %v", test.expression, err)
+			assert.Equal(t, test.line, syntaxErr.Line)
+			assert.Equal(t, test.column, syntaxErr.Column)
+			assert.Equal(t, test.expected, syntaxErr.Expected)
+			assert.Equal(t, test.message, syntaxErr.Error())
+		})
+	}
+}
+
+// TestSyntaxErrorSnippet 测试出错位置的 ^ 标记
+func TestSyntaxErrorSnippet(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"(1+2", "(1+2\n    ^", "指向输入结尾"},
+		{"sqrt(2) + foo bar", "sqrt(2) + foo bar\n              ^~~", "标记整个标识符"},
+		{"π × 2", "π × 2\n  ^", "多字节字符按列对齐"},
+		{"\t1 $", "\t1 $\n\t  ^", "保留制表符"},
+		{"1 +\n  * 2", "  * 2\n  ^", "只显示出错的行"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Parse(test.expression)
+			var syntaxErr *SyntaxError
+			require.True(t, errors.As(err, &syntaxErr))
+			assert.Equal(t, test.expected, syntaxErr.Snippet())
+		})
+	}
+}
+
+// TestTokenTypeString 测试标记类型的可读名称
+func TestTokenTypeString(t *testing.T) {
+	assert.Equal(t, "数字", NUMBER.String())
+	assert.Equal(t, "'^'", POWER.String())
+	assert.Equal(t, "'<='", LE.String())
+	assert.Equal(t, "表达式结尾", EOF.String())
+	assert.Equal(t, "TokenType(99)", TokenType(99).String())
+}
+
+// TestErrorCode 测试错误代码
+func TestErrorCode(t *testing.T) {
+	env := NewEnv()
+	_, err := env.Evaluate("(1+2")
+	assert.Equal(t, CodeSyntax, ErrorCode(err))
+	_, err = env.Evaluate("1/0")
+	assert.Equal(t, CodeEval, ErrorCode(err))
+
+	res := env.Exec(Statement{Line: 3, Text: "1 +"})
+	assert.Equal(t, CodeSyntax, ErrorCode(res.Err), "脚本错误应该保留语法错误的代码")
+	assert.Equal(t, "", ErrorCode(nil))
+}
+
+// TestErrorID 测试错误标识和 errors.Is 的判断
+func TestErrorID(t *testing.T) {
+	tests := []struct {
+		expression string
+		id         string
+		target     error
+		desc       string
+	}{
+		{"1 / 0", "division_by_zero", ErrDivisionByZero, "除零"},
+		{"5 % 0", "division_by_zero", ErrDivisionByZero, "模运算除零"},
+		{"y + 1", "undefined_variable", ErrUndefinedVariable, "未定义的变量"},
+		{"foo(1)", "undefined_function", ErrUndefinedFunction, "未定义的函数"},
+		{"sqrt(1, 2)", "argument_count", ErrArgumentCount, "参数个数"},
+		{"pi = 3", "constant", ErrConstant, "给常量赋值"},
+		{"1 m + 1 s", "dimension", ErrDimension, "量纲不匹配"},
+		{"(1+2", "unexpected_token", ErrUnexpectedToken, "缺少右括号"},
+		{"1 @ 2", "illegal_character", ErrIllegalCharacter, "未知字符"},
+		{"1 + 2 = 3", "invalid_assignment", ErrInvalidAssignment, "无效的赋值"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := NewEnv().Evaluate(test.expression)
+			require.Error(t, err)
+			assert.Equal(t, test.id, ErrorID(err))
+			assert.ErrorIs(t, err, test.target)
+			assert.NotErrorIs(t, err, ErrInternal)
+		})
+	}
+
+	assert.Empty(t, ErrorID(errors.New("其他错误")))
+}
@@ -0,0 +1,92 @@
+package calculator
+
+import (
+	"math/big"
+	"sort"
+)
+
+// etherUnits 以太坊金额单位及其相对 wei 的十进制指数
+var etherUnits = map[string]int{
+	"wei":        0,
+	"kwei":       3,
+	"babbage":    3,
+	"mwei":       6,
+	"lovelace":   6,
+	"gwei":       9,
+	"shannon":    9,
+	"szabo":      12,
+	"microether": 12,
+	"finney":     15,
+	"milliether": 15,
+	"ether":      18,
+	"eth":        18,
+}
+
+// IsEtherUnit 判断名称是否是以太坊金额单位
+func IsEtherUnit(name string) bool {
+	_, ok := etherUnits[name]
+	return ok
+}
+
+// EtherUnits 返回所有以太坊金额单位，按数量级从小到大排列
+func EtherUnits() []string {
+	names := make([]string, 0, len(etherUnits))
+	for name := range etherUnits {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := etherUnits[names[i]], etherUnits[names[j]]
+		if a != b {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// unitFactor 返回一个单位等于多少 wei
+func unitFactor(unit string) (*big.Int, error) {
+	exp, ok := etherUnits[unit]
+	if !ok {
+		return nil, errorf("unknown_unit.ether", unit)
+	}
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil), nil
+}
+
+// etherAmount 把带单位的字面量换算为整数 wei
+func etherAmount(lit, unit string) (Rat, error) {
+	factor, err := unitFactor(unit)
+	if err != nil {
+		return Rat{}, err
+	}
+	amount, err := parseExactLiteral(lit)
+	if err != nil {
+		return Rat{}, err
+	}
+	wei := amount.Mul(amount, new(big.Rat).SetInt(factor))
+	if !wei.IsInt() {
+		return Rat{}, errorf("not_integer.wei", lit, unit)
+	}
+	return Rat{wei}, nil
+}
+
+// convertWei 把以 wei 计的金额换算为指定单位的数量
+//
+// 金额总是整数 wei：不是整数的值（例如 1 ether / 3）先四舍五入到最近的 wei 再换算，
+// 因此换算结果总是有限小数。
+func convertWei(v Value, unit string, prec uint) (Value, error) {
+	factor, err := unitFactor(unit)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toRat(v)
+	if err != nil {
+		return nil, err
+	}
+	wei := ratRound(r.Rat)
+	if _, ok := v.(BigFloat); ok {
+		f := new(big.Float).SetPrec(prec).SetInt(wei)
+		return BigFloat{f.Quo(f, new(big.Float).SetPrec(prec).SetInt(factor))}, nil
+	}
+	return Rat{new(big.Rat).SetFrac(wei, factor)}, nil
+}
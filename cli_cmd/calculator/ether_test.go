@@ -0,0 +1,195 @@
+package calculator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEtherLiterals 测试以太坊单位字面量
+func TestEtherLiterals(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"1.5ether", "1500000000000000000", "小数 ether"},
+		{"30gwei", "30000000000", "gwei"},
+		{"21000wei", "21000", "wei"},
+		{"2 finney", "2000000000000000", "带空格的单位"},
+		{"1 ether + 1 wei", "1000000000000000001", "超过 float64 精度仍然精确"},
+		{"21000 * 30 gwei", "630000000000000", "手续费计算"},
+		{"0.000000000000000001 ether", "1", "最小单位"},
+		{"0x10 gwei", "16000000000", "十六进制数量"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v, err := NewEnv().Evaluate(test.expression)
+			require.NoError(t, err, "计算 %s 时不应该出错", test.expression)
+			assert.Equal(t, test.expected, FormatValue(v))
+		})
+	}
+}
+
+// TestEtherConversion 测试 in 换算运算符
+func TestEtherConversion(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"2 ether in gwei", "2000000000", "ether 换算为 gwei"},
+		{"1500000000 gwei in ether", "1.5", "gwei 换算为 ether"},
+		{"1 wei in ether", "0.000000000000000001", "wei 换算为 ether"},
+		{"1 ether + 500 finney in ether", "1.5", "换算优先级最低"},
+		{"21000 * 30 gwei in ether", "0.00063", "手续费换算为 ether"},
+		{"(2 ether in gwei) / 2", "1000000000", "括号内换算"},
+		{"1 ? 1 ether : 2 ether in gwei", "1000000000", "换算作用于整个条件表达式"},
+		{"0xde0b6b3a7640000 in ether", "1", "十六进制 wei"},
+		{"1 ether / 3 in gwei", "333333333.333333333", "不是整数 wei 时四舍五入"},
+		{"2 ether / 3 in wei", "666666666666666667", "换算为 wei 得到整数"},
+		{"-2 ether / 3 in ether", "-0.666666666666666667", "负数远离零舍入"},
+		{"1 wei / 2 in wei", "1", "半个 wei 远离零舍入"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v, err := NewEnv().Evaluate(test.expression)
+			require.NoError(t, err, "计算 %s 时不应该出错", test.expression)
+			assert.Equal(t, test.expected, FormatValue(v))
+		})
+	}
+}
+
+// TestEtherInModes 测试不同模式下金额保持精确
+func TestEtherInModes(t *testing.T) {
+	for _, mode := range []Mode{ModeFloat, ModeBig, ModeRat} {
+		t.Run(mode.String(), func(t *testing.T) {
+			assert.Equal(t, "123456789123456789123", evaluateIn(t, mode, "123.456789123456789123 ether"))
+		})
+	}
+}
+
+// TestEtherErrors 测试金额相关的错误
+func TestEtherErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		desc       string
+	}{
+		{"1.5 wei", "小数 wei"},
+		{"1 ether in dollars", "未知的目标单位"},
+		{"1 ether in", "缺少目标单位"},
+		{"1 ether in 5", "目标单位不是名称"},
+		{"0x", "空的十六进制数"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := NewEnv().Evaluate(test.expression)
+			assert.Error(t, err, "表达式 %s 应该产生错误", test.expression)
+		})
+	}
+}
+
+// TestEtherRounding 测试高精度模式下按金额输出时舍入到整数 wei
+func TestEtherRounding(t *testing.T) {
+	env := NewEnv()
+	require.NoError(t, env.SetMode(ModeBig))
+	v, err := env.Evaluate("sqrt(2) * 1 ether")
+	require.NoError(t, err)
+	s, err := Format(v, FormatOptions{Unit: "wei"})
+	require.NoError(t, err)
+	assert.Equal(t, "1414213562373095049 wei", s)
+
+	v, err = env.Evaluate("1 ether * 1.1 in gwei")
+	require.NoError(t, err)
+	assert.Equal(t, "1100000000", FormatValue(v), "big.Float 的舍入误差不会出现在金额中")
+}
+
+// TestFormatOptions 测试按单位、进制和科学计数法输出
+func TestFormatOptions(t *testing.T) {
+	tests := []struct {
+		expression string
+		opts       FormatOptions
+		expected   string
+		desc       string
+	}{
+		{"1.5 ether", FormatOptions{Unit: "gwei"}, "1500000000 gwei", "按 gwei 输出"},
+		{"1 gwei", FormatOptions{Unit: "ether"}, "0.000000001 ether", "按 ether 输出"},
+		{"1 ether", FormatOptions{Base: 16}, "0xde0b6b3a7640000", "十六进制输出"},
+		{"-255", FormatOptions{Base: 16}, "-0xff", "负数十六进制"},
+		{"255", FormatOptions{Base: 10}, "255", "十进制输出"},
+		{"16 gwei", FormatOptions{Unit: "gwei", Base: 16}, "0x10 gwei", "单位与十六进制组合"},
+		{"10", FormatOptions{Base: 2}, "0b1010", "二进制输出"},
+		{"-8", FormatOptions{Base: 8}, "-0o10", "负数八进制"},
+		{"1 ether", FormatOptions{Sci: true}, "1e18", "科学计数法"},
+		{"0.1 + 0.2", FormatOptions{Sci: true}, "3e-1", "科学计数法去掉舍入误差"},
+		{"-1234.5", FormatOptions{Sci: true}, "-1.2345e3", "负数科学计数法"},
+		{"0", FormatOptions{Sci: true}, "0e0", "零的科学计数法"},
+		{"21000 gwei", FormatOptions{Unit: "ether", Sci: true}, "2.1e-5 ether", "单位与科学计数法组合"},
+		{"1/3", FormatOptions{FullPrecision: true}, "0.3333333333333333", "float64 完整精度"},
+		{"[1/3, 2^60]", FormatOptions{FullPrecision: true}, "[0.3333333333333333, 1152921504606846976]", "列表完整精度"},
+		{"2^80", FormatOptions{FullPrecision: true}, "1.2089258196146292e+24", "完整精度的大数"},
+		{"1 ether / 3", FormatOptions{Unit: "gwei"}, "333333333.333333333 gwei", "按单位输出时舍入到整数 wei"},
+		{"1 ether / 3", FormatOptions{Unit: "wei"}, "333333333333333333 wei", "按 wei 输出总是整数"},
+		{"sqrt(2) * 1 ether", FormatOptions{Unit: "wei"}, "1414213562373095100 wei", "浮点结果按 wei 输出"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v, err := NewEnv().Evaluate(test.expression)
+			require.NoError(t, err)
+			s, err := Format(v, test.opts)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+
+	_, err := Format(Float(1.5), FormatOptions{Base: 16})
+	assert.Error(t, err, "小数不能按十六进制输出")
+	_, err = Format(Float(1), FormatOptions{Unit: "parsec"})
+	assert.Error(t, err, "未知单位")
+	_, err = Format(Float(1), FormatOptions{Base: 7})
+	assert.Error(t, err, "不支持的进制")
+	_, err = Format(Float(1), FormatOptions{Base: 16, Sci: true})
+	assert.Error(t, err, "科学计数法只能用于十进制")
+
+	for _, mode := range []Mode{ModeBig, ModeRat} {
+		env := NewEnv()
+		require.NoError(t, env.SetMode(mode))
+		v, err := env.Evaluate("1 / 4 * 1e-6")
+		require.NoError(t, err)
+		s, err := Format(v, FormatOptions{Sci: true})
+		require.NoError(t, err)
+		assert.Equal(t, "2.5e-7", s, "%s 模式", mode)
+	}
+}
+
+// TestEtherUnits 测试单位列表按数量级排列
+func TestEtherUnits(t *testing.T) {
+	units := EtherUnits()
+	assert.Equal(t, "wei", units[0])
+	assert.Contains(t, units, "gwei")
+	assert.True(t, IsEtherUnit("ether"))
+	assert.False(t, IsEtherUnit("bitcoin"))
+}
+
+// TestFormatContext 测试格式化受 ctx 的期限限制
+func TestFormatContext(t *testing.T) {
+	s, err := FormatContext(context.Background(), NewRat(1, 3), FormatOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1/3", s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err = FormatContext(ctx, NewRat(1, 4), FormatOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "0.25", s)
+
+	cancel()
+	_, err = FormatContext(ctx, NewRat(1, 4), FormatOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, CodeTimeout, ErrorCode(err))
+}
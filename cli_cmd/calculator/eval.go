@@ -0,0 +1,395 @@
+package calculator
+
+import (
+	"context"
+	"math"
+
+	"cli_cmd/i18n"
+)
+
+// evaluator 遍历语法树并计算结果
+type evaluator struct {
+	env    *Env
+	mode   Mode
+	prec   uint
+	locals map[string]Value // 用户函数的参数，优先于全局变量和常量
+	depth  int              // 用户函数调用的嵌套深度
+	ctx    context.Context  // 取消后停止调用用户函数，避免递归长时间运行
+}
+
+// Eval 对语法树求值，变量在一个临时环境中解析
+func Eval(node Node) (float64, error) {
+	return NewEnv().Eval(node)
+}
+
+func (e *evaluator) eval(node Node) (Value, error) {
+	switch n := node.(type) {
+	case *NumberLit:
+		if e.mode == ModeFloat && !isPrefixedLiteral(n.Literal) && !math.IsInf(n.Value, 0) {
+			return Float(n.Value), nil
+		}
+		return parseNumber(n.Literal, e.mode, e.prec)
+	case *UnitLit:
+		if IsEtherUnit(n.Unit) {
+			return etherAmount(n.Value.Literal, n.Unit)
+		}
+		return e.evalQuantity(n)
+	case *ConvertExpr:
+		return e.evalConvert(n)
+	case *Ident:
+		if v, ok := e.locals[n.Name]; ok {
+			return v, nil
+		}
+		if v, ok := e.env.GetValue(n.Name); ok {
+			return v, nil
+		}
+		if c, ok := e.env.registry.constant(n.Name); ok {
+			return c.value(e.mode, e.prec)
+		}
+		return nil, errorf("undefined_variable.name", n.Name)
+	case *TernaryExpr:
+		cond, err := e.number(n.Cond, msg("use.condition"))
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return e.eval(n.Then)
+		}
+		return e.eval(n.Else)
+	case *CallExpr:
+		return e.evalCall(n)
+	case *ListExpr:
+		return e.evalList(n)
+	case *Lambda:
+		return nil, errorf("invalid_argument.lambda", n)
+	case *AssignExpr:
+		if _, ok := e.env.registry.Const(n.Name.Name); ok {
+			return nil, errorf("constant.assign", n.Name.Name)
+		}
+		v, err := e.eval(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		e.env.SetValue(n.Name.Name, v)
+		return v, nil
+	case *ParenExpr:
+		return e.eval(n.X)
+	case *UnaryExpr:
+		return e.evalUnary(n)
+	case *BinaryExpr:
+		return e.evalBinary(n)
+	case *FuncDef:
+		return nil, errorf("definition.no_value", n.Name.Name)
+	}
+	return nil, errorf("internal.eval_node", node)
+}
+
+// evalCall 计算函数调用
+func (e *evaluator) evalCall(n *CallExpr) (Value, error) {
+	fn, ok := e.env.registry.Func(n.Fun.Name)
+	if !ok {
+		if uf, ok := e.env.UserFunc(n.Fun.Name); ok {
+			return e.callUser(uf, n.Args)
+		}
+		return nil, errorf("undefined_function.name", n.Fun.Name)
+	}
+	if fn.form != nil {
+		if err := fn.checkArgs(len(n.Args)); err != nil {
+			return nil, err
+		}
+		return fn.form(e, fn, n.Args)
+	}
+	args, err := e.evalArgs(fn, n.Args)
+	if err != nil {
+		return nil, err
+	}
+	if hasQuantity(args...) {
+		return e.callWithUnits(fn, args)
+	}
+	return e.callBuiltin(fn, args)
+}
+
+// evalQuantity 计算带单位的字面量，例如 5 km、60 km/h
+func (e *evaluator) evalQuantity(n *UnitLit) (Value, error) {
+	u, err := e.env.registry.parseUnit(n.Unit)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := e.eval(n.Value)
+	if err != nil {
+		return nil, err
+	}
+	return Quantity{Amount: amount, Unit: u}, nil
+}
+
+// evalConvert 计算单位换算：目标为以太坊单位时把 wei 换算为该单位，否则换算为量纲相同的单位
+func (e *evaluator) evalConvert(n *ConvertExpr) (Value, error) {
+	v, err := e.number(n.X, msg("use.convert"))
+	if err != nil {
+		return nil, err
+	}
+	if IsEtherUnit(n.Unit.Name) {
+		if q, ok := v.(Quantity); ok {
+			return nil, errorf("dimension.ether", q, n.Unit.Name)
+		}
+		return convertWei(v, n.Unit.Name, e.prec)
+	}
+	u, err := e.env.registry.parseUnit(n.Unit.Name)
+	if err != nil {
+		return nil, err
+	}
+	return convertUnit(v, u, e.prec)
+}
+
+// evalArgs 计算内置函数的参数并校验参数个数，聚合函数的列表参数展开为多个参数
+func (e *evaluator) evalArgs(fn *Function, argNodes []Node) ([]Value, error) {
+	if !fn.spread {
+		if err := fn.checkArgs(len(argNodes)); err != nil {
+			return nil, err
+		}
+	}
+	args := make([]Value, 0, len(argNodes))
+	for _, arg := range argNodes {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		l, isList := v.(List)
+		switch {
+		case isList && fn.spread:
+			args = append(args, l...)
+		case isList:
+			return nil, errorf("not_number.function_list", fn.Name, arg)
+		default:
+			args = append(args, v)
+		}
+	}
+	if fn.spread {
+		if err := fn.checkArgs(len(args)); err != nil {
+			return nil, errorf("argument_count.spread", err)
+		}
+	}
+	return args, nil
+}
+
+// callBuiltin 以求值后的参数调用内置函数
+func (e *evaluator) callBuiltin(fn *Function, args []Value) (Value, error) {
+	allFloat := true
+	for _, v := range args {
+		if _, ok := v.(Float); !ok {
+			allFloat = false
+		}
+	}
+
+	// 高精度参数优先使用精确实现
+	if fn.exact != nil && !allFloat {
+		return fn.exact(args, e.prec)
+	}
+
+	floats := make([]float64, len(args))
+	for i, v := range args {
+		floats[i] = v.Float64()
+	}
+	result, err := fn.Call(floats)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(result) {
+		return nil, errorf("domain.function", fn.Name)
+	}
+	return fromFloat(result, e.mode, e.prec)
+}
+
+// callUser 调用用户函数，函数体只能看到参数和全局变量
+func (e *evaluator) callUser(fn *UserFunc, argNodes []Node) (Value, error) {
+	if len(argNodes) != len(fn.Params) {
+		return nil, errorf("argument_count.user", fn.Name, len(fn.Params), len(argNodes))
+	}
+	args := make([]Value, len(argNodes))
+	for i, arg := range argNodes {
+		v, err := e.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return e.invoke(fn, args)
+}
+
+// invoke 以求值后的参数调用用户函数
+func (e *evaluator) invoke(fn *UserFunc, args []Value) (Value, error) {
+	if e.depth >= MaxCallDepth {
+		return nil, errorf("call_depth.exceeded", fn.Name, MaxCallDepth)
+	}
+	if err := e.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	locals := make(map[string]Value, len(fn.Params))
+	for i, v := range args {
+		locals[fn.Params[i]] = v
+	}
+	callee := evaluator{env: e.env, mode: e.mode, prec: e.prec, locals: locals, depth: e.depth + 1, ctx: e.ctx}
+	return callee.eval(fn.Body)
+}
+
+// evalUnary 计算一元表达式
+func (e *evaluator) evalUnary(n *UnaryExpr) (Value, error) {
+	x, err := e.number(n.X, operandUse(n.Op))
+	if err != nil {
+		return nil, err
+	}
+	switch n.Op {
+	case MINUS:
+		return negate(x), nil
+	case PLUS:
+		return x, nil
+	case NOT:
+		return boolValue(!truthy(x), x, e.prec), nil
+	}
+	return nil, errorf("internal.unary_operator", operatorSymbol(n.Op))
+}
+
+// evalBinary 计算二元表达式
+func (e *evaluator) evalBinary(n *BinaryExpr) (Value, error) {
+	use := operandUse(n.Op)
+	x, err := e.number(n.X, use)
+	if err != nil {
+		return nil, err
+	}
+
+	// 逻辑运算短路求值
+	switch n.Op {
+	case AND:
+		if !truthy(x) {
+			return boolValue(false, x, e.prec), nil
+		}
+		y, err := e.number(n.Y, use)
+		if err != nil {
+			return nil, err
+		}
+		return boolValue(truthy(y), x, e.prec), nil
+	case OR:
+		if truthy(x) {
+			return boolValue(true, x, e.prec), nil
+		}
+		y, err := e.number(n.Y, use)
+		if err != nil {
+			return nil, err
+		}
+		return boolValue(truthy(y), x, e.prec), nil
+	}
+
+	y, err := e.number(n.Y, use)
+	if err != nil {
+		return nil, err
+	}
+	if hasQuantity(x, y) {
+		return quantityArith(n.Op, x, y, e.prec)
+	}
+	if n.Op == DOTDOT {
+		return rangeList(x, y, e.prec)
+	}
+	return arith(n.Op, x, y, e.prec)
+}
+
+// operandUse 描述运算符的操作数，用于错误信息
+func operandUse(op TokenType) i18n.Localizer {
+	return msg("use.operand", operatorSymbol(op))
+}
+
+// binary 计算 float64 的非短路二元运算
+func binary(op TokenType, x, y float64) (float64, error) {
+	switch op {
+	case PLUS:
+		return x + y, nil
+	case MINUS:
+		return x - y, nil
+	case MULTIPLY:
+		return x * y, nil
+	case DIVIDE:
+		if y == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return x / y, nil
+	case MODULO:
+		if y == 0 {
+			return 0, errorf("division_by_zero.modulo")
+		}
+		// 浮点数取模，结果与被除数同号
+		return math.Mod(x, y), nil
+	case POWER:
+		if x == 0 && y < 0 {
+			return 0, ErrDivisionByZero
+		}
+		result := math.Pow(x, y)
+		if math.IsNaN(result) {
+			return 0, errorf("domain.power", FormatResult(x), FormatResult(y))
+		}
+		// 与 big、rat 模式一致，溢出时报错而不是返回 +Inf
+		if math.IsInf(result, 0) {
+			return 0, errorf("out_of_range.power")
+		}
+		return result, nil
+	case EQ:
+		return boolToFloat(x == y), nil
+	case NEQ:
+		return boolToFloat(x != y), nil
+	case LT:
+		return boolToFloat(x < y), nil
+	case LE:
+		return boolToFloat(x <= y), nil
+	case GT:
+		return boolToFloat(x > y), nil
+	case GE:
+		return boolToFloat(x >= y), nil
+	case BITAND, BITOR, BITXOR, SHL, SHR:
+		return bitwise(op, x, y)
+	}
+	return 0, errorf("internal.binary_operator", operatorSymbol(op))
+}
+
+// bitwise 计算整数位运算
+func bitwise(op TokenType, x, y float64) (float64, error) {
+	a, ok := toInt64(x)
+	if !ok {
+		return 0, errorf("not_integer.bitwise", operatorSymbol(op), FormatResult(x))
+	}
+	b, ok := toInt64(y)
+	if !ok {
+		return 0, errorf("not_integer.bitwise", operatorSymbol(op), FormatResult(y))
+	}
+
+	switch op {
+	case BITAND:
+		return float64(a & b), nil
+	case BITOR:
+		return float64(a | b), nil
+	case BITXOR:
+		return float64(a ^ b), nil
+	}
+
+	if b < 0 || b > 63 {
+		return 0, errorf("invalid_argument.shift", 63, b)
+	}
+	if op == SHL {
+		return float64(a << uint(b)), nil
+	}
+	return float64(a >> uint(b)), nil
+}
+
+// toInt64 把整数值的浮点数转换为 int64
+func toInt64(x float64) (int64, bool) {
+	if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(x), true
+}
+
+// boolToFloat 把布尔值转换为 1 或 0
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+package calculator
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// FormatOptions 结果输出选项
+type FormatOptions struct {
+	// Unit 以太坊单位，非空时把结果视为 wei 并换算为该单位输出，例如 "gwei"
+	Unit string
+	// Base 输出进制，0 或 10 表示十进制；2、8、16 分别以 0b、0o、0x 开头输出（仅限整数）
+	Base int
+	// Sci 以科学计数法输出十进制结果，例如 1.5e18
+	Sci bool
+	// FullPrecision float64 结果输出能还原原值的最短表示，而不是保留 6 位有效数字，用于 JSON、CSV 等机器可读的输出
+	FullPrecision bool
+}
+
+// basePrefixes 各输出进制的前缀
+var basePrefixes = map[int]string{2: "0b", 8: "0o", 16: "0x"}
+
+// Format 按选项格式化数值，列表按选项格式化每个元素；带单位的数量按选项格式化数量部分
+func Format(v Value, opts FormatOptions) (string, error) {
+	if l, ok := v.(List); ok {
+		return formatList(l, func(v Value) (string, error) { return Format(v, opts) })
+	}
+	if q, ok := v.(Quantity); ok {
+		if opts.Unit != "" {
+			return "", errorf("format.quantity_ether", opts.Unit, q)
+		}
+		s, err := Format(q.Amount, opts)
+		if err != nil {
+			return "", err
+		}
+		return s + " " + q.Unit.String(), nil
+	}
+
+	suffix := ""
+	if opts.Unit != "" {
+		converted, err := convertWei(v, opts.Unit, DefaultPrecision)
+		if err != nil {
+			return "", err
+		}
+		v = converted
+		suffix = " " + opts.Unit
+	}
+
+	switch opts.Base {
+	case 0, 10:
+		if opts.Sci {
+			return formatSci(v) + suffix, nil
+		}
+		if f, ok := v.(Float); ok && opts.FullPrecision {
+			return formatFloatFull(float64(f)) + suffix, nil
+		}
+		return FormatValue(v) + suffix, nil
+	case 2, 8, 16:
+		if opts.Sci {
+			return "", errorf("format.sci_base")
+		}
+		s, err := formatBase(v, opts.Base)
+		if err != nil {
+			return "", err
+		}
+		return s + suffix, nil
+	}
+	return "", errorf("format.base", opts.Base)
+}
+
+// FormatContext 与 Format 相同，ctx 取消或超时后不再等待格式化完成，返回 ctx 的错误
+//
+// 很大的精确数值（例如 rat 模式下几百万位的整数）转换为十进制可能需要几秒，
+// 服务端用它把格式化也限制在请求的期限内。
+func FormatContext(ctx context.Context, v Value, opts FormatOptions) (string, error) {
+	if ctx.Done() == nil {
+		return Format(v, opts)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", errorf("stopped.err", err)
+	}
+	type result struct {
+		s   string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		s, err := Format(v, opts)
+		done <- result{s, err}
+	}()
+	select {
+	case r := <-done:
+		return r.s, r.err
+	case <-ctx.Done():
+		return "", errorf("stopped.err", ctx.Err())
+	}
+}
+
+// formatBase 以 0b、0o 或 0x 开头的格式输出整数
+func formatBase(v Value, base int) (string, error) {
+	n, ok := toBigInt(v)
+	if !ok {
+		return "", errorf("format.not_integer", baseNames[base], FormatValue(v))
+	}
+	prefix := basePrefixes[base]
+	if n.Sign() < 0 {
+		return "-" + prefix + new(big.Int).Neg(n).Text(base), nil
+	}
+	return prefix + n.Text(base), nil
+}
+
+// formatFloatFull 输出能还原 float64 的最短十进制表示，整数按精确值输出，不使用指数形式
+func formatFloatFull(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return strconv.FormatFloat(f, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// sciDigits float 模式下科学计数法的最大有效位数，去掉 0.1+0.2 这样的舍入误差
+const sciDigits = 15
+
+// formatSci 以科学计数法输出数值，格式与数字字面量相同，例如 1.5e18、2.5e-7
+func formatSci(v Value) string {
+	var s string
+	switch x := v.(type) {
+	case Float:
+		s = strconv.FormatFloat(float64(x), 'e', sciDigits-1, 64)
+	case BigFloat:
+		if x.Float == nil {
+			return "0e0"
+		}
+		s = x.Text('e', bigDigits(x.Prec())-1)
+	case Rat:
+		if x.Rat == nil {
+			return "0e0"
+		}
+		f := new(big.Float).SetPrec(DefaultPrecision).SetRat(x.Rat)
+		s = f.Text('e', bigDigits(DefaultPrecision)-1)
+	default:
+		s = strconv.FormatFloat(v.Float64(), 'e', sciDigits-1, 64)
+	}
+	return tidySci(s)
+}
+
+// tidySci 去掉尾数末尾的 0 和指数中的 + 号及前导 0，例如 1.500e+07 => 1.5e7
+func tidySci(s string) string {
+	i := strings.IndexAny(s, "eE")
+	if i < 0 {
+		return s
+	}
+	mantissa, exp := s[:i], s[i+1:]
+	if strings.Contains(mantissa, ".") {
+		mantissa = strings.TrimRight(strings.TrimRight(mantissa, "0"), ".")
+	}
+	sign := ""
+	switch exp[0] {
+	case '-':
+		sign, exp = "-", exp[1:]
+	case '+':
+		exp = exp[1:]
+	}
+	exp = strings.TrimLeft(exp, "0")
+	if exp == "" {
+		exp, sign = "0", ""
+	}
+	return mantissa + "e" + sign + exp
+}
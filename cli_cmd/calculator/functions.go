@@ -0,0 +1,399 @@
+package calculator
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// Variadic 表示函数可以接受任意多个参数
+const Variadic = -1
+
+// Func 函数实现，参数个数已经由注册表校验
+type Func func(args []float64) (float64, error)
+
+// exactFunc 高精度模式下的函数实现，prec 为 big.Float 的精度
+type exactFunc func(args []Value, prec uint) (Value, error)
+
+// Function 注册表中的函数
+//
+// 高精度模式下调用 Call 时参数先转换为 float64，结果再转换回当前模式；
+// 部分内置函数带有精确实现，不会损失精度。
+// 聚合函数（sum、avg 等）的列表参数展开为多个参数，参数个数按展开后的个数校验。
+// 通过 Register 等方法注册的函数不接受带单位的参数。
+type Function struct {
+	Name     string
+	MinArgs  int // 最少参数个数
+	MaxArgs  int // 最多参数个数，Variadic 表示不限
+	Call     Func
+	exact    exactFunc
+	spread   bool     // 展开列表参数
+	form     listForm // 不为 nil 时参数不先求值，例如 map、filter
+	keepUnit bool     // 参数可以带量纲相同的单位，结果保持第一个参数的单位
+}
+
+// checkArgs 校验参数个数
+func (f *Function) checkArgs(n int) error {
+	if n < f.MinArgs || (f.MaxArgs != Variadic && n > f.MaxArgs) {
+		switch {
+		case f.MaxArgs == Variadic:
+			return errorf("argument_count.min", f.Name, f.MinArgs, n)
+		case f.MinArgs == f.MaxArgs:
+			return errorf("argument_count.exact", f.Name, f.MinArgs, n)
+		default:
+			return errorf("argument_count.range", f.Name, f.MinArgs, f.MaxArgs, n)
+		}
+	}
+	return nil
+}
+
+// Registry 函数、常量和单位注册表
+//
+// Registry 可以被多个 goroutine 并发使用。
+type Registry struct {
+	mu     sync.RWMutex
+	funcs  map[string]*Function
+	consts map[string]constant
+	units  map[string]unitDef
+}
+
+// constant 注册表中的常量
+type constant struct {
+	f    float64
+	text string // 十进制文本，高精度模式下按此解析；为空时使用 f
+}
+
+// value 按计算模式返回常量的值
+func (c constant) value(mode Mode, prec uint) (Value, error) {
+	if mode == ModeFloat || c.text == "" {
+		return fromFloat(c.f, mode, prec)
+	}
+	return parseNumber(c.text, mode, prec)
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		funcs:  make(map[string]*Function),
+		consts: make(map[string]constant),
+		units:  make(map[string]unitDef),
+	}
+}
+
+// DefaultRegistry 包含内置函数、常量和单位的默认注册表，NewEnv 创建的环境使用它
+var DefaultRegistry = newBuiltinRegistry()
+
+// RegisterFunc 向默认注册表添加固定参数个数的函数
+func RegisterFunc(name string, nargs int, fn Func) error {
+	return DefaultRegistry.Register(name, nargs, fn)
+}
+
+// Register 注册固定参数个数的函数，同名函数会被覆盖
+func (r *Registry) Register(name string, nargs int, fn Func) error {
+	return r.register(name, nargs, nargs, fn)
+}
+
+// RegisterVariadic 注册参数个数可变的函数，至少需要 minArgs 个参数
+func (r *Registry) RegisterVariadic(name string, minArgs int, fn Func) error {
+	return r.register(name, minArgs, Variadic, fn)
+}
+
+// RegisterRange 注册参数个数在 [minArgs, maxArgs] 范围内的函数
+func (r *Registry) RegisterRange(name string, minArgs, maxArgs int, fn Func) error {
+	return r.register(name, minArgs, maxArgs, fn)
+}
+
+func (r *Registry) register(name string, minArgs, maxArgs int, fn Func) error {
+	if !isValidName(name) {
+		return errorf("definition.func_name", name)
+	}
+	if fn == nil {
+		return errorf("definition.func_impl", name)
+	}
+	if minArgs < 0 || (maxArgs != Variadic && maxArgs < minArgs) {
+		return errorf("definition.func_arity", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs[name] = &Function{Name: name, MinArgs: minArgs, MaxArgs: maxArgs, Call: fn}
+	return nil
+}
+
+// RegisterConst 注册常量，同名常量会被覆盖
+func (r *Registry) RegisterConst(name string, value float64) error {
+	return r.registerConst(name, constant{f: value})
+}
+
+// RegisterConstText 以十进制文本注册常量，高精度模式下按文本解析以保留全部有效数字
+func (r *Registry) RegisterConstText(name, text string) error {
+	f, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return errorf("definition.const_value", name, text)
+	}
+	v, _ := f.Float64()
+	return r.registerConst(name, constant{f: v, text: text})
+}
+
+func (r *Registry) registerConst(name string, c constant) error {
+	if !isValidName(name) {
+		return errorf("definition.const_name", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consts[name] = c
+	return nil
+}
+
+// Func 查找函数
+func (r *Registry) Func(name string) (*Function, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.funcs[name]
+	return f, ok
+}
+
+// Const 查找常量
+func (r *Registry) Const(name string) (float64, bool) {
+	c, ok := r.constant(name)
+	return c.f, ok
+}
+
+func (r *Registry) constant(name string) (constant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.consts[name]
+	return c, ok
+}
+
+// FuncNames 返回按字母顺序排列的函数名
+func (r *Registry) FuncNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.funcs))
+	for name := range r.funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ConstNames 返回按字母顺序排列的常量名
+func (r *Registry) ConstNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.consts))
+	for name := range r.consts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Clone 复制注册表，便于在内置函数基础上扩展而不影响默认注册表
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := NewRegistry()
+	for name, f := range r.funcs {
+		copied := *f
+		c.funcs[name] = &copied
+	}
+	for name, v := range r.consts {
+		c.consts[name] = v
+	}
+	for name, u := range r.units {
+		c.units[name] = u
+	}
+	return c
+}
+
+// isValidName 判断名称是否是合法的标识符
+func isValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, ch := range name {
+		if (i == 0 && !isIdentStart(ch)) || !isIdentChar(ch) {
+			return false
+		}
+	}
+	return true
+}
+
+// unary 把单参数的 math 函数包装为 Func
+func unary(fn func(float64) float64) Func {
+	return func(args []float64) (float64, error) {
+		return fn(args[0]), nil
+	}
+}
+
+// setExact 为已注册的函数添加高精度实现
+func (r *Registry) setExact(name string, fn exactFunc) {
+	r.funcs[name].exact = fn
+}
+
+// 内置常量的十进制文本，保留 100 位有效数字
+const (
+	piText = "3.141592653589793238462643383279502884197169399375105820974944592307816406286208998628034825342117068"
+	eText  = "2.718281828459045235360287471352662497757247093699959574966967627724076630353547594571382178525166427"
+)
+
+// newBuiltinRegistry 创建包含内置函数、常量和单位的注册表
+func newBuiltinRegistry() *Registry {
+	r := NewRegistry()
+
+	r.RegisterConstText("pi", piText)
+	r.RegisterConstText("e", eText)
+
+	r.Register("sqrt", 1, func(args []float64) (float64, error) {
+		if args[0] < 0 {
+			return 0, errorf("domain.sqrt")
+		}
+		return math.Sqrt(args[0]), nil
+	})
+	r.Register("pow", 2, func(args []float64) (float64, error) {
+		return binary(POWER, args[0], args[1])
+	})
+	r.Register("abs", 1, unary(math.Abs))
+	r.Register("floor", 1, unary(math.Floor))
+	r.Register("ceil", 1, unary(math.Ceil))
+	r.Register("round", 1, unary(math.Round))
+	r.Register("exp", 1, unary(math.Exp))
+
+	r.RegisterVariadic("min", 1, func(args []float64) (float64, error) {
+		result := args[0]
+		for _, v := range args[1:] {
+			result = math.Min(result, v)
+		}
+		return result, nil
+	})
+	r.RegisterVariadic("max", 1, func(args []float64) (float64, error) {
+		result := args[0]
+		for _, v := range args[1:] {
+			result = math.Max(result, v)
+		}
+		return result, nil
+	})
+
+	// log(x) 为常用对数，log(x, b) 为以 b 为底的对数
+	r.RegisterRange("log", 1, 2, func(args []float64) (float64, error) {
+		if args[0] <= 0 {
+			return 0, errorf("domain.log")
+		}
+		if len(args) == 1 {
+			return math.Log10(args[0]), nil
+		}
+		if args[1] <= 0 || args[1] == 1 {
+			return 0, errorf("domain.log_base")
+		}
+		return math.Log(args[0]) / math.Log(args[1]), nil
+	})
+	r.Register("ln", 1, func(args []float64) (float64, error) {
+		if args[0] <= 0 {
+			return 0, errorf("domain.ln")
+		}
+		return math.Log(args[0]), nil
+	})
+
+	r.Register("sin", 1, unary(math.Sin))
+	r.Register("cos", 1, unary(math.Cos))
+	r.Register("tan", 1, unary(math.Tan))
+	r.Register("asin", 1, unary(math.Asin))
+	r.Register("acos", 1, unary(math.Acos))
+	r.Register("atan", 1, unary(math.Atan))
+	r.Register("atan2", 2, func(args []float64) (float64, error) {
+		return math.Atan2(args[0], args[1]), nil
+	})
+
+	r.setExact("abs", func(args []Value, prec uint) (Value, error) {
+		if sign(args[0]) < 0 {
+			return negate(args[0]), nil
+		}
+		return args[0], nil
+	})
+	r.setExact("floor", roundingFunc(ratFloor))
+	r.setExact("ceil", roundingFunc(ratCeil))
+	r.setExact("round", roundingFunc(ratRound))
+	r.setExact("min", extremumFunc(-1))
+	r.setExact("max", extremumFunc(1))
+	r.setExact("pow", func(args []Value, prec uint) (Value, error) {
+		return power(args[0], args[1], prec)
+	})
+	r.setExact("sqrt", func(args []Value, prec uint) (Value, error) {
+		if sign(args[0]) < 0 {
+			return nil, errorf("domain.sqrt")
+		}
+		switch x := args[0].(type) {
+		case BigFloat:
+			return BigFloat{new(big.Float).SetPrec(resultPrec(prec, x)).Sqrt(x.Float)}, nil
+		case Rat:
+			// 分子分母都是完全平方数时结果精确，否则按浮点数近似
+			num, den := new(big.Int).Sqrt(x.Num()), new(big.Int).Sqrt(x.Denom())
+			if new(big.Int).Mul(num, num).Cmp(x.Num()) == 0 && new(big.Int).Mul(den, den).Cmp(x.Denom()) == 0 {
+				return Rat{new(big.Rat).SetFrac(num, den)}, nil
+			}
+			return floatToRat(math.Sqrt(x.Float64()))
+		}
+		return Float(math.Sqrt(args[0].Float64())), nil
+	})
+
+	registerListFuncs(r)
+	for _, name := range []string{"abs", "floor", "ceil", "round", "min", "max", "sum", "avg", "median", "stddev"} {
+		r.funcs[name].keepUnit = true
+	}
+	registerUnits(r)
+	return r
+}
+
+// roundingFunc 把有理数取整函数包装为高精度实现，结果保持参数的类型
+func roundingFunc(fn func(*big.Rat) *big.Int) exactFunc {
+	return func(args []Value, prec uint) (Value, error) {
+		r, err := toRat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		result := Rat{new(big.Rat).SetInt(fn(r.Rat))}
+		if _, ok := args[0].(BigFloat); ok {
+			return toBigFloat(result, prec)
+		}
+		return result, nil
+	}
+}
+
+// ratFloor 向下取整
+func ratFloor(r *big.Rat) *big.Int {
+	// big.Int.Div 是欧几里得除法，分母为正时即为向下取整
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+// ratCeil 向上取整
+func ratCeil(r *big.Rat) *big.Int {
+	return new(big.Int).Neg(ratFloor(new(big.Rat).Neg(r)))
+}
+
+// ratRound 四舍五入，与 math.Round 一样远离零取整
+func ratRound(r *big.Rat) *big.Int {
+	half := big.NewRat(1, 2)
+	if r.Sign() < 0 {
+		return new(big.Int).Neg(ratFloor(new(big.Rat).Add(new(big.Rat).Neg(r), half)))
+	}
+	return ratFloor(new(big.Rat).Add(r, half))
+}
+
+// extremumFunc 返回求最小值（want 为 -1）或最大值（want 为 1）的高精度实现
+func extremumFunc(want int) exactFunc {
+	return func(args []Value, prec uint) (Value, error) {
+		result := args[0]
+		for _, v := range args[1:] {
+			c, err := compare(v, result, prec)
+			if err != nil {
+				return nil, err
+			}
+			if c == want {
+				result = v
+			}
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,136 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuiltinFunctions 测试内置函数
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+		desc       string
+	}{
+		{"sqrt(16)", 4, "平方根"},
+		{"pow(2,10)", 1024, "幂"},
+		{"min(3, 1, 2)", 1, "多参数最小值"},
+		{"max(3, 1, 2, 7.5)", 7.5, "多参数最大值"},
+		{"max(4)", 4, "单参数最大值"},
+		{"abs(-3)", 3, "绝对值"},
+		{"floor(2.7)", 2, "向下取整"},
+		{"ceil(2.1)", 3, "向上取整"},
+		{"round(2.5)", 3, "四舍五入"},
+		{"log(1000)", 3, "常用对数"},
+		{"log(8, 2)", 3, "指定底数的对数"},
+		{"ln(e)", 1, "自然对数"},
+		{"exp(0)", 1, "指数"},
+		{"sin(0)", 0, "正弦"},
+		{"cos(pi)", -1, "余弦"},
+		{"atan2(1, 1)*4", math.Pi, "反正切"},
+		{"sqrt(pow(3,2)+pow(4,2))", 5, "嵌套调用"},
+		{"2*pi", 2 * math.Pi, "常量参与运算"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result, err := Calculate(test.expression)
+			require.NoError(t, err, "计算 %s 时不应该出错", test.expression)
+			assert.InDelta(t, test.expected, result, 1e-9, "表达式 %s 的结果应该接近 %f", test.expression, test.expected)
+		})
+	}
+}
+
+// TestFunctionErrors 测试函数调用错误
+func TestFunctionErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		desc       string
+	}{
+		{"sqrt(-1)", "负数平方根"},
+		{"sqrt()", "缺少参数"},
+		{"pow(2)", "参数不足"},
+		{"pow(1,2,3)", "参数过多"},
+		{"min()", "可变参数函数缺少参数"},
+		{"log(0)", "对数定义域"},
+		{"log(8, 1)", "对数底数"},
+		{"asin(2)", "结果为 NaN"},
+		{"nosuch(1)", "未定义函数"},
+		{"max(1,)", "结尾逗号"},
+		{"max(1 2)", "缺少逗号"},
+		{"pi = 3", "给常量赋值"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Calculate(test.expression)
+			assert.Error(t, err, "表达式 %s 应该产生错误", test.expression)
+		})
+	}
+}
+
+// TestCustomRegistry 测试调用方注册自定义函数
+func TestCustomRegistry(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	require.NoError(t, r.Register("double", 1, func(args []float64) (float64, error) {
+		return args[0] * 2, nil
+	}))
+	require.NoError(t, r.RegisterVariadic("sum", 0, func(args []float64) (float64, error) {
+		total := 0.0
+		for _, v := range args {
+			total += v
+		}
+		return total, nil
+	}))
+	require.NoError(t, r.RegisterConst("gwei", 1e9))
+
+	env := NewEnvWithRegistry(r)
+	result, err := env.Calculate("double(sum(1, 2, 3)) + sum() + sqrt(4)")
+	require.NoError(t, err)
+	assert.Equal(t, 14.0, result)
+
+	result, err = env.Calculate("30 * gwei")
+	require.NoError(t, err)
+	assert.Equal(t, 3e10, result)
+
+	// 默认注册表不受影响
+	_, err = Calculate("double(1)")
+	assert.Error(t, err)
+}
+
+// TestRegisterErrors 测试非法注册
+func TestRegisterErrors(t *testing.T) {
+	r := NewRegistry()
+	noop := func(args []float64) (float64, error) { return 0, nil }
+
+	assert.Error(t, r.Register("", 1, noop), "空名称")
+	assert.Error(t, r.Register("1abc", 1, noop), "数字开头")
+	assert.Error(t, r.Register("a-b", 1, noop), "非法字符")
+	assert.Error(t, r.Register("f", 1, nil), "空实现")
+	assert.Error(t, r.RegisterRange("f", 2, 1, noop), "参数范围无效")
+	assert.Error(t, r.RegisterConst("", 1), "空常量名")
+}
+
+// TestFunctionErrorPropagation 测试自定义函数返回的错误
+func TestFunctionErrorPropagation(t *testing.T) {
+	r := NewRegistry()
+	require.NoError(t, r.Register("fail", 0, func(args []float64) (float64, error) {
+		return 0, fmt.Errorf("boom")
+	}))
+
+	_, err := NewEnvWithRegistry(r).Calculate("1 + fail()")
+	assert.EqualError(t, err, "boom")
+}
+
+// TestVariablesShadowConstants 测试变量优先于常量
+func TestVariablesShadowConstants(t *testing.T) {
+	env := NewEnv()
+	env.Set("e", 2)
+	result, err := env.Calculate("e * 3")
+	require.NoError(t, err)
+	assert.Equal(t, 6.0, result)
+}
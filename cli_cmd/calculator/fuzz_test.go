@@ -0,0 +1,242 @@
+package calculator
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedExpressions 模糊测试的种子语料，取自各个测试文件中的用例
+var seedExpressions = []string{
+	// parser_test.go
+	"1+2", "10%3", "2+3*4-1", "((1+2)*3)+1", "(2+3)*(4-1)", "100/10/2", "10.5-0.5",
+	"-5+3", "5+-3", "(-5+3)*2", " 1 + 2 ", "\t1+2\t", "", "1+", "*2", "(1+2", "1+2)",
+	"1/0", "1%0", "abc", "1*/2",
+	// operators_test.go
+	"2^3^2", "(2^3)^2", "2**10", "-2^2", "2^-1", "(-8)^(1/3)", "6 xor 3", "1 | 6 xor 3",
+	"-8 >> 1", "1 << 64", "1.5 & 1", "1 < 2 == 1", "0 || 1 && 0", "0 && 1/0", "!!5",
+	"0 ? 1 : 0 ? 2 : 3", "1 ? 0 ? 4 : 5 : 6", "max(0 ? 1 : 2, 1)", "1 ? : 2", "1 & & 2", "xor 1",
+	// ether_test.go
+	"1.5ether", "21000 * 30 gwei in ether", "0xde0b6b3a7640000 in ether", "1 ether in dollars",
+	"1 ? 1 ether : 2 ether in gwei", "0.000000000000000001 ether", "1.5 wei",
+	// errors_test.go
+	"1 + 2 = 3", "1 +\n  * 2", "1 @ 2", "π × 2", "√4 + 1", "sqrt(2) + foo bar", "x = 1 # 注释",
+	// literal_test.go
+	"1e18", "2.5E-3*x", "1e18wei", "1_000_000", "0x_ff_ff", "0b1010 + 0o17", "1.2.3", "0b102", "1__0", ".",
+	// userfunc_test.go
+	"f(x, y) = x^2 + y", "a = f(x) = x", "f(1) = 2",
+	// list_test.go
+	"[1, 2, 3]", "[]", "3..-1", "[0, 1..3, 10]", "sum([1, 2], 3)", "percentile(1..101, 90)",
+	"map(1..4, x => x ^ 2)", "filter(1..10, x => x % 3 == 0)", "map([4, 9], sqrt)", "1.5..3", "x => x",
+	// units_test.go
+	"5 km / 2 h in m/s", "3 MB * 8 in Mbit", "60 km/h", "9.81 kg*m/s^2", "2 m^-1", "2 m^ 2", "(2 km) ^ 2",
+	"1 m + 1 s", "sum([3 GB, 500 MB])", "1 ether/h", "1 km / 1 m",
+}
+
+// sameResult 判断两次计算的结果是否一致：都出错，或者值相同（NaN 视为相同）
+func sameResult(a float64, aErr error, b float64, bErr error) bool {
+	if aErr != nil || bErr != nil {
+		return aErr != nil && bErr != nil
+	}
+	return a == b || math.IsNaN(a) && math.IsNaN(b)
+}
+
+// hasPrefixedLiteral 判断语法树中是否有 0x、0b、0o 字面量，这类字面量按精确整数计算
+func hasPrefixedLiteral(node Node) bool {
+	found := false
+	Walk(node, func(n Node) bool {
+		if lit, ok := n.(*NumberLit); ok && isPrefixedLiteral(lit.Literal) {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// FuzzCalculate 任意输入都不能使解析和计算 panic，错误的输入必须一致地报告为语法错误
+func FuzzCalculate(f *testing.F) {
+	for _, expr := range seedExpressions {
+		f.Add(expr)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		value, err := Calculate(input)
+		again, againErr := Calculate(input)
+		require.True(t, sameResult(value, err, again, againErr), "同一个表达式的结果应该相同")
+
+		node, parseErr := Parse(input)
+		if parseErr != nil {
+			require.Error(t, err, "无法解析的表达式不能计算成功")
+			var syntaxErr *SyntaxError
+			require.ErrorAs(t, parseErr, &syntaxErr)
+			require.True(t, syntaxErr.Offset >= 0 && syntaxErr.Offset <= len(input), "错误位置 %d 超出输入范围", syntaxErr.Offset)
+			require.GreaterOrEqual(t, syntaxErr.Line, 1)
+			require.GreaterOrEqual(t, syntaxErr.Column, 1)
+			_ = syntaxErr.Snippet()
+			return
+		}
+
+		// 输出的表达式能重新解析为相同的语法树，并且计算结果相同
+		printed := FormatNode(node)
+		reparsed, reparseErr := Parse(printed)
+		require.NoError(t, reparseErr, "%q 输出为 %q 后无法解析", input, printed)
+		require.Equal(t, printed, FormatNode(reparsed))
+		printedValue, printedErr := Calculate(printed)
+		require.True(t, sameResult(value, err, printedValue, printedErr),
+			"%q = %v (%v)，但 %q = %v (%v)", input, value, err, printed, printedValue, printedErr)
+
+		// 字节码与遍历语法树的结果相同；带前缀的整数按精确值计算，可能与 float64 有舍入差异
+		if hasPrefixedLiteral(node) {
+			return
+		}
+		if prog, compileErr := Compile(input); compileErr == nil {
+			compiled, compiledErr := prog.Eval(nil)
+			require.True(t, sameResult(value, err, compiled, compiledErr),
+				"%q = %v (%v)，但编译后为 %v (%v)", input, value, err, compiled, compiledErr)
+		}
+	})
+}
+
+// FuzzLexer 词法分析总能结束，每个标记都对应输入中的一段文本
+func FuzzLexer(f *testing.F) {
+	for _, expr := range seedExpressions {
+		f.Add(expr)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		lexer := NewLexer(input)
+		end := 0
+		for i := 0; ; i++ {
+			require.LessOrEqual(t, i, len(input)+1, "标记数量不能超过输入长度")
+			tok := lexer.NextToken()
+			require.GreaterOrEqual(t, tok.Pos, end, "标记的位置必须递增")
+			require.LessOrEqual(t, tok.Pos+len(tok.Value), len(input))
+			require.Equal(t, input[tok.Pos:tok.Pos+len(tok.Value)], tok.Value)
+			if tok.Type == EOF {
+				require.Equal(t, len(input), tok.Pos, "EOF 位于输入末尾")
+				return
+			}
+			require.NotEmpty(t, tok.Value, "只有 EOF 可以为空")
+			end = tok.Pos + len(tok.Value)
+
+			if tok.Type == NUMBER {
+				n, reason := scanNumber(tok.Value)
+				require.Equal(t, len(tok.Value), n)
+				require.Empty(t, reason)
+				_, _ = parseExactLiteral(tok.Value)
+				_, _ = parseFloatLiteral(tok.Value)
+			}
+		}
+	})
+}
+
+// operandLiteral 按 strconv 的最短格式输出数字，负数加括号
+func operandLiteral(x float64) string {
+	s := strconv.FormatFloat(x, 'g', -1, 64)
+	if x < 0 {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// TestCommutativity 测试加法和乘法的交换律
+func TestCommutativity(t *testing.T) {
+	config := &quick.Config{MaxCount: 500, Rand: rand.New(rand.NewSource(1))}
+	for _, op := range []string{"+", "*"} {
+		property := func(a, b float64) bool {
+			x, errX := Calculate(operandLiteral(a) + op + operandLiteral(b))
+			y, errY := Calculate(operandLiteral(b) + " " + op + " " + operandLiteral(a))
+			return sameResult(x, errX, y, errY)
+		}
+		assert.NoError(t, quick.Check(property, config), "%s 应该满足交换律", op)
+	}
+
+	property := func(a, b int32) bool {
+		x, errX := Calculate(operandLiteral(float64(a)) + " xor " + operandLiteral(float64(b)))
+		y, errY := Calculate(operandLiteral(float64(b)) + " xor " + operandLiteral(float64(a)))
+		return sameResult(x, errX, y, errY)
+	}
+	assert.NoError(t, quick.Check(property, config), "xor 应该满足交换律")
+}
+
+// exprStyle 随机表达式的输出风格
+type exprStyle struct {
+	space  func() string // 标记之间的空白
+	parens int           // 每个子表达式外的括号层数
+}
+
+// randomExpr 生成随机表达式，返回按指定风格输出表达式的函数
+func randomExpr(r *rand.Rand, depth int) func(exprStyle) string {
+	leaves := []string{"0", "1", "2", "3.5", "0.25", "10", "1e3", "1_000", "0x1f", "pi", "e"}
+	if depth <= 0 || r.Intn(4) == 0 {
+		leaf := leaves[r.Intn(len(leaves))]
+		return func(exprStyle) string { return leaf }
+	}
+
+	wrap := func(st exprStyle, s string) string {
+		return strings.Repeat("("+st.space(), st.parens) + s + strings.Repeat(st.space()+")", st.parens)
+	}
+	switch r.Intn(5) {
+	case 0:
+		op := []string{"-", "!"}[r.Intn(2)]
+		x := randomExpr(r, depth-1)
+		return func(st exprStyle) string {
+			return "(" + wrap(st, op+st.space()+"("+x(st)+")") + ")"
+		}
+	case 1:
+		fn := []string{"sqrt", "abs", "floor", "max", "min"}[r.Intn(5)]
+		x, y := randomExpr(r, depth-1), randomExpr(r, depth-1)
+		return func(st exprStyle) string {
+			args := x(st)
+			if fn == "max" || fn == "min" {
+				args += st.space() + "," + st.space() + y(st)
+			}
+			return fn + "(" + st.space() + args + st.space() + ")"
+		}
+	case 2:
+		cond, then, els := randomExpr(r, depth-1), randomExpr(r, depth-1), randomExpr(r, depth-1)
+		return func(st exprStyle) string {
+			return "(" + wrap(st, cond(st)+st.space()+"?"+st.space()+then(st)+st.space()+":"+st.space()+els(st)) + ")"
+		}
+	}
+	ops := []string{"+", "-", "*", "/", "%", "^", "<", "==", "&&", "||", "&", "<<"}
+	op := ops[r.Intn(len(ops))]
+	x, y := randomExpr(r, depth-1), randomExpr(r, depth-1)
+	return func(st exprStyle) string {
+		return "(" + wrap(st, x(st)+st.space()+op+st.space()+y(st)) + ")"
+	}
+}
+
+// TestRedundantSyntax 测试空白和多余的括号不影响计算结果
+func TestRedundantSyntax(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	none := func() string { return "" }
+	spaces := func() string {
+		blank := []string{" ", "\t", "\n"}[r.Intn(3)]
+		return strings.Repeat(blank, r.Intn(3))
+	}
+
+	for i := 0; i < 500; i++ {
+		expr := randomExpr(r, 4)
+		canonical := expr(exprStyle{space: func() string { return " " }})
+		expected, expectedErr := Calculate(canonical)
+
+		node, err := Parse(canonical)
+		require.NoError(t, err, canonical)
+		variants := []string{
+			expr(exprStyle{space: none}),
+			expr(exprStyle{space: spaces}),
+			expr(exprStyle{space: spaces, parens: 2}),
+			FormatNode(node),
+		}
+		for _, variant := range variants {
+			actual, actualErr := Calculate(variant)
+			require.True(t, sameResult(expected, expectedErr, actual, actualErr),
+				"%q = %v (%v)，但 %q = %v (%v)", canonical, expected, expectedErr, variant, actual, actualErr)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package calculator
+
+import "math"
+
+// 超出 Limits 时返回的错误，可以用 errors.Is 判断
+var (
+	ErrTooLong  = errorf("too_long")
+	ErrTooDeep  = errorf("too_deep")
+	ErrTooLarge = errorf("too_large")
+)
+
+// Limits 限制单条语句的规模，字段为零表示不限制
+type Limits struct {
+	MaxLength int // 语句的最大长度（字节）
+	MaxDepth  int // 语法树的最大嵌套深度
+	MaxDigits int // 结果按十进制输出的最大位数（估算），在格式化之前检查，避免转换几百万位的数字
+}
+
+// Limits 返回环境的语句规模限制
+func (e *Env) Limits() Limits {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.limits
+}
+
+// SetLimits 设置语句规模限制，用于执行不可信的输入
+func (e *Env) SetLimits(l Limits) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.limits = l
+}
+
+// parse 按环境的规模限制解析语句
+func (e *Env) parse(src string) (Node, error) {
+	limits := e.Limits()
+	if limits.MaxLength > 0 && len(src) > limits.MaxLength {
+		return nil, errorf("too_long.bytes", len(src), limits.MaxLength)
+	}
+	node, err := parse(src, e.registry)
+	if err != nil {
+		return nil, err
+	}
+	if limits.MaxDepth > 0 {
+		if depth := Depth(node); depth > limits.MaxDepth {
+			return nil, errorf("too_deep.levels", depth, limits.MaxDepth)
+		}
+	}
+	return node, nil
+}
+
+// Depth 返回语法树的最大嵌套深度，单个节点的深度为 1
+func Depth(node Node) int {
+	max := 0
+	root := true
+	Walk(node, func(n Node) bool {
+		if root {
+			root = false
+			return true
+		}
+		if d := Depth(n); d > max {
+			max = d
+		}
+		return false
+	})
+	return max + 1
+}
+
+// checkDigits 检查结果的十进制位数是否超出限制
+func (l Limits) checkDigits(v Value) error {
+	if l.MaxDigits <= 0 {
+		return nil
+	}
+	if digits := estimateDigits(v); digits > l.MaxDigits {
+		return errorf("too_large.digits", digits, l.MaxDigits)
+	}
+	return nil
+}
+
+// estimateDigits 估算数值按十进制输出时的位数，只用到二进制位数，不做任何转换
+func estimateDigits(v Value) int {
+	switch x := v.(type) {
+	case Rat:
+		if x.Rat == nil {
+			return 1
+		}
+		return int(float64(x.Num().BitLen()+x.Denom().BitLen())*math.Log10(2)) + 1
+	case BigFloat:
+		// 超出精度的整数按科学计数法输出，位数不超过精度对应的十进制位数
+		if x.Float == nil {
+			return 1
+		}
+		return int(float64(x.Prec())*math.Log10(2)) + 1
+	case Quantity:
+		return estimateDigits(x.Amount)
+	case List:
+		digits := 0
+		for _, elem := range x {
+			digits += estimateDigits(elem)
+		}
+		return digits
+	}
+	return 21 // float64 小于 1e21 的整数按整数输出，其余按最多 17 位有效数字输出
+}
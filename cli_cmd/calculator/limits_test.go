@@ -0,0 +1,125 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDepth 测试语法树深度
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   int
+		desc       string
+	}{
+		{"1", 1, "单个数字"},
+		{"1 + 2", 2, "二元表达式"},
+		{"1 + 2 * 3", 3, "嵌套的二元表达式"},
+		{"((1))", 3, "括号"},
+		{"max(1, -x)", 3, "函数调用"},
+		{"x = 1 ? 2 : 3", 3, "赋值和条件表达式"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, Depth(node))
+		})
+	}
+}
+
+// TestLimits 测试语句规模限制
+func TestLimits(t *testing.T) {
+	env := NewEnv()
+	env.SetLimits(Limits{MaxLength: 20, MaxDepth: 4})
+	assert.Equal(t, Limits{MaxLength: 20, MaxDepth: 4}, env.Limits())
+
+	_, err := env.Evaluate("1 + 2 * 3")
+	assert.NoError(t, err)
+
+	_, err = env.Evaluate(strings.Repeat("1+", 10) + "1")
+	assert.True(t, errors.Is(err, ErrTooLong))
+	assert.Equal(t, CodeLimit, ErrorCode(err))
+
+	_, err = env.Evaluate("((((1))))")
+	assert.True(t, errors.Is(err, ErrTooDeep))
+
+	res := env.Exec(Statement{Line: 1, Text: "f(x) = ((((x))))"})
+	assert.Equal(t, CodeLimit, ErrorCode(res.Err), "函数定义也受限制")
+
+	env.SetLimits(Limits{})
+	_, err = env.Evaluate("((((1))))")
+	assert.NoError(t, err, "零值表示不限制")
+}
+
+// TestMaxDigits 测试结果位数限制在格式化之前检查
+func TestMaxDigits(t *testing.T) {
+	tests := []struct {
+		mode       Mode
+		expression string
+		digits     int
+		desc       string
+	}{
+		{ModeFloat, "2^1000", 21, "float64 不超过 21 位"},
+		{ModeRat, "2^1000", 302, "整数"},
+		{ModeRat, "2^1000 / 3^100", 350, "分子和分母的位数之和"},
+		{ModeBig, "2^1000", 78, "big 模式按精度估算"},
+		{ModeRat, "[2^1000, 1]", 303, "列表"},
+		{ModeRat, "2^1000 wei", 302, "带单位的数值"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			env := NewEnv()
+			require.NoError(t, env.SetMode(test.mode))
+			v, err := env.Evaluate(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.digits, estimateDigits(v))
+
+			env.SetLimits(Limits{MaxDigits: test.digits - 1})
+			_, err = env.Evaluate(test.expression)
+			assert.True(t, errors.Is(err, ErrTooLarge), "%v", err)
+			assert.Equal(t, CodeLimit, ErrorCode(err))
+
+			env.SetLimits(Limits{MaxDigits: test.digits})
+			_, err = env.Evaluate(test.expression)
+			assert.NoError(t, err, "刚好等于限制")
+		})
+	}
+
+	// 中间结果不受限制，只检查语句的结果
+	env := NewEnv()
+	require.NoError(t, env.SetMode(ModeRat))
+	env.SetLimits(Limits{MaxDigits: 10})
+	v, err := env.Evaluate("2^100000 % 7")
+	require.NoError(t, err)
+	assert.Equal(t, "2", FormatValue(v))
+}
+
+// TestExecContext 测试取消和超时
+func TestExecContext(t *testing.T) {
+	env := NewEnv()
+	execAll(t, env, "fib(n) = n < 2 ? n : fib(n - 1) + fib(n - 2)")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res := env.ExecContext(ctx, Statement{Text: "fib(100)"})
+	assert.Less(t, time.Since(start), 2*time.Second, "超时后应该尽快停止")
+	assert.True(t, errors.Is(res.Err, context.DeadlineExceeded))
+	assert.Equal(t, CodeTimeout, ErrorCode(res.Err))
+
+	res = env.ExecContext(ctx, Statement{Text: "1 + 1"})
+	assert.Equal(t, CodeTimeout, ErrorCode(res.Err), "已超时的 ctx 不再执行语句")
+
+	res = env.ExecContext(context.Background(), Statement{Text: "fib(10)"})
+	require.NoError(t, res.Err)
+	assert.Equal(t, "55", FormatValue(res.Value))
+}
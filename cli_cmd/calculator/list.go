@@ -0,0 +1,349 @@
+package calculator
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+
+	"cli_cmd/i18n"
+)
+
+// MaxListLength 列表的最大元素个数，用于限制 1..n 这样的范围占用的内存
+const MaxListLength = 1000000
+
+// List 数值列表，例如 [1, 2, 3] 或 1..10
+//
+// 列表的元素都是数值，列表字面量中的列表会被展开，因此列表不会嵌套。
+// 列表不能参与算术运算，可以传给 sum、avg 等聚合函数，或者用 map、filter 变换。
+type List []Value
+
+// Float64 列表没有对应的数值，返回 NaN；Env.Eval 等返回 float64 的方法对列表结果返回错误
+func (l List) Float64() float64 { return math.NaN() }
+
+func (l List) String() string { return FormatValue(l) }
+
+func (List) rank() int { return 0 }
+
+// formatList 按 format 格式化每个元素，输出 [a, b, c]
+func formatList(l List, format func(Value) (string, error)) (string, error) {
+	elems := make([]string, len(l))
+	for i, v := range l {
+		s, err := format(v)
+		if err != nil {
+			return "", err
+		}
+		elems[i] = s
+	}
+	return "[" + strings.Join(elems, ", ") + "]", nil
+}
+
+// scalar 返回数值的 float64 值，列表和带单位的数量没有对应的数值
+func scalar(v Value) (float64, error) {
+	switch x := v.(type) {
+	case List:
+		return 0, errorf("not_number.list", x)
+	case Quantity:
+		return 0, errorf("not_number.quantity", x.Unit, x)
+	}
+	return v.Float64(), nil
+}
+
+// intValue 返回与 like 同类型的整数
+func intValue(n *big.Int, like Value, prec uint) Value {
+	switch like.(type) {
+	case BigFloat:
+		return BigFloat{new(big.Float).SetPrec(prec).SetInt(n)}
+	case Rat:
+		return Rat{new(big.Rat).SetInt(n)}
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return Float(f)
+}
+
+// number 对表达式求值，结果必须是数值；use 描述数值的用途，用于错误信息
+func (e *evaluator) number(node Node, use i18n.Localizer) (Value, error) {
+	v, err := e.eval(node)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := v.(List); ok {
+		return nil, errorf("not_number.use", use, node)
+	}
+	return v, nil
+}
+
+// evalList 计算列表字面量，元素是列表时展开
+func (e *evaluator) evalList(n *ListExpr) (Value, error) {
+	list := make(List, 0, len(n.Elems))
+	for _, elem := range n.Elems {
+		v, err := e.eval(elem)
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := v.(List); ok {
+			list = append(list, l...)
+		} else {
+			list = append(list, v)
+		}
+		if len(list) > MaxListLength {
+			return nil, errorf("list_too_long.list", MaxListLength)
+		}
+	}
+	return list, nil
+}
+
+// rangeList 返回 from..to 的整数列表，包含两端；from 大于 to 时递减
+func rangeList(from, to Value, prec uint) (Value, error) {
+	a, okA := toBigInt(from)
+	b, okB := toBigInt(to)
+	if !okA || !okB {
+		return nil, errorf("not_integer.range", FormatValue(from), FormatValue(to))
+	}
+	step := big.NewInt(1)
+	if a.Cmp(b) > 0 {
+		step.SetInt64(-1)
+	}
+	n := new(big.Int).Sub(b, a)
+	if n.Abs(n).Cmp(big.NewInt(MaxListLength-1)) > 0 {
+		return nil, errorf("list_too_long.range", FormatValue(from), FormatValue(to), MaxListLength)
+	}
+
+	like := from
+	if to.rank() > from.rank() {
+		like = to
+	}
+	list := make(List, 0, n.Int64()+1)
+	for i := new(big.Int).Set(a); ; i.Add(i, step) {
+		list = append(list, intValue(i, like, prec))
+		if i.Cmp(b) == 0 {
+			return list, nil
+		}
+	}
+}
+
+// listForm 参数不先求值的内置函数，例如 map 的第二个参数是匿名函数
+type listForm func(e *evaluator, fn *Function, args []Node) (Value, error)
+
+// listArg 计算函数的列表参数
+func (e *evaluator) listArg(fn *Function, node Node) (List, error) {
+	v, err := e.eval(node)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := v.(List)
+	if !ok {
+		return nil, errorf("invalid_argument.list", fn.Name, node)
+	}
+	return l, nil
+}
+
+// funcArg 把匿名函数或函数名参数转换为可以对每个元素调用的函数
+func (e *evaluator) funcArg(fn *Function, node Node) (func(Value) (Value, error), error) {
+	switch n := node.(type) {
+	case *Lambda:
+		locals := make(map[string]Value, len(e.locals)+1)
+		for name, v := range e.locals {
+			locals[name] = v
+		}
+		body := evaluator{env: e.env, mode: e.mode, prec: e.prec, locals: locals, depth: e.depth, ctx: e.ctx}
+		return func(x Value) (Value, error) {
+			locals[n.Param.Name] = x
+			return body.eval(n.Body)
+		}, nil
+	case *Ident:
+		if builtin, ok := e.env.registry.Func(n.Name); ok && builtin.form == nil && builtin.checkArgs(1) == nil {
+			return func(x Value) (Value, error) {
+				return e.callBuiltin(builtin, []Value{x})
+			}, nil
+		}
+		if uf, ok := e.env.UserFunc(n.Name); ok && len(uf.Params) == 1 {
+			return func(x Value) (Value, error) {
+				return e.invoke(uf, []Value{x})
+			}, nil
+		}
+	}
+	return nil, errorf("invalid_argument.callback", fn.Name, node)
+}
+
+// mapForm map(list, f) 对每个元素调用 f，返回结果的列表
+func mapForm(e *evaluator, fn *Function, args []Node) (Value, error) {
+	list, err := e.listArg(fn, args[0])
+	if err != nil {
+		return nil, err
+	}
+	f, err := e.funcArg(fn, args[1])
+	if err != nil {
+		return nil, err
+	}
+	result := make(List, len(list))
+	for i, x := range list {
+		if err := e.ctx.Err(); err != nil {
+			return nil, err
+		}
+		y, err := f(x)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := y.(List); ok {
+			return nil, errorf("invalid_argument.callback_result", fn.Name, args[1])
+		}
+		result[i] = y
+	}
+	return result, nil
+}
+
+// filterForm filter(list, f) 返回 f 的结果非零的元素
+func filterForm(e *evaluator, fn *Function, args []Node) (Value, error) {
+	list, err := e.listArg(fn, args[0])
+	if err != nil {
+		return nil, err
+	}
+	f, err := e.funcArg(fn, args[1])
+	if err != nil {
+		return nil, err
+	}
+	result := make(List, 0, len(list))
+	for _, x := range list {
+		if err := e.ctx.Err(); err != nil {
+			return nil, err
+		}
+		keep, err := f(x)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := keep.(List); ok {
+			return nil, errorf("invalid_argument.callback_result", fn.Name, args[1])
+		}
+		if truthy(keep) {
+			result = append(result, x)
+		}
+	}
+	return result, nil
+}
+
+// registerListFuncs 注册聚合函数和 map、filter
+//
+// 聚合函数的列表参数展开为多个参数，因此 sum([1, 2], 3) 与 sum(1, 2, 3) 相同。
+func registerListFuncs(r *Registry) {
+	r.RegisterVariadic("sum", 0, func(args []float64) (float64, error) {
+		total := 0.0
+		for _, x := range args {
+			total += x
+		}
+		return total, nil
+	})
+	r.RegisterVariadic("count", 0, func(args []float64) (float64, error) {
+		return float64(len(args)), nil
+	})
+	r.RegisterVariadic("avg", 1, func(args []float64) (float64, error) {
+		return mean(args), nil
+	})
+	r.RegisterVariadic("median", 1, func(args []float64) (float64, error) {
+		return percentile(args, 50), nil
+	})
+	// stddev 为总体标准差
+	r.RegisterVariadic("stddev", 1, func(args []float64) (float64, error) {
+		m := mean(args)
+		variance := 0.0
+		for _, x := range args {
+			variance += (x - m) * (x - m)
+		}
+		return math.Sqrt(variance / float64(len(args))), nil
+	})
+	// percentile(list, p) 的最后一个参数是百分位 p，按相邻元素线性插值
+	r.RegisterVariadic("percentile", 2, func(args []float64) (float64, error) {
+		p := args[len(args)-1]
+		if !(p >= 0 && p <= 100) {
+			return 0, errorf("invalid_argument.percentile", FormatResult(p))
+		}
+		return percentile(args[:len(args)-1], p), nil
+	})
+
+	for _, name := range []string{"sum", "count", "avg", "median", "stddev", "percentile", "min", "max"} {
+		r.funcs[name].spread = true
+	}
+	r.setExact("sum", func(args []Value, prec uint) (Value, error) {
+		return sumValues(args, prec)
+	})
+	r.setExact("avg", func(args []Value, prec uint) (Value, error) {
+		total, err := sumValues(args, prec)
+		if err != nil {
+			return nil, err
+		}
+		return arith(DIVIDE, total, intValue(big.NewInt(int64(len(args))), total, prec), prec)
+	})
+	r.setExact("median", func(args []Value, prec uint) (Value, error) {
+		sorted, err := sortValues(args, prec)
+		if err != nil {
+			return nil, err
+		}
+		mid := len(sorted) / 2
+		if len(sorted)%2 == 1 {
+			return sorted[mid], nil
+		}
+		total, err := arith(PLUS, sorted[mid-1], sorted[mid], prec)
+		if err != nil {
+			return nil, err
+		}
+		return arith(DIVIDE, total, intValue(big.NewInt(2), total, prec), prec)
+	})
+
+	r.funcs["map"] = &Function{Name: "map", MinArgs: 2, MaxArgs: 2, Call: formOnly("map"), form: mapForm}
+	r.funcs["filter"] = &Function{Name: "filter", MinArgs: 2, MaxArgs: 2, Call: formOnly("filter"), form: filterForm}
+}
+
+// formOnly 返回 listForm 函数的 Call 实现，这类函数不能以数值参数调用
+func formOnly(name string) Func {
+	return func([]float64) (float64, error) {
+		return 0, errorf("invalid_argument.list_function", name)
+	}
+}
+
+// mean 返回算术平均值
+func mean(xs []float64) float64 {
+	total := 0.0
+	for _, x := range xs {
+		total += x
+	}
+	return total / float64(len(xs))
+}
+
+// percentile 返回第 p 百分位数（0 <= p <= 100），在排序后的相邻元素之间线性插值
+func percentile(xs []float64, p float64) float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	frac := rank - float64(lo)
+	return sorted[lo] + (sorted[lo+1]-sorted[lo])*frac
+}
+
+// sumValues 按数值类型精确求和
+func sumValues(args []Value, prec uint) (Value, error) {
+	var total Value = Float(0)
+	for _, v := range args {
+		var err error
+		if total, err = arith(PLUS, total, v, prec); err != nil {
+			return nil, err
+		}
+	}
+	return total, nil
+}
+
+// sortValues 返回按大小排序的数值
+func sortValues(args []Value, prec uint) ([]Value, error) {
+	sorted := append([]Value(nil), args...)
+	var err error
+	sort.SliceStable(sorted, func(i, j int) bool {
+		c, cmpErr := compare(sorted[i], sorted[j], prec)
+		if cmpErr != nil {
+			err = cmpErr
+		}
+		return c < 0
+	})
+	return sorted, err
+}
@@ -0,0 +1,147 @@
+package calculator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLists 测试列表、范围和聚合函数
+func TestLists(t *testing.T) {
+	tests := []struct {
+		stmts    []string
+		expected string
+		desc     string
+	}{
+		{[]string{"[1, 2, 3]"}, "[1, 2, 3]", "列表字面量"},
+		{[]string{"[]"}, "[]", "空列表"},
+		{[]string{"1..5"}, "[1, 2, 3, 4, 5]", "范围包含两端"},
+		{[]string{"3..-1"}, "[3, 2, 1, 0, -1]", "递减的范围"},
+		{[]string{"n = 3", "1..n*2"}, "[1, 2, 3, 4, 5, 6]", "范围的优先级低于算术运算"},
+		{[]string{"[0, 1..3, 10]"}, "[0, 1, 2, 3, 10]", "展开列表元素"},
+		{[]string{"sum(1..100)"}, "5050", "求和"},
+		{[]string{"sum([1, 2], 3)"}, "6", "展开聚合函数的列表参数"},
+		{[]string{"sum([])"}, "0", "空列表求和"},
+		{[]string{"count(1..10)"}, "10", "元素个数"},
+		{[]string{"avg([10, 20, 60])"}, "30", "平均值"},
+		{[]string{"median([3, 1, 2])"}, "2", "奇数个元素的中位数"},
+		{[]string{"median([4, 1, 3, 2])"}, "2.5", "偶数个元素的中位数"},
+		{[]string{"stddev([2, 4, 4, 4, 5, 5, 7, 9])"}, "2", "总体标准差"},
+		{[]string{"percentile(1..101, 90)"}, "91", "百分位数"},
+		{[]string{"percentile([10, 20], 25)"}, "12.5", "百分位数线性插值"},
+		{[]string{"max([1, 5, 2])"}, "5", "min、max 接受列表"},
+		{[]string{"map(1..4, x => x ^ 2)"}, "[1, 4, 9, 16]", "map"},
+		{[]string{"filter(1..10, x => x % 3 == 0)"}, "[3, 6, 9]", "filter"},
+		{[]string{"map([4, 9], sqrt)"}, "[2, 3]", "map 内置函数"},
+		{[]string{"double(x) = x * 2", "map([1, 2], double)"}, "[2, 4]", "map 用户函数"},
+		{[]string{"scale(xs, k) = map(xs, x => x * k)", "scale([1, 2], 10)"}, "[10, 20]", "匿名函数使用外层参数"},
+		{[]string{"x = 100", "map([1], x => x + 1)"}, "[2]", "匿名函数参数优先于变量"},
+		{[]string{"gas = [20, 30, 25] gwei"}, "", "列表不能带单位"},
+		{[]string{"gas = [20 gwei, 30 gwei]", "avg(gas) in gwei"}, "25", "列表变量"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res := execAll(t, NewEnv(), test.stmts...)
+			if test.expected == "" {
+				assert.Error(t, res.Err)
+				return
+			}
+			require.NoError(t, res.Err)
+			assert.Equal(t, test.expected, FormatValue(res.Value))
+		})
+	}
+}
+
+// TestListModes 测试高精度模式下的列表和聚合函数
+func TestListModes(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		expr     string
+		expected string
+		desc     string
+	}{
+		{ModeRat, "avg([1, 2, 2])", "5/3", "精确平均值"},
+		{ModeRat, "sum(map(1..3, x => 1 / x))", "11/6", "精确求和"},
+		{ModeRat, "median([1/3, 1/2])", "5/12", "精确中位数"},
+		{ModeBig, "1..3", "[1, 2, 3]", "big 模式的范围"},
+		{ModeFloat, "0x1..0x3", "[1, 2, 3]", "精确字面量的范围"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			env := NewEnv()
+			require.NoError(t, env.SetMode(test.mode))
+			v, err := env.Evaluate(test.expr)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, FormatValue(v))
+		})
+	}
+}
+
+// TestListErrors 测试列表的错误用法
+func TestListErrors(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+		desc     string
+	}{
+		{"[1, 2] + 1", "运算符 '+' 的操作数不能是列表: [1, 2]", "算术运算"},
+		{"-[1]", "运算符 '-' 的操作数不能是列表: [1]", "一元运算"},
+		{"[1] ? 1 : 2", "条件不能是列表: [1]", "条件"},
+		{"sqrt([4])", "函数 sqrt 的参数不能是列表: [4]", "普通函数"},
+		{"avg([])", "函数 avg 至少需要 1 个参数，但得到 0 个（列表参数按元素个数计算）", "空列表的平均值"},
+		{"percentile([1, 2], 101)", "百分位必须在 0 到 100 之间: 101", "百分位超出范围"},
+		{"1.5..3", "范围的端点必须是整数: 1.5 .. 3", "非整数范围"},
+		{"1..1e7", "范围 1 .. 10000000 超过 1000000 个元素", "范围过大"},
+		{"map(5, x => x)", "函数 map 的第一个参数必须是列表: 5", "map 的参数不是列表"},
+		{"map([1], y)", "函数 map 的第二个参数必须是匿名函数或单参数函数的名称，例如 x => x * 2: y", "map 的参数不是函数"},
+		{"map([1], x => [x])", "函数 map 的函数参数必须返回数值: x => [x]", "map 返回列表"},
+		{"x => x", "匿名函数只能作为 map、filter 的参数: x => x", "单独的匿名函数"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := NewEnv().Evaluate(test.expr)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+// TestListScalar 测试返回 float64 的接口对列表结果报错
+func TestListScalar(t *testing.T) {
+	_, err := Calculate("1..3")
+	assert.EqualError(t, err, "结果是列表，不是数值: [1, 2, 3]")
+
+	env := NewEnv()
+	_, err = env.Calculate("xs = [1, 2]")
+	assert.Error(t, err)
+	_, ok := env.Get("xs")
+	assert.False(t, ok, "列表变量没有 float64 值")
+
+	prog := mustCompile(t, "xs + 1")
+	_, err = prog.Eval(env)
+	assert.Error(t, err)
+	_, err = Compile("sum(1..3)")
+	assert.Error(t, err, "编译的表达式不支持列表")
+}
+
+// TestListCancel 测试 map 在取消后停止
+func TestListCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := NewEnv().ExecContext(ctx, Statement{Text: "map(1..10, x => x)"})
+	assert.ErrorIs(t, res.Err, context.Canceled)
+}
+
+// TestFormatList 测试按选项格式化列表
+func TestFormatList(t *testing.T) {
+	s, err := Format(List{Float(255), Float(16)}, FormatOptions{Base: 16})
+	require.NoError(t, err)
+	assert.Equal(t, "[0xff, 0x10]", s)
+
+	_, err = Format(List{Float(1.5)}, FormatOptions{Base: 16})
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package calculator
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"cli_cmd/i18n"
+)
+
+// baseNames 带前缀的整数字面量的进制名称
+var baseNames = map[int]message{2: msg("base.2"), 8: msg("base.8"), 16: msg("base.16")}
+
+// literalBase 返回字面量前缀表示的进制，没有前缀时返回 10
+func literalBase(lit string) int {
+	if len(lit) < 2 || lit[0] != '0' {
+		return 10
+	}
+	switch lit[1] {
+	case 'x', 'X':
+		return 16
+	case 'b', 'B':
+		return 2
+	case 'o', 'O':
+		return 8
+	}
+	return 10
+}
+
+// isPrefixedLiteral 判断是否是 0x、0b、0o 开头的整数字面量
+//
+// 这类字面量是精确整数：float 模式下也解析为 Rat，以免大额 wei 丢失精度。
+func isPrefixedLiteral(lit string) bool {
+	return literalBase(lit) != 10
+}
+
+// isDigitOf 判断字符是否是指定进制的数字
+func isDigitOf(ch byte, base int) bool {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch-'0') < base
+	case 'a' <= ch && ch <= 'f':
+		return base == 16
+	case 'A' <= ch && ch <= 'F':
+		return base == 16
+	}
+	return false
+}
+
+// isNumberTail 判断字符能否紧跟在数字后面组成一个词，用于确定格式错误的字面量的范围
+func isNumberTail(ch byte) bool {
+	return isDigitOf(ch, 10) || ch == '_' || ch == '.' ||
+		('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
+
+// scanNumber 扫描 s 开头的数字字面量，返回字面量的字节长度
+//
+// 支持的格式：
+//
+//	123  1.5  .5  1.  1e18  2.5E-3  1_000_000
+//	0x1f  0b1010  0o17  0xdead_beef
+//
+// '_' 只能出现在数字之间（或紧跟在进制前缀之后）。数字后紧跟的字母不属于字面量，
+// 因此 1ether 是数字 1 和单位 ether，e 后面不是数字时也不作为指数；
+// 同样 1..10 中的 ".." 是范围运算符，不属于字面量。
+// 格式错误时返回错误原因，长度包括其后紧跟的字母、数字、下划线和小数点。
+func scanNumber(s string) (int, i18n.Localizer) {
+	n, reason := scanNumberPrefix(s)
+	if reason == nil {
+		return n, nil
+	}
+	for n < len(s) && isNumberTail(s[n]) {
+		n++
+	}
+	return n, reason
+}
+
+func scanNumberPrefix(s string) (int, i18n.Localizer) {
+	if base := literalBase(s); base != 10 {
+		i, digits, reason := scanDigits(s, 2, base, true)
+		if reason != nil {
+			return i, reason
+		}
+		if i < len(s) && isNumberTail(s[i]) && (s[i] != '.' || isDecimalPoint(s, i)) {
+			return i, msg("number.invalid_digit", baseNames[base], s[i])
+		}
+		if digits == 0 {
+			return i, msg("number.missing_digits_after", s[:2], baseNames[base])
+		}
+		return i, nil
+	}
+
+	i, digits, reason := scanDigits(s, 0, 10, false)
+	if reason != nil {
+		return i, reason
+	}
+	if isDecimalPoint(s, i) {
+		var frac int
+		if i, frac, reason = scanDigits(s, i+1, 10, false); reason != nil {
+			return i, reason
+		}
+		digits += frac
+	}
+	if digits == 0 {
+		return i, msg("number.missing_digits")
+	}
+
+	// 指数：e 后面必须是数字或者带符号的数字，否则 e 属于后面的标识符
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigitOf(s[j], 10) {
+			if i, _, reason = scanDigits(s, j, 10, false); reason != nil {
+				return i, reason
+			}
+		}
+	}
+
+	switch {
+	case isDecimalPoint(s, i):
+		return i, msg("number.extra_point")
+	case i < len(s) && s[i] == '_':
+		return i, msg("number.separator")
+	}
+	return i, nil
+}
+
+// isDecimalPoint 判断 s[i] 是否是小数点，".." 是范围运算符
+func isDecimalPoint(s string, i int) bool {
+	return i < len(s) && s[i] == '.' && (i+1 >= len(s) || s[i+1] != '.')
+}
+
+// scanDigits 从 s[i] 开始扫描指定进制的数字和 '_'，返回结束位置和数字个数
+//
+// afterPrefix 为 true 时允许 '_' 紧跟在进制前缀之后，例如 0x_ff。
+func scanDigits(s string, i, base int, afterPrefix bool) (int, int, i18n.Localizer) {
+	digits := 0
+	for i < len(s) {
+		switch {
+		case isDigitOf(s[i], base):
+			digits++
+		case s[i] == '_':
+			prevOK := digits > 0 || afterPrefix && i == 2
+			if !prevOK || s[i-1] == '_' || i+1 >= len(s) || !isDigitOf(s[i+1], base) {
+				return i, digits, msg("number.separator")
+			}
+		default:
+			return i, digits, nil
+		}
+		i++
+	}
+	return i, digits, nil
+}
+
+// cleanLiteral 去掉字面量中的 '_' 和进制前缀，返回数字部分和进制
+func cleanLiteral(lit string) (string, int) {
+	base := literalBase(lit)
+	if base != 10 {
+		lit = lit[2:]
+	}
+	return strings.ReplaceAll(lit, "_", ""), base
+}
+
+// parseFloatLiteral 把数字字面量解析为 float64
+//
+// 超出 float64 范围时返回 ±Inf 和 ErrOutOfRange，语法正确的字面量在 big、rat 模式下仍然有效。
+func parseFloatLiteral(lit string) (float64, error) {
+	digits, base := cleanLiteral(lit)
+	if base != 10 {
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return 0, errorf("invalid_number.literal", lit)
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f, nil
+	}
+	value, err := strconv.ParseFloat(digits, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return value, errorf("out_of_range.literal", lit)
+	}
+	if err != nil {
+		return 0, errorf("invalid_number.literal", lit)
+	}
+	return value, nil
+}
+
+// parseExactLiteral 把数字字面量精确解析为有理数
+func parseExactLiteral(lit string) (*big.Rat, error) {
+	digits, base := cleanLiteral(lit)
+	if base != 10 {
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return nil, errorf("invalid_number.literal", lit)
+		}
+		return new(big.Rat).SetInt(n), nil
+	}
+	r, ok := new(big.Rat).SetString(digits)
+	if !ok {
+		return nil, errorf("invalid_number.literal", lit)
+	}
+	return r, nil
+}
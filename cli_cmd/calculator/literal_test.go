@@ -0,0 +1,156 @@
+package calculator
+
+import (
+	"strings"
+	"testing"
+
+	"cli_cmd/i18n"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScanNumber 测试数字字面量的扫描
+func TestScanNumber(t *testing.T) {
+	tests := []struct {
+		input  string
+		length int
+		reason string
+		desc   string
+	}{
+		{"123", 3, "", "整数"},
+		{"1.5+2", 3, "", "小数"},
+		{".5", 2, "", "省略整数部分"},
+		{"1.", 2, "", "省略小数部分"},
+		{"1e18", 4, "", "指数"},
+		{"2.5E-3*x", 6, "", "带符号的指数"},
+		{"1ether", 1, "", "单位不是指数"},
+		{"1e18wei", 4, "", "指数后跟单位"},
+		{"1e+x", 1, "", "e 后不是数字"},
+		{"1_000_000", 9, "", "数字分隔符"},
+		{"0x1f", 4, "", "十六进制"},
+		{"0XdeadBEEF", 10, "", "大写前缀"},
+		{"0b1010", 6, "", "二进制"},
+		{"0o17", 4, "", "八进制"},
+		{"0x_ff_ff", 8, "", "前缀后的分隔符"},
+		{"1..10", 1, "", "范围运算符"},
+		{"1.5..2", 3, "", "小数后的范围运算符"},
+		{"0xf..0x1f", 3, "", "十六进制后的范围运算符"},
+		{"1.2.3", 5, "多余的小数点", "两个小数点"},
+		{"1e5.5", 5, "多余的小数点", "指数后的小数点"},
+		{"1__0", 4, "'_' 只能用在数字之间", "连续的分隔符"},
+		{"1_", 2, "'_' 只能用在数字之间", "末尾的分隔符"},
+		{"1_.5", 4, "'_' 只能用在数字之间", "小数点前的分隔符"},
+		{"1._5", 4, "'_' 只能用在数字之间", "小数点后的分隔符"},
+		{"0x", 2, "0x 后缺少十六进制数字", "缺少十六进制数字"},
+		{"0b102", 5, "二进制数字中不能包含 '2'", "二进制数字越界"},
+		{"0o8", 3, "八进制数字中不能包含 '8'", "八进制数字越界"},
+		{"0x1g + 1", 4, "十六进制数字中不能包含 'g'", "十六进制中的字母"},
+		{".", 1, "缺少数字", "只有小数点"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			n, reason := scanNumber(test.input)
+			assert.Equal(t, test.length, n)
+			if test.reason == "" {
+				assert.Nil(t, reason)
+			} else {
+				assert.Equal(t, test.reason, i18n.Text(i18n.Chinese, reason))
+			}
+		})
+	}
+}
+
+// TestNumberLiterals 测试各种数字字面量的值
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"1e18", "1000000000000000000", "指数"},
+		{"2.5e-3 * 1000", "2.5", "负指数"},
+		{"1_000_000 + 1", "1000001", "数字分隔符"},
+		{"0x1f + 0b1010 + 0o17", "56", "带前缀的整数"},
+		{"0b1111_0000", "240", "二进制分隔符"},
+		{"1e18 wei in ether", "1", "指数与以太坊单位"},
+		{"1.5e3gwei in gwei", "1500", "指数紧跟单位"},
+		{"2ether in gwei", "2000000000", "e 开头的单位"},
+		{"0xffffffffffffffffff", "4722366482869645213695", "大整数保持精确"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v, err := NewEnv().Evaluate(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, FormatValue(v))
+		})
+	}
+
+	for _, mode := range []Mode{ModeBig, ModeRat} {
+		env := NewEnv()
+		require.NoError(t, env.SetMode(mode))
+		v, err := env.Evaluate("1_000.5e1 + 0o10")
+		require.NoError(t, err)
+		assert.Equal(t, "10013", FormatValue(v), "%s 模式", mode)
+	}
+}
+
+// TestLargeLiterals 测试超出 float64 范围的字面量由计算模式决定如何处理
+func TestLargeLiterals(t *testing.T) {
+	huge := "1" + strings.Repeat("0", 400)
+	tests := []struct {
+		mode       Mode
+		expression string
+		expected   string
+		desc       string
+	}{
+		{ModeBig, "1e400 / 1e399", "10", "big 模式的大指数"},
+		{ModeRat, "1e400 / 1e399", "10", "rat 模式的大指数"},
+		{ModeBig, huge + " + 1", "1e+400", "big 模式的超长整数按精度舍入"},
+		{ModeRat, huge + " + 1", huge[:400] + "1", "rat 模式的超长整数"},
+		{ModeRat, "1e-400 * 1e400", "1", "rat 模式的极小数"},
+		{ModeFloat, "1e-400", "0", "float 模式下溢为 0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Parse(test.expression)
+			require.NoError(t, err, "超出 float64 范围不是语法错误")
+			assert.Equal(t, test.expected, evaluateIn(t, test.mode, test.expression))
+		})
+	}
+
+	// float 模式下仍然报告超出范围
+	for _, expression := range []string{"1e400", huge} {
+		_, err := NewEnv().Evaluate(expression)
+		assert.ErrorIs(t, err, ErrOutOfRange)
+		assert.NotEqual(t, CodeSyntax, ErrorCode(err))
+
+		_, err = Compile(expression)
+		assert.ErrorIs(t, err, ErrOutOfRange)
+	}
+}
+
+// TestInvalidNumbers 测试格式错误的数字报告为语法错误
+func TestInvalidNumbers(t *testing.T) {
+	tests := []struct {
+		expression string
+		message    string
+		desc       string
+	}{
+		{"1.2.3 + 1", "第 1 列: 无效的数字 '1.2.3': 多余的小数点", "两个小数点"},
+		{"2 * 0b102", "第 5 列: 无效的数字 '0b102': 二进制数字中不能包含 '2'", "二进制数字越界"},
+		{"1__000", "第 1 列: 无效的数字 '1__000': '_' 只能用在数字之间", "连续的分隔符"},
+		{"x = .", "第 5 列: 无效的数字 '.': 缺少数字", "只有小数点"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Parse(test.expression)
+			assert.EqualError(t, err, test.message)
+			assert.Equal(t, CodeSyntax, ErrorCode(err))
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package calculator
+
+import (
+	"strings"
+
+	"cli_cmd/i18n"
+)
+
+// message 消息目录中的一条消息及其参数，输出时才按语言格式化
+type message struct {
+	key  string
+	args []interface{}
+}
+
+// msg 创建消息，key 是 messages 中的键
+func msg(key string, args ...interface{}) message {
+	return message{key: key, args: args}
+}
+
+func (m message) Localize(lang i18n.Lang) string {
+	return messages.Sprintf(lang, m.key, m.args...)
+}
+
+func (m message) String() string { return m.Localize(i18n.Current()) }
+
+// isErrorID 判断 id 是否为错误标识，错误标识在消息目录中有不带参数的说明
+func isErrorID(id string) bool {
+	_, ok := messages[id]
+	return ok && !strings.Contains(id, ".")
+}
+
+// messages calculator 包的消息目录
+//
+// 错误的键为“错误标识”或“错误标识.具体情况”，见 errorf；错误标识本身的消息是不带参数的简短说明。
+var messages = i18n.Catalog{
+	// 各 ID 的说明（不带参数）
+	"division_by_zero":   {i18n.Chinese: "除零错误", i18n.English: "division by zero"},
+	"undefined_variable": {i18n.Chinese: "未定义的变量", i18n.English: "undefined variable"},
+	"undefined_function": {i18n.Chinese: "未定义的函数", i18n.English: "undefined function"},
+	"argument_count":     {i18n.Chinese: "参数个数错误", i18n.English: "wrong number of arguments"},
+	"invalid_argument":   {i18n.Chinese: "无效的参数", i18n.English: "invalid argument"},
+	"domain":             {i18n.Chinese: "参数超出定义域", i18n.English: "argument out of domain"},
+	"out_of_range":       {i18n.Chinese: "结果超出范围", i18n.English: "result out of range"},
+	"not_integer":        {i18n.Chinese: "不是整数", i18n.English: "not an integer"},
+	"not_number":         {i18n.Chinese: "不是数值", i18n.English: "not a number"},
+	"list_too_long":      {i18n.Chinese: "列表过长", i18n.English: "list too long"},
+	"constant":           {i18n.Chinese: "不能给常量赋值", i18n.English: "cannot assign to a constant"},
+	"call_depth":         {i18n.Chinese: "函数调用过深", i18n.English: "call depth exceeded"},
+	"stopped":            {i18n.Chinese: "计算已停止", i18n.English: "calculation stopped"},
+	"invalid_number":     {i18n.Chinese: "无效的数字", i18n.English: "invalid number"},
+	"unknown_unit":       {i18n.Chinese: "未知的单位", i18n.English: "unknown unit"},
+	"unit":               {i18n.Chinese: "无效的单位", i18n.English: "invalid unit"},
+	"setting":            {i18n.Chinese: "无效的设置", i18n.English: "invalid setting"},
+	"format":             {i18n.Chinese: "无法输出结果", i18n.English: "cannot format result"},
+	"not_compilable":     {i18n.Chinese: "表达式不能编译", i18n.English: "expression cannot be compiled"},
+	"not_differentiable": {i18n.Chinese: "无法求导", i18n.English: "cannot differentiate"},
+	"definition":         {i18n.Chinese: "无效的定义", i18n.English: "invalid definition"},
+	"unit_file":          {i18n.Chinese: "无效的单位文件", i18n.English: "invalid unit file"},
+	"case_file":          {i18n.Chinese: "无效的用例文件", i18n.English: "invalid case file"},
+	"read":               {i18n.Chinese: "读取失败", i18n.English: "read failed"},
+	"internal":           {i18n.Chinese: "内部错误", i18n.English: "internal error"},
+	"unexpected_token":   {i18n.Chinese: "意外的标记", i18n.English: "unexpected token"},
+	"illegal_character":  {i18n.Chinese: "无法识别的字符", i18n.English: "unrecognized character"},
+	"empty_expression":   {i18n.Chinese: "表达式不能为空", i18n.English: "expression is empty"},
+	"invalid_assignment": {i18n.Chinese: "无效的赋值", i18n.English: "invalid assignment"},
+	"invalid_parameter":  {i18n.Chinese: "无效的参数名", i18n.English: "invalid parameter"},
+	"dimension":          {i18n.Chinese: "量纲不匹配", i18n.English: "dimension mismatch"},
+	"too_long":           {i18n.Chinese: "表达式过长", i18n.English: "expression too long"},
+	"too_deep":           {i18n.Chinese: "表达式嵌套过深", i18n.English: "expression nested too deeply"},
+	"too_large":          {i18n.Chinese: "结果过大", i18n.English: "result too large"},
+
+	// 位置
+	"position.line_column": {i18n.Chinese: "第 %d 行第 %d 列: %s", i18n.English: "line %d, column %d: %s"},
+	"position.column":      {i18n.Chinese: "第 %d 列: %s", i18n.English: "column %d: %s"},
+	"position.line":        {i18n.Chinese: "第 %d 行: %s", i18n.English: "line %d: %s"},
+
+	// 标记
+	"token.number":       {i18n.Chinese: "数字", i18n.English: "number"},
+	"token.ident":        {i18n.Chinese: "标识符", i18n.English: "identifier"},
+	"token.eof":          {i18n.Chinese: "表达式结尾", i18n.English: "end of expression"},
+	"token.illegal":      {i18n.Chinese: "非法字符", i18n.English: "illegal character"},
+	"token.number_value": {i18n.Chinese: "数字 %s", i18n.English: "number %s"},
+	"token.ident_value":  {i18n.Chinese: "标识符 %s", i18n.English: "identifier %s"},
+	"token.quoted":       {i18n.Chinese: "'%s'", i18n.English: "'%s'"},
+	"list.separator":     {i18n.Chinese: "、", i18n.English: ", "},
+	"list.or":            {i18n.Chinese: "%s 或 %s", i18n.English: "%s or %s"},
+
+	// 数字字面量
+	"base.2":                      {i18n.Chinese: "二进制", i18n.English: "binary"},
+	"base.8":                      {i18n.Chinese: "八进制", i18n.English: "octal"},
+	"base.16":                     {i18n.Chinese: "十六进制", i18n.English: "hexadecimal"},
+	"number.invalid_digit":        {i18n.Chinese: "%s数字中不能包含 '%c'", i18n.English: "%s number cannot contain '%c'"},
+	"number.missing_digits_after": {i18n.Chinese: "%s 后缺少%s数字", i18n.English: "missing %[2]s digits after %[1]s"},
+	"number.missing_digits":       {i18n.Chinese: "缺少数字", i18n.English: "missing digits"},
+	"number.extra_point":          {i18n.Chinese: "多余的小数点", i18n.English: "unexpected decimal point"},
+	"number.separator":            {i18n.Chinese: "'_' 只能用在数字之间", i18n.English: "'_' must be between digits"},
+
+	// 语法错误
+	"invalid_number.syntax":         {i18n.Chinese: "无效的数字 '%s': %s", i18n.English: "invalid number '%s': %s"},
+	"illegal_character.char":        {i18n.Chinese: "无法识别的字符 '%s'", i18n.English: "unrecognized character '%s'"},
+	"unexpected_token.token":        {i18n.Chinese: "意外的 %s", i18n.English: "unexpected %s"},
+	"unexpected_token.expected":     {i18n.Chinese: "期望 %s，但得到 %s", i18n.English: "expected %s, got %s"},
+	"unexpected_token.extra":        {i18n.Chinese: "表达式解析不完整，多余的 %s", i18n.English: "incomplete expression, unexpected %s"},
+	"invalid_number.literal":        {i18n.Chinese: "无法解析数字: %s", i18n.English: "cannot parse number: %s"},
+	"invalid_number.literal_syntax": {i18n.Chinese: "无法解析数字 %s", i18n.English: "cannot parse number %s"},
+	"out_of_range.literal":          {i18n.Chinese: "数字超出 float64 范围: %s", i18n.English: "number out of float64 range: %s"},
+	"invalid_assignment.func_def":   {i18n.Chinese: "函数定义不能作为赋值的值", i18n.English: "a function definition cannot be assigned"},
+	"invalid_assignment.target":     {i18n.Chinese: "赋值语句左侧必须是变量名或函数声明: %s", i18n.English: "the left side of an assignment must be a variable or a function declaration: %s"},
+	"invalid_parameter.name":        {i18n.Chinese: "函数参数必须是名称: %s", i18n.English: "function parameters must be names: %s"},
+	"invalid_parameter.duplicate":   {i18n.Chinese: "重复的参数名: %s", i18n.English: "duplicate parameter: %s"},
+
+	// 运算
+	"division_by_zero.modulo":  {i18n.Chinese: "模运算的除数不能为零", i18n.English: "modulo by zero"},
+	"internal.binary_operator": {i18n.Chinese: "未知的二元运算符: %s", i18n.English: "unknown binary operator: %s"},
+	"internal.unary_operator":  {i18n.Chinese: "未知的一元运算符: %s", i18n.English: "unknown unary operator: %s"},
+	"out_of_range.power":       {i18n.Chinese: "幂运算结果超出范围", i18n.English: "power result out of range"},
+	"internal.power":           {i18n.Chinese: "无法计算幂运算: %v", i18n.English: "cannot compute power of %v"},
+	"not_integer.bitwise":      {i18n.Chinese: "位运算 %s 的操作数必须是整数: %s", i18n.English: "operands of bitwise %s must be integers: %s"},
+	"invalid_argument.shift":   {i18n.Chinese: "移位位数必须在 0 到 %d 之间: %v", i18n.English: "shift count must be between 0 and %d: %v"},
+	"domain.power":             {i18n.Chinese: "幂运算结果无定义: %s ^ %s", i18n.English: "power is undefined: %s ^ %s"},
+	"domain.function":          {i18n.Chinese: "函数 %s 的参数超出定义域", i18n.English: "argument of %s is out of its domain"},
+	"domain.sqrt":              {i18n.Chinese: "sqrt 的参数不能为负数", i18n.English: "sqrt of a negative number"},
+	"domain.log":               {i18n.Chinese: "log 的参数必须为正数", i18n.English: "log requires a positive argument"},
+	"domain.log_base":          {i18n.Chinese: "log 的底数必须为正数且不等于 1", i18n.English: "log base must be positive and not 1"},
+	"domain.ln":                {i18n.Chinese: "ln 的参数必须为正数", i18n.English: "ln requires a positive argument"},
+	"out_of_range.result":      {i18n.Chinese: "结果超出范围: %v", i18n.English: "result out of range: %v"},
+	"invalid_number.exact":     {i18n.Chinese: "无法将 %v 转换为精确数值", i18n.English: "cannot convert %v to an exact number"},
+	"invalid_number.value":     {i18n.Chinese: "无法转换为数值: %v", i18n.English: "cannot convert to a number: %v"},
+
+	// 变量和函数
+	"undefined_variable.name":     {i18n.Chinese: "未定义的变量: %s", i18n.English: "undefined variable: %s"},
+	"undefined_function.name":     {i18n.Chinese: "未定义的函数: %s", i18n.English: "undefined function: %s"},
+	"undefined_function.compiled": {i18n.Chinese: "未定义的函数: %s（编译的表达式只支持内置函数）", i18n.English: "undefined function: %s (compiled expressions only support built-in functions)"},
+	"constant.assign":             {i18n.Chinese: "不能给常量赋值: %s", i18n.English: "cannot assign to constant: %s"},
+	"argument_count.min":          {i18n.Chinese: "函数 %s 至少需要 %d 个参数，但得到 %d 个", i18n.English: "function %s needs at least %d arguments, got %d"},
+	"argument_count.exact":        {i18n.Chinese: "函数 %s 需要 %d 个参数，但得到 %d 个", i18n.English: "function %s needs %d arguments, got %d"},
+	"argument_count.range":        {i18n.Chinese: "函数 %s 需要 %d 到 %d 个参数，但得到 %d 个", i18n.English: "function %s needs %d to %d arguments, got %d"},
+	"argument_count.user":         {i18n.Chinese: "函数 %s 需要 %d 个参数，但提供了 %d 个", i18n.English: "function %s takes %d arguments, but %d were given"},
+	"argument_count.spread":       {i18n.Chinese: "%s（列表参数按元素个数计算）", i18n.English: "%s (list arguments count as their elements)"},
+	"call_depth.exceeded":         {i18n.Chinese: "函数 %s 的调用深度超过 %d 层", i18n.English: "call depth of function %s exceeds %d"},
+	"invalid_argument.lambda":     {i18n.Chinese: "匿名函数只能作为 map、filter 的参数: %s", i18n.English: "anonymous functions can only be arguments of map and filter: %s"},
+	"definition.no_value":         {i18n.Chinese: "函数定义 %s 没有值，请使用 Env.Exec 或 Env.Define", i18n.English: "function definition %s has no value, use Env.Exec or Env.Define"},
+	"internal.eval_node":          {i18n.Chinese: "无法求值的节点: %T", i18n.English: "cannot evaluate node: %T"},
+	"not_number.function_list":    {i18n.Chinese: "函数 %s 的参数不能是列表: %s", i18n.English: "arguments of function %s cannot be lists: %s"},
+	"definition.func_name":        {i18n.Chinese: "无效的函数名: %q", i18n.English: "invalid function name: %q"},
+	"definition.func_impl":        {i18n.Chinese: "函数 %s 的实现不能为空", i18n.English: "function %s has no implementation"},
+	"definition.func_arity":       {i18n.Chinese: "函数 %s 的参数个数无效", i18n.English: "function %s has an invalid number of parameters"},
+	"definition.const_value":      {i18n.Chinese: "无效的常量值: %s = %q", i18n.English: "invalid constant value: %s = %q"},
+	"definition.const_name":       {i18n.Chinese: "无效的常量名: %q", i18n.English: "invalid constant name: %q"},
+	"definition.builtin":          {i18n.Chinese: "不能重新定义内置函数: %s", i18n.English: "cannot redefine built-in function: %s"},
+	"definition.constant_name":    {i18n.Chinese: "函数名与常量重名: %s", i18n.English: "function name conflicts with a constant: %s"},
+	"definition.not_func_def":     {i18n.Chinese: "不是函数定义: %s", i18n.English: "not a function definition: %s"},
+
+	// 设置
+	"setting.mode_number": {i18n.Chinese: "未知的计算模式: %d", i18n.English: "unknown mode: %d"},
+	"setting.mode":        {i18n.Chinese: "未知的计算模式: %s（可选 float、big、rat）", i18n.English: "unknown mode: %s (float, big or rat)"},
+	"setting.precision":   {i18n.Chinese: "精度必须在 1 到 %d 位之间: %d", i18n.English: "precision must be between 1 and %d bits: %d"},
+
+	// 用途，用于“不能是列表”等错误
+	"use.condition": {i18n.Chinese: "条件", i18n.English: "condition"},
+	"use.convert":   {i18n.Chinese: "单位换算的数量", i18n.English: "amount to convert"},
+	"use.operand":   {i18n.Chinese: "运算符 '%s' 的操作数", i18n.English: "operand of '%s'"},
+
+	// 列表
+	"not_number.list":                  {i18n.Chinese: "结果是列表，不是数值: %s", i18n.English: "result is a list, not a number: %s"},
+	"not_number.quantity":              {i18n.Chinese: "结果带有单位 %s，不是数值: %s", i18n.English: "result has unit %s, not a number: %s"},
+	"not_number.use":                   {i18n.Chinese: "%s不能是列表: %s", i18n.English: "%s cannot be a list: %s"},
+	"list_too_long.list":               {i18n.Chinese: "列表超过 %d 个元素", i18n.English: "list has more than %d elements"},
+	"not_integer.range":                {i18n.Chinese: "范围的端点必须是整数: %s .. %s", i18n.English: "range bounds must be integers: %s .. %s"},
+	"list_too_long.range":              {i18n.Chinese: "范围 %s .. %s 超过 %d 个元素", i18n.English: "range %s .. %s has more than %d elements"},
+	"invalid_argument.list":            {i18n.Chinese: "函数 %s 的第一个参数必须是列表: %s", i18n.English: "the first argument of %s must be a list: %s"},
+	"invalid_argument.callback":        {i18n.Chinese: "函数 %s 的第二个参数必须是匿名函数或单参数函数的名称，例如 x => x * 2: %s", i18n.English: "the second argument of %s must be an anonymous function or the name of a one-parameter function, such as x => x * 2: %s"},
+	"invalid_argument.callback_result": {i18n.Chinese: "函数 %s 的函数参数必须返回数值: %s", i18n.English: "the function passed to %s must return a number: %s"},
+	"invalid_argument.percentile":      {i18n.Chinese: "百分位必须在 0 到 100 之间: %s", i18n.English: "percentile must be between 0 and 100: %s"},
+	"invalid_argument.list_function":   {i18n.Chinese: "函数 %s 的参数必须是列表和函数", i18n.English: "the arguments of %s must be a list and a function"},
+
+	// 以太坊单位和输出
+	"unknown_unit.ether":    {i18n.Chinese: "未知的以太坊单位: %s", i18n.English: "unknown ether unit: %s"},
+	"not_integer.wei":       {i18n.Chinese: "金额 %s %s 不是整数 wei", i18n.English: "amount %s %s is not a whole number of wei"},
+	"format.quantity_ether": {i18n.Chinese: "带单位的结果不能按以太坊单位 %s 输出: %s", i18n.English: "a result with a unit cannot be printed in ether unit %s: %s"},
+	"format.sci_base":       {i18n.Chinese: "科学计数法只能用于十进制输出", i18n.English: "scientific notation only works with base 10"},
+	"format.base":           {i18n.Chinese: "不支持的输出进制: %d（可选 2、8、10、16）", i18n.English: "unsupported output base: %d (2, 8, 10 or 16)"},
+	"format.not_integer":    {i18n.Chinese: "只有整数可以按%s输出: %s", i18n.English: "only integers can be printed in %s: %s"},
+
+	// 规模限制和脚本
+	"too_long.bytes":   {i18n.Chinese: "表达式过长: %d 字节，最多 %d 字节", i18n.English: "expression too long: %d bytes, at most %d"},
+	"too_deep.levels":  {i18n.Chinese: "表达式嵌套过深: %d 层，最多 %d 层", i18n.English: "expression nested too deeply: %d levels, at most %d"},
+	"too_large.digits": {i18n.Chinese: "结果过大: 约 %d 位数字，最多 %d 位", i18n.English: "result too large: about %d digits, at most %d"},
+	"stopped.err":      {i18n.Chinese: "计算已停止: %s", i18n.English: "calculation stopped: %s"},
+	"read.script":      {i18n.Chinese: "读取脚本失败: %v", i18n.English: "failed to read script: %v"},
+
+	// 编译和求导
+	"not_compilable.unit":          {i18n.Chinese: "编译的表达式不支持单位: %s", i18n.English: "compiled expressions do not support units: %s"},
+	"not_compilable.list":          {i18n.Chinese: "编译的表达式不支持列表: %s", i18n.English: "compiled expressions do not support lists: %s"},
+	"not_compilable.assign":        {i18n.Chinese: "编译的表达式不支持赋值: %s", i18n.English: "compiled expressions do not support assignments: %s"},
+	"not_compilable.func_def":      {i18n.Chinese: "编译的表达式不支持函数定义: %s", i18n.English: "compiled expressions do not support function definitions: %s"},
+	"not_compilable.list_function": {i18n.Chinese: "编译的表达式不支持列表函数: %s", i18n.English: "compiled expressions do not support list functions: %s"},
+	"internal.compile_node":        {i18n.Chinese: "无法编译的节点: %T", i18n.English: "cannot compile node: %T"},
+	"not_differentiable.statement": {i18n.Chinese: "只能对表达式求导，不能对 %s 求导", i18n.English: "only expressions can be differentiated, not %s"},
+	"not_differentiable.node":      {i18n.Chinese: "无法对 %s 求导", i18n.English: "cannot differentiate %s"},
+	"not_differentiable.operator":  {i18n.Chinese: "无法对 %s 求导: 不支持运算符 '%s'", i18n.English: "cannot differentiate %s: operator '%s' is not supported"},
+	"not_differentiable.function":  {i18n.Chinese: "无法对 %s 求导: 不支持函数 %s", i18n.English: "cannot differentiate %s: function %s is not supported"},
+
+	// 单位
+	"dimension.ether":          {i18n.Chinese: "量纲不匹配: %s 不能换算为以太坊单位 %s", i18n.English: "dimension mismatch: %s cannot be converted to ether unit %s"},
+	"dimension.convert":        {i18n.Chinese: "量纲不匹配: %s 不能换算为 %s", i18n.English: "dimension mismatch: %s cannot be converted to %s"},
+	"dimension.operator":       {i18n.Chinese: "量纲不匹配: %s %s %s", i18n.English: "dimension mismatch: %s %s %s"},
+	"dimension.function":       {i18n.Chinese: "量纲不匹配: 函数 %s 的参数 %s 与 %s", i18n.English: "dimension mismatch: arguments %[2]s and %[3]s of function %[1]s"},
+	"unit.exponent_quantity":   {i18n.Chinese: "指数不能带单位: %s", i18n.English: "an exponent cannot have a unit: %s"},
+	"unit.exponent":            {i18n.Chinese: "带单位的数值的指数必须是绝对值不超过 %d 的整数: %s", i18n.English: "the exponent of a quantity must be an integer of at most %d in absolute value: %s"},
+	"unit.exponent_overflow":   {i18n.Chinese: "单位 %s 的指数超过 %d", i18n.English: "an exponent of unit %s exceeds %d"},
+	"unit.operator":            {i18n.Chinese: "运算符 '%s' 不能用于带单位的数值: %s %s %s", i18n.English: "operator '%s' cannot be used with quantities: %s %s %s"},
+	"unit.function":            {i18n.Chinese: "函数 %s 的参数不能带单位", i18n.English: "arguments of function %s cannot have units"},
+	"definition.unit_name":     {i18n.Chinese: "无效的单位名: %q", i18n.English: "invalid unit name: %q"},
+	"definition.unit_keyword":  {i18n.Chinese: "单位名不能是关键字: %s", i18n.English: "a unit name cannot be a keyword: %s"},
+	"definition.unit_ether":    {i18n.Chinese: "单位名与以太坊单位重复: %s", i18n.English: "unit name conflicts with an ether unit: %s"},
+	"definition.unit":          {i18n.Chinese: "单位 %s 的定义无效: %s", i18n.English: "invalid definition of unit %s: %s"},
+	"unit.empty":               {i18n.Chinese: "单位不能为空", i18n.English: "unit is empty"},
+	"unit.unexpected":          {i18n.Chinese: "单位 %s 中意外的 %s", i18n.English: "unexpected %[2]s in unit %[1]s"},
+	"unit.missing_name":        {i18n.Chinese: "单位 %s 中缺少单位名称，得到 %s", i18n.English: "missing unit name in %s, got %s"},
+	"unknown_unit.name":        {i18n.Chinese: "未知的单位: %s", i18n.English: "unknown unit: %s"},
+	"unit.definition_exponent": {i18n.Chinese: "单位 %s 的指数必须是绝对值不超过 %d 的非零整数", i18n.English: "exponents in unit %s must be non-zero integers of at most %d in absolute value"},
+
+	// 单位文件
+	"unit_file.yaml_scalar":   {i18n.Chinese: "第 %d 行: 单位定义必须是字符串或数字", i18n.English: "line %d: a unit definition must be a string or a number"},
+	"unit_file.json_value":    {i18n.Chinese: "单位定义必须是字符串或数字: %s", i18n.English: "a unit definition must be a string or a number: %s"},
+	"unit_file.path":          {i18n.Chinese: "%s: %s", i18n.English: "%s: %s"},
+	"unit_file.format":        {i18n.Chinese: "不支持的单位文件格式 %q（可选 .yaml、.yml、.json）", i18n.English: "unsupported unit file format %q (.yaml, .yml or .json)"},
+	"unit_file.currency_base": {i18n.Chinese: "汇率需要指定基准货币 currency.base", i18n.English: "exchange rates need a base currency in currency.base"},
+	"unit_file.duplicate":     {i18n.Chinese: "重复定义的单位: %s", i18n.English: "unit defined twice: %s"},
+
+	// 用例文件
+	"case_file.position":         {i18n.Chinese: "%s:%d: %s", i18n.English: "%s:%d: %s"},
+	"case_file.no_statement":     {i18n.Chinese: "期望之前没有语句", i18n.English: "expectation without a statement"},
+	"case_file.duplicate":        {i18n.Chinese: "第 %d 行的语句已经有期望", i18n.English: "the statement on line %d already has an expectation"},
+	"case_file.missing_result":   {i18n.Chinese: "缺少期望的结果", i18n.English: "missing expected result"},
+	"case_file.missing_code":     {i18n.Chinese: "缺少期望的错误代码", i18n.English: "missing expected error code"},
+	"case_file.unknown_code":     {i18n.Chinese: "未知的错误代码: %s", i18n.English: "unknown error code: %s"},
+	"case_file.setting_position": {i18n.Chinese: "设置必须写在所有语句之前", i18n.English: "settings must come before all statements"},
+	"case_file.setting_format":   {i18n.Chinese: "设置的格式为 @名称 值: @%s", i18n.English: "settings are written as @name value: @%s"},
+	"case_file.precision":        {i18n.Chinese: "无效的精度: %s", i18n.English: "invalid precision: %s"},
+	"case_file.setting":          {i18n.Chinese: "未知的设置: @%s", i18n.English: "unknown setting: @%s"},
+	"case_file.lang":             {i18n.Chinese: "不支持的语言: %s", i18n.English: "unsupported language: %s"},
+	"read.case_file":             {i18n.Chinese: "读取用例文件 %s 失败: %v", i18n.English: "failed to read case file %s: %v"},
+	"case.any_result":            {i18n.Chinese: "(任意结果)", i18n.English: "(any result)"},
+	"case.valid_setting":         {i18n.Chinese: "(有效的设置)", i18n.English: "(a valid setting)"},
+}
@@ -0,0 +1,74 @@
+package calculator
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"cli_cmd/i18n"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// formatVerb 匹配格式化动词，%% 不算
+var formatVerb = regexp.MustCompile(`%(\[[0-9]+\])?[-+# 0-9.]*[a-zA-Z]`)
+
+// TestMessageCatalog 测试消息目录的每条消息都有各语言的版本，且参数个数一致
+func TestMessageCatalog(t *testing.T) {
+	for key, texts := range messages {
+		zh := texts[i18n.Chinese]
+		verbs := len(formatVerb.FindAllString(strings.ReplaceAll(zh, "%%", ""), -1))
+		for _, lang := range i18n.Langs() {
+			text, ok := texts[lang]
+			if !assert.True(t, ok, "%s 缺少 %s 的消息", key, lang) {
+				continue
+			}
+			assert.NotEmpty(t, text, "%s 的 %s 消息为空", key, lang)
+			assert.Equal(t, verbs, len(formatVerb.FindAllString(strings.ReplaceAll(text, "%%", ""), -1)),
+				"%s 的 %s 消息参数个数与中文不同", key, lang)
+		}
+
+		if !strings.Contains(key, ".") {
+			assert.Zero(t, verbs, "错误标识 %s 的说明不能带参数", key)
+		}
+	}
+}
+
+// TestErrorIDsDescribed 测试每个错误标识都有不带参数的说明，token、base 等键是错误信息的组成部分，不是错误
+func TestErrorIDsDescribed(t *testing.T) {
+	for key := range messages {
+		id, _, _ := strings.Cut(key, ".")
+		switch id {
+		case "token", "base", "use", "position", "case", "number", "list":
+			continue
+		}
+		assert.True(t, isErrorID(id), "%s 的错误标识 %s 没有说明", key, id)
+	}
+}
+
+// TestLocalizedErrors 测试错误信息按语言输出
+func TestLocalizedErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		chinese    string
+		english    string
+		desc       string
+	}{
+		{"1 / 0", "除零错误", "division by zero", "除零"},
+		{"y + 1", "未定义的变量: y", "undefined variable: y", "未定义的变量"},
+		{"(1+2", "第 5 列: 期望 ')'，但得到 表达式结尾", "column 5: expected ')', got end of expression", "语法错误"},
+		{"1 @ 2", "第 3 列: 无法识别的字符 '@'", "column 3: unrecognized character '@'", "未知字符"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := NewEnv().Evaluate(test.expression)
+			l, ok := err.(i18n.Localizer)
+			if assert.True(t, ok, "错误 %T 不能本地化", err) {
+				assert.Equal(t, test.chinese, l.Localize(i18n.Chinese))
+				assert.Equal(t, test.english, l.Localize(i18n.English))
+			}
+			assert.Equal(t, test.chinese, err.Error())
+		})
+	}
+}
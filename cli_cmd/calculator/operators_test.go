@@ -0,0 +1,216 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPowerOperator 测试幂运算
+func TestPowerOperator(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+		desc       string
+	}{
+		{"2^10", 1024, "幂运算"},
+		{"2**10", 1024, "双星号幂运算"},
+		{"2^3^2", 512, "右结合"},
+		{"(2^3)^2", 64, "括号改变结合性"},
+		{"-2^2", -4, "幂运算优先于负号"},
+		{"2^-1", 0.5, "负指数"},
+		{"2*3^2", 18, "幂运算优先于乘法"},
+		{"4^0.5", 2, "小数指数"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result, err := Calculate(test.expression)
+			require.NoError(t, err, "计算 %s 时不应该出错", test.expression)
+			assert.Equal(t, test.expected, result, "表达式 %s 的结果应该是 %f", test.expression, test.expected)
+		})
+	}
+}
+
+// TestPowerOverflow 测试 float 模式下幂运算溢出时报错，与 big、rat 模式一致
+func TestPowerOverflow(t *testing.T) {
+	tests := []struct {
+		expression string
+		target     error
+		desc       string
+	}{
+		{"2^(10^18)", ErrOutOfRange, "指数过大"},
+		{"10^400", ErrOutOfRange, "刚好超出 float64"},
+		{"(-2)^1025", ErrOutOfRange, "负无穷"},
+		{"pow(2, 1024)", ErrOutOfRange, "pow 函数"},
+		{"0^-1", ErrDivisionByZero, "零的负数次幂"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Calculate(test.expression)
+			assert.ErrorIs(t, err, test.target)
+
+			prog, err := Compile(test.expression)
+			if err == nil {
+				_, err = prog.Eval(NewEnv())
+			}
+			assert.ErrorIs(t, err, test.target, "编译后执行")
+		})
+	}
+
+	result, err := Calculate("2^1023")
+	require.NoError(t, err, "刚好不溢出")
+	assert.Equal(t, 8.98846567431158e307, result)
+}
+
+// TestBitwiseOperators 测试位运算
+func TestBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+		desc       string
+	}{
+		{"6 & 3", 2, "按位与"},
+		{"6 | 3", 7, "按位或"},
+		{"6 xor 3", 5, "按位异或"},
+		{"1 << 10", 1024, "左移"},
+		{"1024 >> 3", 128, "右移"},
+		{"1 + 1 << 2", 8, "加法优先于移位"},
+		{"1 | 2 & 3", 3, "按位与优先于按位或"},
+		{"1 | 6 xor 3", 5, "异或优先于按位或"},
+		{"-8 >> 1", -4, "算术右移"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result, err := Calculate(test.expression)
+			require.NoError(t, err, "计算 %s 时不应该出错", test.expression)
+			assert.Equal(t, test.expected, result, "表达式 %s 的结果应该是 %f", test.expression, test.expected)
+		})
+	}
+}
+
+// TestComparisonAndLogic 测试比较和逻辑运算
+func TestComparisonAndLogic(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+		desc       string
+	}{
+		{"1 == 1", 1, "相等"},
+		{"1 != 1", 0, "不相等"},
+		{"1 < 2", 1, "小于"},
+		{"2 <= 2", 1, "小于等于"},
+		{"1 > 2", 0, "大于"},
+		{"3 >= 2", 1, "大于等于"},
+		{"1 + 1 == 2", 1, "算术优先于比较"},
+		{"1 < 2 == 1", 1, "大小比较优先于相等比较"},
+		{"1 && 0", 0, "逻辑与"},
+		{"1 || 0", 1, "逻辑或"},
+		{"5 && 3", 1, "逻辑结果归一化"},
+		{"!0", 1, "逻辑非"},
+		{"!5", 0, "非零取反"},
+		{"!!5", 1, "双重取反"},
+		{"0 || 1 && 0", 0, "与优先于或"},
+		{"0 && 1/0", 0, "与短路"},
+		{"1 || 1/0", 1, "或短路"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result, err := Calculate(test.expression)
+			require.NoError(t, err, "计算 %s 时不应该出错", test.expression)
+			assert.Equal(t, test.expected, result, "表达式 %s 的结果应该是 %f", test.expression, test.expected)
+		})
+	}
+}
+
+// TestTernary 测试条件表达式
+func TestTernary(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+		desc       string
+	}{
+		{"1 ? 2 : 3", 2, "条件为真"},
+		{"0 ? 2 : 3", 3, "条件为假"},
+		{"1 > 2 ? 10 : 20", 20, "比较作为条件"},
+		{"0 ? 1 : 0 ? 2 : 3", 3, "右结合"},
+		{"1 ? 0 ? 4 : 5 : 6", 5, "嵌套在中间分支"},
+		{"(1 ? 2 : 3) + 1", 3, "括号内条件表达式"},
+		{"1 ? 2 : 1/0", 2, "未选中的分支不求值"},
+		{"max(0 ? 1 : 2, 1)", 2, "函数参数中的条件表达式"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			result, err := Calculate(test.expression)
+			require.NoError(t, err, "计算 %s 时不应该出错", test.expression)
+			assert.Equal(t, test.expected, result, "表达式 %s 的结果应该是 %f", test.expression, test.expected)
+		})
+	}
+}
+
+// TestTernaryAssignment 测试条件表达式赋值
+func TestTernaryAssignment(t *testing.T) {
+	env := NewEnv()
+	result, err := env.Calculate("fee = 5 > 3 ? 21000 : 0")
+	require.NoError(t, err)
+	assert.Equal(t, 21000.0, result)
+}
+
+// TestOperatorErrors 测试运算符错误
+func TestOperatorErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		desc       string
+	}{
+		{"1.5 & 1", "小数位运算"},
+		{"1 << -1", "负数移位"},
+		{"1 << 64", "移位过多"},
+		{"(-8)^(1/3)", "负数的小数次幂"},
+		{"1 ? 2", "缺少冒号"},
+		{"1 ? : 2", "缺少中间分支"},
+		{"1 ==", "比较缺少右侧"},
+		{"1 & & 2", "非法运算符组合"},
+		{"xor 1", "关键字开头"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := Calculate(test.expression)
+			assert.Error(t, err, "表达式 %s 应该产生错误", test.expression)
+		})
+	}
+}
+
+// TestPrecedenceTable 测试优先级表的顺序
+func TestPrecedenceTable(t *testing.T) {
+	order := [][]TokenType{
+		{OR},
+		{AND},
+		{BITOR},
+		{BITXOR},
+		{BITAND},
+		{EQ, NEQ},
+		{LT, LE, GT, GE},
+		{SHL, SHR},
+		{PLUS, MINUS},
+		{MULTIPLY, DIVIDE, MODULO},
+		{POWER},
+	}
+
+	prev := precTernary
+	for _, level := range order {
+		prec := binaryOps[level[0]].prec
+		assert.Greater(t, prec, prev, "%s 的优先级应该更高", operatorSymbol(level[0]))
+		for _, op := range level {
+			assert.Equal(t, prec, binaryOps[op].prec, "%s 应该与 %s 同级", operatorSymbol(op), operatorSymbol(level[0]))
+		}
+		prev = prec
+	}
+	assert.Less(t, binaryOps[MULTIPLY].prec, precUnary)
+	assert.Greater(t, binaryOps[POWER].prec, precUnary)
+}
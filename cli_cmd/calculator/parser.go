@@ -1,16 +1,17 @@
 package calculator
 
 import (
+	"errors"
 	"fmt"
-	"strconv"
-	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // Token 表示表达式中的一个标记
 type Token struct {
 	Type  TokenType
 	Value string
+	Pos   int // 标记在输入中的起始偏移（字节）
 }
 
 // TokenType 标记类型
@@ -18,108 +19,275 @@ type TokenType int
 
 const (
 	NUMBER TokenType = iota
+	IDENT
 	PLUS
 	MINUS
 	MULTIPLY
 	DIVIDE
 	MODULO
+	POWER    // ^ 或 **
+	BITAND   // &
+	BITOR    // |
+	BITXOR   // xor
+	SHL      // <<
+	SHR      // >>
+	EQ       // ==
+	NEQ      // !=
+	LT       // <
+	LE       // <=
+	GT       // >
+	GE       // >=
+	AND      // &&
+	OR       // ||
+	NOT      // !
+	QUESTION // ?
+	COLON    // :
+	IN       // in
 	LPAREN
 	RPAREN
+	COMMA
+	ASSIGN
+	LBRACKET // [
+	RBRACKET // ]
+	DOTDOT   // ..
+	ARROW    // =>
 	EOF
+	ILLEGAL // 无法识别的字符
 )
 
+// keywords 作为运算符使用的关键字
+var keywords = map[string]TokenType{
+	"xor": BITXOR,
+	"in":  IN,
+}
+
 // Lexer 词法分析器
 type Lexer struct {
 	input    string
 	position int
 	current  rune
+	width    int // 当前字符占用的字节数
 }
 
 // NewLexer 创建新的词法分析器
 func NewLexer(input string) *Lexer {
 	l := &Lexer{
-		input:    strings.ReplaceAll(input, " ", ""), // 移除所有空格
+		input:    input,
 		position: 0,
 	}
-	if len(l.input) > 0 {
-		l.current = rune(l.input[0])
-	}
+	l.decode()
 	return l
 }
 
+// decode 解码当前位置的字符
+func (l *Lexer) decode() {
+	if l.position >= len(l.input) {
+		l.current, l.width = 0, 0 // EOF
+		return
+	}
+	l.current, l.width = utf8.DecodeRuneInString(l.input[l.position:])
+}
+
+// atEOF 判断是否已读完输入；输入中的 NUL 字符不是结束
+func (l *Lexer) atEOF() bool {
+	return l.position >= len(l.input)
+}
+
 // advance 移动到下一个字符
 func (l *Lexer) advance() {
-	l.position++
-	if l.position >= len(l.input) {
-		l.current = 0 // EOF
-	} else {
-		l.current = rune(l.input[l.position])
+	l.position += l.width
+	l.decode()
+}
+
+// peek 返回下一个字符但不移动位置
+func (l *Lexer) peek() rune {
+	next := l.position + l.width
+	if next >= len(l.input) {
+		return 0
 	}
+	ch, _ := utf8.DecodeRuneInString(l.input[next:])
+	return ch
 }
 
-// skipWhitespace 跳过空白字符
+// skipWhitespace 跳过空白字符和 # 开头的注释（直到行尾）
 func (l *Lexer) skipWhitespace() {
-	for l.current != 0 && unicode.IsSpace(l.current) {
+	for !l.atEOF() {
+		switch {
+		case unicode.IsSpace(l.current):
+			l.advance()
+		case l.current == '#':
+			for !l.atEOF() && l.current != '\n' {
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// readNumber 读取数字字面量，格式见 scanNumber；格式错误时 ok 为 false
+func (l *Lexer) readNumber() (lit string, ok bool) {
+	start := l.position
+	n, reason := scanNumber(l.input[start:])
+	for l.position < start+n {
 		l.advance()
 	}
+	return l.input[start:l.position], reason == nil
 }
 
-// readNumber 读取数字
-func (l *Lexer) readNumber() string {
+// readIdent 读取标识符（字母或下划线开头，后跟字母、数字或下划线）
+func (l *Lexer) readIdent() string {
 	start := l.position
-	for l.current != 0 && (unicode.IsDigit(l.current) || l.current == '.') {
+	for !l.atEOF() && isIdentChar(l.current) {
 		l.advance()
 	}
 	return l.input[start:l.position]
 }
 
+func isNumberStart(ch byte) bool {
+	return isDigitOf(ch, 10) || ch == '.'
+}
+
+func isIdentStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+func isIdentChar(ch rune) bool {
+	return isIdentStart(ch) || unicode.IsDigit(ch)
+}
+
 // NextToken 获取下一个标记
 func (l *Lexer) NextToken() Token {
-	for l.current != 0 {
-		l.skipWhitespace()
+	l.skipWhitespace()
+	pos := l.position
+
+	if l.atEOF() {
+		return Token{EOF, "", pos}
+	}
 
-		if unicode.IsDigit(l.current) {
-			return Token{NUMBER, l.readNumber()}
+	// ".." 是范围运算符，不是数字
+	if l.current < utf8.RuneSelf && isNumberStart(byte(l.current)) && !(l.current == '.' && l.peek() == '.') {
+		lit, ok := l.readNumber()
+		if !ok {
+			return Token{ILLEGAL, lit, pos}
 		}
+		return Token{NUMBER, lit, pos}
+	}
 
-		switch l.current {
-		case '+':
-			l.advance()
-			return Token{PLUS, "+"}
-		case '-':
-			l.advance()
-			return Token{MINUS, "-"}
-		case '*':
-			l.advance()
-			return Token{MULTIPLY, "*"}
-		case '/':
-			l.advance()
-			return Token{DIVIDE, "/"}
-		case '%':
-			l.advance()
-			return Token{MODULO, "%"}
-		case '(':
-			l.advance()
-			return Token{LPAREN, "("}
-		case ')':
-			l.advance()
-			return Token{RPAREN, ")"}
-		default:
-			return Token{EOF, ""}
+	if isIdentStart(l.current) {
+		ident := l.readIdent()
+		if t, ok := keywords[ident]; ok {
+			return Token{t, ident, pos}
 		}
+		return Token{IDENT, ident, pos}
+	}
+
+	// 双字符运算符
+	if t, ok := twoCharOps[[2]rune{l.current, l.peek()}]; ok {
+		l.advance()
+		l.advance()
+		return Token{t, l.input[pos:l.position], pos}
+	}
+
+	t, ok := oneCharOps[l.current]
+	if !ok {
+		l.advance()
+		return Token{ILLEGAL, l.input[pos:l.position], pos}
 	}
-	return Token{EOF, ""}
+	l.advance()
+	return Token{t, l.input[pos:l.position], pos}
+}
+
+// oneCharOps 单字符运算符和分隔符
+var oneCharOps = map[rune]TokenType{
+	'+': PLUS,
+	'-': MINUS,
+	'*': MULTIPLY,
+	'/': DIVIDE,
+	'%': MODULO,
+	'^': POWER,
+	'&': BITAND,
+	'|': BITOR,
+	'<': LT,
+	'>': GT,
+	'!': NOT,
+	'?': QUESTION,
+	':': COLON,
+	'(': LPAREN,
+	')': RPAREN,
+	',': COMMA,
+	'=': ASSIGN,
+	'[': LBRACKET,
+	']': RBRACKET,
+}
+
+// twoCharOps 双字符运算符，优先于单字符运算符匹配
+var twoCharOps = map[[2]rune]TokenType{
+	{'*', '*'}: POWER,
+	{'<', '<'}: SHL,
+	{'>', '>'}: SHR,
+	{'=', '='}: EQ,
+	{'!', '='}: NEQ,
+	{'<', '='}: LE,
+	{'>', '='}: GE,
+	{'&', '&'}: AND,
+	{'|', '|'}: OR,
+	{'.', '.'}: DOTDOT,
+	{'=', '>'}: ARROW,
+}
+
+// 运算符优先级，数值越大结合越紧密
+const (
+	precLowest  = 0
+	precConvert = 1  // x in unit
+	precTernary = 2  // ?:
+	precUnary   = 14 // -x, +x, !x
+)
+
+// binaryOp 二元运算符的优先级和结合性
+type binaryOp struct {
+	prec       int
+	rightAssoc bool
+}
+
+// binaryOps 二元运算符优先级表
+var binaryOps = map[TokenType]binaryOp{
+	OR:       {prec: 3},
+	AND:      {prec: 4},
+	BITOR:    {prec: 5},
+	BITXOR:   {prec: 6},
+	BITAND:   {prec: 7},
+	EQ:       {prec: 8},
+	NEQ:      {prec: 8},
+	LT:       {prec: 9},
+	LE:       {prec: 9},
+	GT:       {prec: 9},
+	GE:       {prec: 9},
+	DOTDOT:   {prec: 10},
+	SHL:      {prec: 11},
+	SHR:      {prec: 11},
+	PLUS:     {prec: 12},
+	MINUS:    {prec: 12},
+	MULTIPLY: {prec: 13},
+	DIVIDE:   {prec: 13},
+	MODULO:   {prec: 13},
+	POWER:    {prec: 15, rightAssoc: true},
 }
 
 // Parser 语法分析器
 type Parser struct {
 	lexer        *Lexer
 	currentToken Token
+	registry     *Registry // 识别数字后的单位
 }
 
-// NewParser 创建新的语法分析器
+// NewParser 创建新的语法分析器，数字后的单位按默认注册表识别
 func NewParser(lexer *Lexer) *Parser {
-	p := &Parser{lexer: lexer}
+	return newParser(lexer, DefaultRegistry)
+}
+
+func newParser(lexer *Lexer, registry *Registry) *Parser {
+	p := &Parser{lexer: lexer, registry: registry}
 	p.currentToken = p.lexer.NextToken()
 	return p
 }
@@ -130,168 +298,410 @@ func (p *Parser) eat(tokenType TokenType) error {
 		p.currentToken = p.lexer.NextToken()
 		return nil
 	}
-	return fmt.Errorf("期望标记类型 %v，但得到 %v", tokenType, p.currentToken.Type)
+	return p.unexpected(tokenType)
+}
+
+// errorAt 在指定标记处创建语法错误，key 是消息目录中的键
+func (p *Parser) errorAt(tok Token, expected []TokenType, key string, args ...interface{}) error {
+	return newSyntaxError(p.lexer.input, tok.Pos, tok.Value, expected, key, args...)
 }
 
-// factor 解析因子（数字或括号表达式）
-func (p *Parser) factor() (float64, error) {
+// unexpected 报告当前标记不是期望的标记
+func (p *Parser) unexpected(expected ...TokenType) error {
+	tok := p.currentToken
+	if tok.Type == ILLEGAL {
+		if _, reason := scanNumber(tok.Value); reason != nil && isNumberStart(tok.Value[0]) {
+			return p.errorAt(tok, nil, "invalid_number.syntax", tok.Value, reason)
+		}
+		return p.errorAt(tok, expected, "illegal_character.char", tok.Value)
+	}
+	if len(expected) == 0 {
+		return p.errorAt(tok, nil, "unexpected_token.token", describeToken(tok))
+	}
+	return p.errorAt(tok, expected, "unexpected_token.expected", expectedTokens(expected), describeToken(tok))
+}
+
+// operandStart 可以作为操作数开头的标记
+var operandStart = []TokenType{NUMBER, IDENT, LPAREN, LBRACKET}
+
+// operand 解析操作数（数字、变量、函数调用、匿名函数、括号表达式、列表或一元表达式）
+func (p *Parser) operand() (Node, error) {
 	token := p.currentToken
 
-	if token.Type == NUMBER {
+	switch token.Type {
+	case NUMBER:
 		if err := p.eat(NUMBER); err != nil {
-			return 0, err
+			return nil, err
 		}
-		value, err := strconv.ParseFloat(token.Value, 64)
-		if err != nil {
-			return 0, fmt.Errorf("无法解析数字: %s", token.Value)
+		// 语法由词法分析保证；超出 float64 范围的数字留给 parseNumber 按计算模式处理
+		value, err := parseFloatLiteral(token.Value)
+		if err != nil && !errors.Is(err, ErrOutOfRange) {
+			return nil, p.errorAt(token, nil, "invalid_number.literal_syntax", token.Value)
 		}
-		return value, nil
-	}
+		lit := &NumberLit{ValuePos: token.Pos, Literal: token.Value, Value: value}
+
+		// 数字后紧跟单位，例如 1.5ether、30 gwei、60 km/h
+		if unit := p.currentToken; unit.Type == IDENT && (IsEtherUnit(unit.Value) || p.registry.HasUnit(unit.Value)) {
+			unit, err := p.unit()
+			if err != nil {
+				return nil, err
+			}
+			return &UnitLit{Value: lit, UnitPos: unit.Pos, Unit: unit.Value}, nil
+		}
+		return lit, nil
 
-	if token.Type == LPAREN {
+	case IDENT:
+		if err := p.eat(IDENT); err != nil {
+			return nil, err
+		}
+		ident := &Ident{NamePos: token.Pos, Name: token.Value}
+		switch p.currentToken.Type {
+		case LPAREN:
+			return p.call(ident)
+		case ARROW:
+			return p.lambda(ident)
+		}
+		return ident, nil
+
+	case LPAREN:
 		if err := p.eat(LPAREN); err != nil {
-			return 0, err
+			return nil, err
 		}
-		result, err := p.expr()
+		x, err := p.expr()
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
+		rparen := p.currentToken.Pos
 		if err := p.eat(RPAREN); err != nil {
-			return 0, err
+			return nil, err
 		}
-		return result, nil
-	}
+		return &ParenExpr{Lparen: token.Pos, X: x, Rparen: rparen}, nil
 
-	if token.Type == MINUS {
-		if err := p.eat(MINUS); err != nil {
-			return 0, err
+	case LBRACKET:
+		if err := p.eat(LBRACKET); err != nil {
+			return nil, err
 		}
-		result, err := p.factor()
+		elems, rbrack, err := p.exprList(RBRACKET)
 		if err != nil {
-			return 0, err
+			return nil, err
+		}
+		return &ListExpr{Lbrack: token.Pos, Elems: elems, Rbrack: rbrack}, nil
+
+	case MINUS, PLUS, NOT:
+		if err := p.eat(token.Type); err != nil {
+			return nil, err
 		}
-		return -result, nil
+		x, err := p.parseExpr(precUnary)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{OpPos: token.Pos, Op: token.Type, X: x}, nil
+	}
+
+	return nil, p.unexpected(operandStart...)
+}
+
+// call 解析函数调用的参数列表
+func (p *Parser) call(fun *Ident) (Node, error) {
+	lparen := p.currentToken.Pos
+	if err := p.eat(LPAREN); err != nil {
+		return nil, err
 	}
 
-	if token.Type == PLUS {
-		if err := p.eat(PLUS); err != nil {
-			return 0, err
+	args, rparen, err := p.exprList(RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	return &CallExpr{Fun: fun, Lparen: lparen, Args: args, Rparen: rparen}, nil
+}
+
+// exprList 解析以逗号分隔的表达式列表直到 end，返回表达式和 end 标记的位置
+func (p *Parser) exprList(end TokenType) ([]Node, int, error) {
+	var list []Node
+	if p.currentToken.Type != end {
+		for {
+			x, err := p.expr()
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, x)
+			if p.currentToken.Type != COMMA {
+				break
+			}
+			if err := p.eat(COMMA); err != nil {
+				return nil, 0, err
+			}
 		}
-		return p.factor()
 	}
 
-	return 0, fmt.Errorf("意外的标记: %s", token.Value)
+	pos := p.currentToken.Pos
+	if err := p.eat(end); err != nil {
+		return nil, 0, err
+	}
+	return list, pos, nil
 }
 
-// term 解析项（处理 *, /, % 运算符）
-func (p *Parser) term() (float64, error) {
-	result, err := p.factor()
+// lambda 解析匿名函数 x => body 中 "=>" 及之后的部分
+func (p *Parser) lambda(param *Ident) (Node, error) {
+	arrow := p.currentToken.Pos
+	if err := p.eat(ARROW); err != nil {
+		return nil, err
+	}
+	body, err := p.expr()
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	return &Lambda{Param: param, Arrow: arrow, Body: body}, nil
+}
+
+// parseExpr 使用优先级爬升法解析优先级不低于 minPrec 的表达式
+func (p *Parser) parseExpr(minPrec int) (Node, error) {
+	x, err := p.operand()
+	if err != nil {
+		return nil, err
 	}
 
-	for p.currentToken.Type == MULTIPLY || p.currentToken.Type == DIVIDE || p.currentToken.Type == MODULO {
+	for {
 		token := p.currentToken
 
-		switch token.Type {
-		case MULTIPLY:
-			if err := p.eat(MULTIPLY); err != nil {
-				return 0, err
-			}
-			right, err := p.factor()
+		if token.Type == IN && precConvert >= minPrec {
+			x, err = p.convert(x)
 			if err != nil {
-				return 0, err
+				return nil, err
 			}
-			result = result * right
+			continue
+		}
 
-		case DIVIDE:
-			if err := p.eat(DIVIDE); err != nil {
-				return 0, err
-			}
-			right, err := p.factor()
+		if token.Type == QUESTION && precTernary >= minPrec {
+			x, err = p.ternary(x)
 			if err != nil {
-				return 0, err
+				return nil, err
 			}
-			if right == 0 {
-				return 0, fmt.Errorf("除零错误")
-			}
-			result = result / right
+			continue
+		}
 
-		case MODULO:
-			if err := p.eat(MODULO); err != nil {
-				return 0, err
-			}
-			right, err := p.factor()
-			if err != nil {
-				return 0, err
-			}
-			if right == 0 {
-				return 0, fmt.Errorf("模运算的除数不能为零")
-			}
-			// Go的浮点数取模运算
-			result = float64(int(result) % int(right))
+		op, ok := binaryOps[token.Type]
+		if !ok || op.prec < minPrec {
+			return x, nil
+		}
+		if err := p.eat(token.Type); err != nil {
+			return nil, err
+		}
+
+		next := op.prec + 1
+		if op.rightAssoc {
+			next = op.prec
+		}
+		y, err := p.parseExpr(next)
+		if err != nil {
+			return nil, err
 		}
+		x = &BinaryExpr{X: x, OpPos: token.Pos, Op: token.Type, Y: y}
 	}
+}
 
-	return result, nil
+// ternary 解析条件表达式 cond ? a : b 中 "?" 之后的部分
+func (p *Parser) ternary(cond Node) (Node, error) {
+	question := p.currentToken.Pos
+	if err := p.eat(QUESTION); err != nil {
+		return nil, err
+	}
+	then, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	colon := p.currentToken.Pos
+	if err := p.eat(COLON); err != nil {
+		return nil, err
+	}
+	// 条件表达式是右结合的：a ? b : c ? d : e
+	els, err := p.parseExpr(precTernary)
+	if err != nil {
+		return nil, err
+	}
+	return &TernaryExpr{Cond: cond, Question: question, Then: then, Colon: colon, Else: els}, nil
 }
 
-// expr 解析表达式（处理 +, - 运算符）
-func (p *Parser) expr() (float64, error) {
-	result, err := p.term()
+// convert 解析单位换算 x in unit 中 "in" 之后的部分
+func (p *Parser) convert(x Node) (Node, error) {
+	inPos := p.currentToken.Pos
+	if err := p.eat(IN); err != nil {
+		return nil, err
+	}
+	unit, err := p.unit()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
+	return &ConvertExpr{X: x, InPos: inPos, Unit: &Ident{NamePos: unit.Pos, Name: unit.Value}}, nil
+}
 
-	for p.currentToken.Type == PLUS || p.currentToken.Type == MINUS {
-		token := p.currentToken
+// unit 解析单位，返回单位在输入中的原始文本，例如 km、m/s、kg*m/s^2
+//
+// 复合单位的各部分之间不能有空白：60 km/h 是速度，而 5 km / 2 h 是两个数量相除。
+// 以太坊单位不能组成复合单位。
+func (p *Parser) unit() (Token, error) {
+	first := p.currentToken
+	if err := p.eat(IDENT); err != nil {
+		return Token{}, err
+	}
+	end := first.Pos + len(first.Value)
+	if IsEtherUnit(first.Value) {
+		return first, nil
+	}
+	for n := p.unitPart(end); n > 0; n = p.unitPart(end) {
+		end += n
+		for p.currentToken.Pos < end {
+			p.currentToken = p.lexer.NextToken()
+		}
+	}
+	return Token{Type: IDENT, Value: p.lexer.input[first.Pos:end], Pos: first.Pos}, nil
+}
 
-		switch token.Type {
-		case PLUS:
-			if err := p.eat(PLUS); err != nil {
-				return 0, err
-			}
-			right, err := p.term()
-			if err != nil {
-				return 0, err
+// unitPart 返回紧跟在 end 处的复合单位部分（*unit、/unit 或 ^n）的字节长度，没有时返回 0
+func (p *Parser) unitPart(end int) int {
+	lexer := *p.lexer // 向前查看，不影响原来的词法分析器
+	tok := p.currentToken
+	if tok.Pos != end {
+		return 0
+	}
+	next := lexer.NextToken()
+	if next.Pos != tok.Pos+len(tok.Value) {
+		return 0
+	}
+	switch tok.Type {
+	case MULTIPLY, DIVIDE:
+		if next.Type == IDENT && p.registry.HasUnit(next.Value) {
+			return next.Pos + len(next.Value) - end
+		}
+	case POWER:
+		if next.Type == MINUS {
+			if next = lexer.NextToken(); next.Pos != tok.Pos+len(tok.Value)+1 {
+				return 0
 			}
-			result = result + right
+		}
+		if next.Type == NUMBER && isUnitExponent(next.Value) {
+			return next.Pos + len(next.Value) - end
+		}
+	}
+	return 0
+}
 
-		case MINUS:
-			if err := p.eat(MINUS); err != nil {
-				return 0, err
-			}
-			right, err := p.term()
-			if err != nil {
-				return 0, err
-			}
-			result = result - right
+// expr 解析完整的表达式
+func (p *Parser) expr() (Node, error) {
+	return p.parseExpr(precLowest)
+}
+
+// statement 解析语句（赋值、函数定义或表达式）
+func (p *Parser) statement() (Node, error) {
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.currentToken.Type != ASSIGN {
+		return x, nil
+	}
+
+	switch lhs := x.(type) {
+	case *Ident:
+		eqPos := p.currentToken.Pos
+		if err := p.eat(ASSIGN); err != nil {
+			return nil, err
 		}
+		value, err := p.statement() // 支持 a = b = 1 形式的连续赋值
+		if err != nil {
+			return nil, err
+		}
+		if def, ok := value.(*FuncDef); ok {
+			return nil, p.errorAt(Token{Pos: def.Pos(), Value: def.Name.Name}, nil, "invalid_assignment.func_def")
+		}
+		return &AssignExpr{Name: lhs, EqPos: eqPos, Value: value}, nil
+	case *CallExpr:
+		return p.funcDef(lhs)
 	}
 
-	return result, nil
+	lhs := Token{Pos: x.Pos(), Value: p.lexer.input[x.Pos():x.End()]}
+	return nil, p.errorAt(lhs, []TokenType{IDENT}, "invalid_assignment.target", lhs.Value)
 }
 
-// Calculate 计算数学表达式
-func Calculate(expression string) (float64, error) {
-	if strings.TrimSpace(expression) == "" {
-		return 0, fmt.Errorf("表达式不能为空")
+// funcDef 解析函数定义 f(x, y) = body 中 "=" 及之后的部分
+func (p *Parser) funcDef(call *CallExpr) (Node, error) {
+	params := make([]*Ident, len(call.Args))
+	seen := make(map[string]bool)
+	for i, arg := range call.Args {
+		param, ok := arg.(*Ident)
+		if !ok {
+			tok := Token{Pos: arg.Pos(), Value: p.lexer.input[arg.Pos():arg.End()]}
+			return nil, p.errorAt(tok, []TokenType{IDENT}, "invalid_parameter.name", tok.Value)
+		}
+		if seen[param.Name] {
+			tok := Token{Pos: param.Pos(), Value: param.Name}
+			return nil, p.errorAt(tok, nil, "invalid_parameter.duplicate", param.Name)
+		}
+		seen[param.Name] = true
+		params[i] = param
 	}
 
-	lexer := NewLexer(expression)
-	parser := NewParser(lexer)
+	eqPos := p.currentToken.Pos
+	if err := p.eat(ASSIGN); err != nil {
+		return nil, err
+	}
+	body, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return &FuncDef{
+		Name:   call.Fun,
+		Lparen: call.Lparen,
+		Params: params,
+		Rparen: call.Rparen,
+		EqPos:  eqPos,
+		Body:   body,
+	}, nil
+}
 
-	result, err := parser.expr()
+// Parse 解析完整的语句并返回语法树
+func (p *Parser) Parse() (Node, error) {
+	node, err := p.statement()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	// 检查是否还有未处理的标记
-	if parser.currentToken.Type != EOF {
-		return 0, fmt.Errorf("表达式解析不完整，剩余: %s", parser.currentToken.Value)
+	if p.currentToken.Type == ILLEGAL {
+		return nil, p.unexpected(EOF)
 	}
+	if p.currentToken.Type != EOF {
+		return nil, p.errorAt(p.currentToken, []TokenType{EOF}, "unexpected_token.extra", describeToken(p.currentToken))
+	}
+
+	return node, nil
+}
+
+// Parse 将表达式字符串解析为语法树，数字后的单位按默认注册表识别
+func Parse(expression string) (Node, error) {
+	return parse(expression, DefaultRegistry)
+}
 
-	return result, nil
+func parse(expression string, registry *Registry) (Node, error) {
+	if IsBlank(expression) {
+		return nil, newSyntaxError(expression, 0, "", nil, "empty_expression")
+	}
+	return newParser(NewLexer(expression), registry).Parse()
+}
+
+// IsBlank 判断输入是否只包含空白和注释
+func IsBlank(input string) bool {
+	return NewLexer(input).NextToken().Type == EOF
+}
+
+// Calculate 计算数学表达式
+func Calculate(expression string) (float64, error) {
+	node, err := Parse(expression)
+	if err != nil {
+		return 0, err
+	}
+	return Eval(node)
 }
 
 // FormatResult 格式化结果输出
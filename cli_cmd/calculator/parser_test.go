@@ -162,6 +162,7 @@ func TestErrorCases(t *testing.T) {
 		{"abc", "无效字符"},
 		{"1+2*", "结尾操作符"},
 		{"1*/2", "非法操作符组合"},
+		{"1\x00+2", "输入中的 NUL 字符"},
 	}
 
 	for _, test := range tests {
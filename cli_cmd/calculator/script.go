@@ -0,0 +1,108 @@
+package calculator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"cli_cmd/i18n"
+)
+
+// Statement 脚本中的一条语句
+type Statement struct {
+	Line int    // 行号，从 1 开始
+	Text string // 语句所在行的原文，列号按原文计算
+}
+
+// Result 一条语句的执行结果
+type Result struct {
+	Statement
+	Value   Value
+	Assign  bool          // 语句是赋值
+	Func    *UserFunc     // 语句是函数定义时为定义的函数，此时 Value 为 nil
+	Err     error         // 执行失败时为 *ScriptError
+	Elapsed time.Duration // 解析和求值所用的时间
+}
+
+// ScriptError 脚本中某条语句的执行错误，Line 为 0 表示语句不是来自脚本
+type ScriptError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *ScriptError) Error() string { return e.Localize(i18n.Current()) }
+
+// Localize 按指定的语言返回带行号的错误信息
+func (e *ScriptError) Localize(lang i18n.Lang) string {
+	if e.Line == 0 {
+		return i18n.Text(lang, e.Err)
+	}
+	var syntaxErr *SyntaxError
+	if errors.As(e.Err, &syntaxErr) {
+		return messages.Sprintf(lang, "position.line_column", e.Line, syntaxErr.Column, syntaxErr.msg)
+	}
+	return messages.Sprintf(lang, "position.line", e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error { return e.Err }
+
+// Exec 在当前环境中执行一条语句
+//
+// 函数定义语句定义用户函数，其他语句的结果记录到 ans 和 _ 变量中。
+func (e *Env) Exec(stmt Statement) Result {
+	return e.ExecContext(context.Background(), stmt)
+}
+
+// ExecContext 与 Exec 相同，ctx 取消或超时后停止执行并返回 ctx 的错误
+func (e *Env) ExecContext(ctx context.Context, stmt Statement) Result {
+	res := Result{Statement: stmt}
+	start := time.Now()
+	node, err := e.parse(stmt.Text)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		if def, ok := node.(*FuncDef); ok {
+			res.Func, err = e.Define(def)
+		} else {
+			_, res.Assign = node.(*AssignExpr)
+			res.Value, err = e.evaluate(ctx, node)
+		}
+	}
+	res.Elapsed = time.Since(start)
+	if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		err = errorf("stopped.err", err)
+	}
+	if err != nil {
+		res.Err = &ScriptError{Line: stmt.Line, Text: stmt.Text, Err: err}
+	}
+	return res
+}
+
+// RunScript 在当前环境中逐行执行脚本，跳过空行和只有注释的行
+//
+// 每条语句执行后调用 fn，fn 返回 false 时停止执行。
+// 语句按行读取并立即执行，因此可以处理来自管道的持续输入。
+// 返回值只表示读取失败，语句的执行错误通过 Result.Err 传给 fn。
+func (e *Env) RunScript(r io.Reader, fn func(Result) bool) error {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if IsBlank(text) {
+			continue
+		}
+		if !fn(e.Exec(Statement{Line: line, Text: text})) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errorf("read.script", err)
+	}
+	return nil
+}
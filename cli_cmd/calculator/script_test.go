@@ -0,0 +1,90 @@
+package calculator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runAll 执行整个脚本并收集所有结果
+func runAll(t *testing.T, env *Env, script string) []Result {
+	var results []Result
+	err := env.RunScript(strings.NewReader(script), func(res Result) bool {
+		results = append(results, res)
+		return true
+	})
+	require.NoError(t, err)
+	return results
+}
+
+// TestRunScript 测试脚本中的注释、空行和赋值
+func TestRunScript(t *testing.T) {
+	script := `# 计算手续费
+gas = 21000
+price = 30 gwei   # 单价
+
+gas * price in ether
+ans * 2
+`
+	results := runAll(t, NewEnv(), script)
+	require.Len(t, results, 4)
+
+	lines := []int{2, 3, 5, 6}
+	for i, res := range results {
+		require.NoError(t, res.Err)
+		assert.Equal(t, lines[i], res.Line, "第 %d 条语句的行号", i+1)
+	}
+	assert.True(t, results[0].Assign)
+	assert.True(t, results[1].Assign)
+	assert.False(t, results[2].Assign)
+	assert.Equal(t, "0.00063", FormatValue(results[2].Value))
+	assert.Equal(t, "0.00126", FormatValue(results[3].Value))
+}
+
+// TestRunScriptErrors 测试出错的语句不影响后续语句
+func TestRunScriptErrors(t *testing.T) {
+	results := runAll(t, NewEnv(), "x = 2\r\n  x +\r\ny\r\nx * 3\r\n")
+	require.Len(t, results, 4)
+
+	var scriptErr *ScriptError
+	require.True(t, errors.As(results[1].Err, &scriptErr))
+	assert.Equal(t, 2, scriptErr.Line)
+	assert.Equal(t, "第 2 行第 6 列: 期望 数字、标识符、'(' 或 '['，但得到 表达式结尾", scriptErr.Error())
+	var syntaxErr *SyntaxError
+	assert.True(t, errors.As(results[1].Err, &syntaxErr), "应该可以取得语法错误")
+
+	assert.EqualError(t, results[2].Err, "第 3 行: 未定义的变量: y")
+	require.NoError(t, results[3].Err)
+	assert.Equal(t, "6", FormatValue(results[3].Value))
+}
+
+// TestRunScriptStop 测试回调返回 false 时停止执行
+func TestRunScriptStop(t *testing.T) {
+	env := NewEnv()
+	count := 0
+	err := env.RunScript(strings.NewReader("a = 1\nb = 2\nc = 3\n"), func(res Result) bool {
+		count++
+		return res.Line < 2
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	_, ok := env.GetValue("c")
+	assert.False(t, ok, "停止后不应该继续执行")
+}
+
+// TestComments 测试表达式中的 # 注释
+func TestComments(t *testing.T) {
+	result, err := Calculate("1 + 2 # 注释")
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, result)
+
+	assert.True(t, IsBlank("  # 只有注释"))
+	assert.True(t, IsBlank(""))
+	assert.False(t, IsBlank("1 # 注释"))
+
+	_, err = Parse("# 只有注释")
+	assert.Error(t, err, "只有注释的表达式应该报错")
+}
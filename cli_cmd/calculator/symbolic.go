@@ -0,0 +1,505 @@
+package calculator
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxSimplifyPasses Simplify 最多重复化简的次数
+const maxSimplifyPasses = 10
+
+// Simplify 化简语法树，返回新的语法树，原语法树不会被修改
+//
+// 化简包括常量折叠（结果无法用简短的小数表示时保留原式，例如 1 / 3）、
+// 代数恒等式（x + 0、x * 1、x ^ 1 等）以及合并同类项和同底数幂。
+// 化简不考虑定义域，例如 x / x 化简为 1。
+func Simplify(node Node) Node {
+	s := FormatNode(node)
+	for i := 0; i < maxSimplifyPasses; i++ {
+		node = simplify(node)
+		next := FormatNode(node)
+		if next == s {
+			break
+		}
+		s = next
+	}
+	return node
+}
+
+// simplify 自底向上化简一遍
+func simplify(node Node) Node {
+	switch n := node.(type) {
+	case *ParenExpr:
+		return simplify(n.X)
+	case *UnaryExpr:
+		return simplifyUnary(n.Op, simplify(n.X))
+	case *BinaryExpr:
+		return simplifyBinary(n.Op, simplify(n.X), simplify(n.Y))
+	case *TernaryExpr:
+		cond, then, els := simplify(n.Cond), simplify(n.Then), simplify(n.Else)
+		if c, ok := constValue(cond); ok {
+			if truthyFloat(c) {
+				return then
+			}
+			return els
+		}
+		if equalNode(then, els) {
+			return then
+		}
+		return &TernaryExpr{Cond: cond, Then: then, Else: els}
+	case *CallExpr:
+		return simplifyCall(n)
+	case *ListExpr:
+		elems := make([]Node, len(n.Elems))
+		for i, elem := range n.Elems {
+			elems[i] = simplify(elem)
+		}
+		return &ListExpr{Elems: elems}
+	case *Lambda:
+		return &Lambda{Param: n.Param, Body: simplify(n.Body)}
+	case *ConvertExpr:
+		return &ConvertExpr{X: simplify(n.X), Unit: n.Unit}
+	case *AssignExpr:
+		return &AssignExpr{Name: n.Name, Value: simplify(n.Value)}
+	case *FuncDef:
+		return &FuncDef{Name: n.Name, Params: n.Params, Body: simplify(n.Body)}
+	}
+	return node
+}
+
+// simplifyUnary 化简一元表达式，x 已经化简
+func simplifyUnary(op TokenType, x Node) Node {
+	c, isConst := constValue(x)
+	switch op {
+	case PLUS:
+		return x
+	case NOT:
+		if isConst {
+			return number(boolToFloat(!truthyFloat(c)))
+		}
+	case MINUS:
+		if isConst {
+			return number(-c)
+		}
+		switch n := x.(type) {
+		case *UnaryExpr:
+			if n.Op == MINUS {
+				return n.X
+			}
+		case *BinaryExpr:
+			if n.Op == MINUS {
+				return simplifyBinary(MINUS, n.Y, n.X)
+			}
+			if k, z, ok := coefficient(n); ok {
+				return simplifyBinary(MULTIPLY, number(-k), z)
+			}
+		}
+	}
+	return &UnaryExpr{Op: op, X: x}
+}
+
+// simplifyBinary 化简二元表达式，x 和 y 已经化简
+func simplifyBinary(op TokenType, x, y Node) Node {
+	cx, xConst := constValue(x)
+	cy, yConst := constValue(y)
+	if xConst && yConst {
+		if r, ok := foldBinary(op, cx, cy); ok {
+			return number(r)
+		}
+		return &BinaryExpr{X: x, Op: op, Y: y}
+	}
+
+	switch op {
+	case PLUS:
+		switch {
+		case xConst && cx == 0:
+			return y
+		case yConst && cy == 0:
+			return x
+		case yConst && cy < 0:
+			return simplifyBinary(MINUS, x, number(-cy))
+		case isNeg(y):
+			return simplifyBinary(MINUS, x, y.(*UnaryExpr).X)
+		case isNeg(x):
+			return simplifyBinary(MINUS, y, x.(*UnaryExpr).X)
+		}
+		if r, ok := combineTerms(x, y, 1); ok {
+			return r
+		}
+		// 按左结合重排：x + (a + b) => x + a + b，x + (a - b) => x + a - b
+		if b, ok := y.(*BinaryExpr); ok && (b.Op == PLUS || b.Op == MINUS) {
+			return simplifyBinary(b.Op, simplifyBinary(PLUS, x, b.X), b.Y)
+		}
+	case MINUS:
+		switch {
+		case yConst && cy == 0:
+			return x
+		case xConst && cx == 0:
+			return simplifyUnary(MINUS, y)
+		case equalNode(x, y):
+			return number(0)
+		case yConst && cy < 0:
+			return simplifyBinary(PLUS, x, number(-cy))
+		case isNeg(y):
+			return simplifyBinary(PLUS, x, y.(*UnaryExpr).X)
+		}
+		if r, ok := combineTerms(x, y, -1); ok {
+			return r
+		}
+		// x - (a + b) => x - a - b，x - (a - b) => x - a + b
+		if b, ok := y.(*BinaryExpr); ok && (b.Op == PLUS || b.Op == MINUS) {
+			next := PLUS
+			if b.Op == PLUS {
+				next = MINUS
+			}
+			return simplifyBinary(next, simplifyBinary(MINUS, x, b.X), b.Y)
+		}
+	case MULTIPLY:
+		switch {
+		case xConst && cx == 0, yConst && cy == 0:
+			return number(0)
+		case xConst && cx == 1:
+			return y
+		case yConst && cy == 1:
+			return x
+		case xConst && cx == -1:
+			return simplifyUnary(MINUS, y)
+		case yConst && cy == -1:
+			return simplifyUnary(MINUS, x)
+		case yConst:
+			// 常数系数放在左边
+			return simplifyBinary(MULTIPLY, y, x)
+		case isNeg(x) && isNeg(y):
+			return simplifyBinary(MULTIPLY, x.(*UnaryExpr).X, y.(*UnaryExpr).X)
+		case isNeg(x):
+			return simplifyUnary(MINUS, simplifyBinary(MULTIPLY, x.(*UnaryExpr).X, y))
+		case isNeg(y):
+			return simplifyUnary(MINUS, simplifyBinary(MULTIPLY, x, y.(*UnaryExpr).X))
+		}
+		if b, ok := y.(*BinaryExpr); ok {
+			switch b.Op {
+			case MULTIPLY:
+				// 按左结合重排：x * (a * b) => x * a * b
+				return simplifyBinary(MULTIPLY, simplifyBinary(MULTIPLY, x, b.X), b.Y)
+			case DIVIDE:
+				// x * (a / b) => x * a / b
+				return simplifyBinary(DIVIDE, simplifyBinary(MULTIPLY, x, b.X), b.Y)
+			}
+		}
+		if b, ok := x.(*BinaryExpr); ok && b.Op == DIVIDE {
+			// a / b * y => a * y / b
+			return simplifyBinary(DIVIDE, simplifyBinary(MULTIPLY, b.X, y), b.Y)
+		}
+		// 同底数幂相乘
+		bx, ex := powerParts(x)
+		by, ey := powerParts(y)
+		if equalNode(bx, by) {
+			if _, ok := constValue(bx); !ok {
+				kx, ok1 := constValue(ex)
+				ky, ok2 := constValue(ey)
+				if ok1 && ok2 {
+					return simplifyBinary(POWER, bx, number(kx+ky))
+				}
+			}
+		}
+	case DIVIDE:
+		switch {
+		case xConst && cx == 0:
+			return number(0)
+		case yConst && cy == 1:
+			return x
+		case yConst && cy == -1:
+			return simplifyUnary(MINUS, x)
+		case equalNode(x, y):
+			return number(1)
+		case isNeg(x):
+			return simplifyUnary(MINUS, simplifyBinary(DIVIDE, x.(*UnaryExpr).X, y))
+		case isNeg(y):
+			return simplifyUnary(MINUS, simplifyBinary(DIVIDE, x, y.(*UnaryExpr).X))
+		}
+		// c1 * z / c2 => (c1 / c2) * z
+		if k, z, ok := coefficient(x); ok && yConst {
+			if r, ok := foldBinary(DIVIDE, k, cy); ok {
+				return simplifyBinary(MULTIPLY, number(r), z)
+			}
+		}
+		if b, ok := y.(*BinaryExpr); ok && b.Op == DIVIDE {
+			// x / (a / b) => x * b / a
+			return simplifyBinary(DIVIDE, simplifyBinary(MULTIPLY, x, b.Y), b.X)
+		}
+		if b, ok := x.(*BinaryExpr); ok && b.Op == DIVIDE {
+			// a / b / y => a / (b * y)
+			return simplifyBinary(DIVIDE, b.X, simplifyBinary(MULTIPLY, b.Y, y))
+		}
+	case POWER:
+		switch {
+		case yConst && cy == 0:
+			return number(1)
+		case yConst && cy == 1:
+			return x
+		case xConst && cx == 1:
+			return number(1)
+		case xConst && cx == 0 && yConst && cy > 0:
+			return number(0)
+		}
+		// (a ^ b) ^ c => a ^ (b * c)，b 和 c 都是常数
+		if p, ok := x.(*BinaryExpr); ok && p.Op == POWER && yConst {
+			if k, ok := constValue(p.Y); ok {
+				if r, ok := foldBinary(MULTIPLY, k, cy); ok {
+					return simplifyBinary(POWER, p.X, number(r))
+				}
+			}
+		}
+	}
+	return &BinaryExpr{X: x, Op: op, Y: y}
+}
+
+// simplifyCall 化简函数调用，参数都是常数时按 DefaultRegistry 中的函数折叠
+func simplifyCall(n *CallExpr) Node {
+	args := make([]Node, len(n.Args))
+	values := make([]float64, len(n.Args))
+	allConst := true
+	for i, arg := range n.Args {
+		args[i] = simplify(arg)
+		c, ok := constValue(args[i])
+		values[i], allConst = c, allConst && ok
+	}
+	if fn, ok := DefaultRegistry.Func(n.Fun.Name); ok && allConst && fn.checkArgs(len(values)) == nil {
+		if r, err := fn.Call(values); err == nil && tidy(r) {
+			return number(r)
+		}
+	}
+	return &CallExpr{Fun: n.Fun, Args: args}
+}
+
+// combineTerms 合并同类项 x + sign * y，例如 2 * x + x => 3 * x
+func combineTerms(x, y Node, sign float64) (Node, bool) {
+	kx, zx := term(x)
+	ky, zy := term(y)
+	if !equalNode(zx, zy) {
+		return nil, false
+	}
+	if _, ok := constValue(zx); ok {
+		return nil, false
+	}
+	k, ok := foldBinary(PLUS, kx, sign*ky)
+	if !ok {
+		return nil, false
+	}
+	return simplifyBinary(MULTIPLY, number(k), zx), true
+}
+
+// term 把项拆成常数系数和其余部分，例如 -2 * x => -2, x
+func term(n Node) (float64, Node) {
+	if k, z, ok := coefficient(n); ok {
+		return k, z
+	}
+	if isNeg(n) {
+		k, z := term(n.(*UnaryExpr).X)
+		return -k, z
+	}
+	return 1, n
+}
+
+// coefficient 拆分 c * z 形式的乘积，c 可以是左结合乘积中最左边的因子
+func coefficient(n Node) (float64, Node, bool) {
+	b, ok := n.(*BinaryExpr)
+	if !ok || b.Op != MULTIPLY {
+		return 0, nil, false
+	}
+	if k, ok := constValue(b.X); ok {
+		return k, b.Y, true
+	}
+	if k, z, ok := coefficient(b.X); ok {
+		return k, bin(MULTIPLY, z, b.Y), true
+	}
+	return 0, nil, false
+}
+
+// powerParts 拆分 a ^ b 形式的幂，其他表达式视为 n ^ 1
+func powerParts(n Node) (Node, Node) {
+	if b, ok := n.(*BinaryExpr); ok && b.Op == POWER {
+		return b.X, b.Y
+	}
+	return n, number(1)
+}
+
+// foldBinary 计算常数之间的运算，结果无法简短表示时返回 false
+func foldBinary(op TokenType, x, y float64) (float64, bool) {
+	var r float64
+	switch op {
+	case AND:
+		r = boolToFloat(truthyFloat(x) && truthyFloat(y))
+	case OR:
+		r = boolToFloat(truthyFloat(x) || truthyFloat(y))
+	default:
+		var err error
+		if r, err = binary(op, x, y); err != nil {
+			return 0, false
+		}
+	}
+	return r, tidy(r)
+}
+
+// tidy 判断数值能否用不超过 12 位有效数字的小数精确表示
+func tidy(f float64) bool {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return false
+	}
+	digits := strings.TrimLeft(strconv.FormatFloat(math.Abs(f), 'e', -1, 64), "0")
+	if i := strings.IndexByte(digits, 'e'); i >= 0 {
+		digits = digits[:i]
+	}
+	return len(strings.Replace(digits, ".", "", 1)) <= 12
+}
+
+// number 创建数值节点，负数表示为一元负号
+func number(f float64) Node {
+	if f < 0 {
+		return &UnaryExpr{Op: MINUS, X: number(-f)}
+	}
+	if f == 0 {
+		f = 0 // 避免输出 -0
+	}
+	return &NumberLit{Literal: strconv.FormatFloat(f, 'f', -1, 64), Value: f}
+}
+
+// constValue 返回数值节点的值，包括负数
+func constValue(n Node) (float64, bool) {
+	switch x := n.(type) {
+	case *NumberLit:
+		if isPrefixedLiteral(x.Literal) {
+			v, err := parseNumber(x.Literal, ModeFloat, 0)
+			if err != nil {
+				return 0, false
+			}
+			return v.Float64(), true
+		}
+		return x.Value, !math.IsInf(x.Value, 0)
+	case *ParenExpr:
+		return constValue(x.X)
+	case *UnaryExpr:
+		if c, ok := constValue(x.X); ok && x.Op == MINUS {
+			return -c, true
+		}
+	}
+	return 0, false
+}
+
+// isNeg 判断是否为 -x 形式的表达式，负数常量除外
+func isNeg(n Node) bool {
+	u, ok := n.(*UnaryExpr)
+	if !ok || u.Op != MINUS {
+		return false
+	}
+	_, isConst := constValue(u.X)
+	return !isConst
+}
+
+// equalNode 判断两棵语法树是否表示相同的表达式
+func equalNode(x, y Node) bool {
+	return FormatNode(x) == FormatNode(y)
+}
+
+// 用于 FormatNode 的优先级，原子表达式最高
+const precAtom = 16
+
+// FormatNode 输出表达式，只在改变运算顺序时添加括号
+//
+// 与 Node.String 不同，源码中多余的括号会被去掉，例如 ((x)) + (1 * 2) 输出为 x + 1 * 2。
+func FormatNode(node Node) string {
+	var sb strings.Builder
+	formatNode(&sb, node)
+	return sb.String()
+}
+
+func formatNode(sb *strings.Builder, node Node) {
+	switch n := node.(type) {
+	case *ParenExpr:
+		formatNode(sb, n.X)
+	case *UnaryExpr:
+		sb.WriteString(operatorSymbol(n.Op))
+		// 连续的一元运算符加括号，避免 --x 这样难以阅读的写法
+		formatOperand(sb, n.X, nodePrec(n.X) < precUnary || nodePrec(n.X) == precUnary)
+	case *BinaryExpr:
+		op := binaryOps[n.Op]
+		px, py := nodePrec(n.X), nodePrec(n.Y)
+		formatOperand(sb, n.X, px < op.prec || px == op.prec && op.rightAssoc)
+		sb.WriteString(" " + operatorSymbol(n.Op) + " ")
+		// 前缀运算符总是向右结合，右操作数为一元表达式时不需要括号
+		formatOperand(sb, n.Y, py != precUnary && (py < op.prec || py == op.prec && !op.rightAssoc))
+	case *TernaryExpr:
+		formatOperand(sb, n.Cond, nodePrec(n.Cond) <= precTernary)
+		sb.WriteString(" ? ")
+		formatNode(sb, n.Then)
+		sb.WriteString(" : ")
+		formatOperand(sb, n.Else, nodePrec(n.Else) < precTernary)
+	case *ConvertExpr:
+		formatOperand(sb, n.X, nodePrec(n.X) < precConvert)
+		sb.WriteString(" in " + n.Unit.Name)
+	case *CallExpr:
+		sb.WriteString(n.Fun.Name + "(")
+		for i, arg := range n.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			formatNode(sb, arg)
+		}
+		sb.WriteByte(')')
+	case *ListExpr:
+		sb.WriteByte('[')
+		for i, elem := range n.Elems {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			formatNode(sb, elem)
+		}
+		sb.WriteByte(']')
+	case *Lambda:
+		sb.WriteString(n.Param.Name + " => ")
+		formatNode(sb, n.Body)
+	case *AssignExpr:
+		sb.WriteString(n.Name.Name + " = ")
+		formatNode(sb, n.Value)
+	case *FuncDef:
+		params := make([]string, len(n.Params))
+		for i, param := range n.Params {
+			params[i] = param.Name
+		}
+		sb.WriteString(n.Name.Name + "(" + strings.Join(params, ", ") + ") = ")
+		formatNode(sb, n.Body)
+	default:
+		sb.WriteString(node.String())
+	}
+}
+
+// formatOperand 输出操作数，paren 为 true 时加括号
+func formatOperand(sb *strings.Builder, node Node, paren bool) {
+	if paren {
+		sb.WriteByte('(')
+	}
+	formatNode(sb, node)
+	if paren {
+		sb.WriteByte(')')
+	}
+}
+
+// nodePrec 返回表达式顶层运算符的优先级
+func nodePrec(node Node) int {
+	switch n := node.(type) {
+	case *ParenExpr:
+		return nodePrec(n.X)
+	case *UnaryExpr:
+		return precUnary
+	case *BinaryExpr:
+		return binaryOps[n.Op].prec
+	case *TernaryExpr:
+		return precTernary
+	case *ConvertExpr:
+		return precConvert
+	case *AssignExpr, *FuncDef, *Lambda:
+		return precLowest
+	}
+	return precAtom
+}
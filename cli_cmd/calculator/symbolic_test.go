@@ -0,0 +1,114 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimplify 测试化简
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"x*1+0", "x", "单位元"},
+		{"2*3+x*0", "6", "常量折叠和零元"},
+		{"(x)+(1*2)", "x + 2", "去掉多余的括号"},
+		{"1/3+1", "1 / 3 + 1", "不折叠无限小数"},
+		{"sqrt(16)+sin(1)", "4 + sin(1)", "折叠函数调用"},
+		{"x+x", "2 * x", "相同的项"},
+		{"2*x+3*x-x", "4 * x", "合并同类项"},
+		{"x*x*x", "x ^ 3", "同底数幂相乘"},
+		{"(x^2)^3", "x ^ 6", "幂的乘方"},
+		{"x*2*y", "2 * x * y", "常数系数提到最前"},
+		{"6*x/4", "1.5 * x", "常数系数相除"},
+		{"-(-x)", "x", "双重负号"},
+		{"-(a-b)", "b - a", "负号进入减法"},
+		{"x + -y", "x - y", "加负数"},
+		{"x - -1", "x + 1", "减负常数"},
+		{"a-(b-c)", "a - b + c", "去括号"},
+		{"x/x + (y - y)", "1", "相同的操作数"},
+		{"x^0 + 0^2 + 1^y", "2", "乘方的特殊情况"},
+		{"1 > 0 ? x : y", "x", "常量条件"},
+		{"c ? x*1 : x", "x", "相同的分支"},
+		{"f(x) = x*1 + 0", "f(x) = x", "函数定义的函数体"},
+		{"y = 2*3", "y = 6", "赋值的值"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, FormatNode(Simplify(node)))
+		})
+	}
+}
+
+// TestSimplifyValue 测试化简前后的值相同
+func TestSimplifyValue(t *testing.T) {
+	expressions := []string{
+		"x*2*y + y*x - 3", "-(x - y) * -(y - x)", "x / (y / 2) / 4",
+		"(x + 1)^2 * (x + 1)", "a - (b - (c - x))", "-x^2 + (-x)^2",
+		"2^-x * 2^x", "sqrt(x^2) + max(x, y) * 0.5", "x > y ? x - y : -(y - x)",
+	}
+	env := NewEnv()
+	for _, vars := range [][3]float64{{1.5, 2, 3}, {-2, 0.25, 7}, {3, -1, 0.5}} {
+		env.Set("x", vars[0])
+		env.Set("y", vars[1])
+		env.Set("a", vars[2])
+		env.Set("b", vars[0]+vars[1])
+		env.Set("c", vars[1]*vars[2])
+		for _, expr := range expressions {
+			node, err := Parse(expr)
+			require.NoError(t, err)
+			simplified := FormatNode(Simplify(node))
+			reparsed, err := Parse(simplified)
+			require.NoError(t, err, "化简结果 %s 应该能重新解析", simplified)
+
+			expected, err := env.Eval(node)
+			require.NoError(t, err)
+			actual, err := env.Eval(reparsed)
+			require.NoError(t, err)
+			assert.InDelta(t, expected, actual, 1e-9, "%s => %s", expr, simplified)
+		}
+	}
+}
+
+// TestFormatNode 测试最少括号的输出
+func TestFormatNode(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"((1 + 2)) * 3", "(1 + 2) * 3", "需要的括号"},
+		{"1 + (2 * 3)", "1 + 2 * 3", "多余的括号"},
+		{"(1 - 2) - 3", "1 - 2 - 3", "左结合"},
+		{"1 - (2 - 3)", "1 - (2 - 3)", "右操作数同级"},
+		{"(2 ^ 3) ^ 2", "(2 ^ 3) ^ 2", "右结合的乘方"},
+		{"2 ^ (3 ^ 2)", "2 ^ 3 ^ 2", "乘方的右操作数"},
+		{"(-x) ^ 2", "(-x) ^ 2", "负数的乘方"},
+		{"-(x ^ 2)", "-x ^ 2", "乘方的相反数"},
+		{"2 * (-x)", "2 * -x", "右操作数为一元表达式"},
+		{"-(-x)", "-(-x)", "连续的一元运算符"},
+		{"(a ? b : c) ? d : (e ? f : g)", "(a ? b : c) ? d : e ? f : g", "条件表达式"},
+		{"(1 + 2) in gwei", "1 + 2 in gwei", "单位换算"},
+		{"max((1), (2 + 3))", "max(1, 2 + 3)", "函数参数"},
+		{"[(1), 2 * 3]", "[1, 2 * 3]", "列表"},
+		{"(1..n) + 1", "(1 .. n) + 1", "范围"},
+		{"1 + 2..(n << 1)", "1 + 2 .. n << 1", "范围的操作数"},
+		{"map(xs, x => (x * 2))", "map(xs, x => x * 2)", "匿名函数"},
+		{"-(x => x)", "-(x => x)", "匿名函数作为操作数"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			node, err := Parse(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, FormatNode(node))
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package calculator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unitFile 单位文件的内容，格式为 YAML 或 JSON：
+//
+//	units:
+//	  rack: 42 U
+//	  U: 44.45 mm
+//	  req: base
+//	currency:
+//	  base: USD
+//	  rates:
+//	    EUR: 1.08   # 1 EUR = 1.08 USD
+//	    CNY: 0.14
+//
+// units 的定义格式见 Registry.RegisterUnit，定义中可以引用同一文件中的其他单位；
+// currency.base 是基准货币，rates 是一个单位的各货币折合多少基准货币。
+type unitFile struct {
+	Units    map[string]unitText `yaml:"units" json:"units"`
+	Currency struct {
+		Base  string              `yaml:"base" json:"base"`
+		Rates map[string]unitText `yaml:"rates" json:"rates"`
+	} `yaml:"currency" json:"currency"`
+}
+
+// unitText 单位定义或汇率的文本，文件中可以写成字符串或数字；数字保留原始文本，不经过 float64
+type unitText string
+
+func (t *unitText) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return errorf("unit_file.yaml_scalar", node.Line)
+	}
+	*t = unitText(node.Value)
+	return nil
+}
+
+func (t *unitText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = unitText(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return errorf("unit_file.json_value", data)
+	}
+	*t = unitText(n)
+	return nil
+}
+
+// LoadUnits 从 YAML（.yaml、.yml）或 JSON（.json）文件加载单位和汇率
+//
+// 文件中的单位全部定义成功才会加入注册表；与已有单位同名时覆盖已有单位。
+func (r *Registry) LoadUnits(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := r.loadUnits(data, filepath.Ext(path)); err != nil {
+		return errorf("unit_file.path", path, err)
+	}
+	return nil
+}
+
+// loadUnits 按扩展名解析单位文件并注册其中的单位
+func (r *Registry) loadUnits(data []byte, ext string) error {
+	var f unitFile
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			return err
+		}
+	default:
+		return errorf("unit_file.format", ext)
+	}
+
+	defs, err := f.definitions()
+	if err != nil {
+		return err
+	}
+	return r.registerUnits(defs)
+}
+
+// definitions 返回文件中所有单位的定义，汇率转换为以基准货币表示的定义
+func (f *unitFile) definitions() (map[string]string, error) {
+	defs := make(map[string]string, len(f.Units)+len(f.Currency.Rates)+1)
+	for name, def := range f.Units {
+		defs[name] = string(def)
+	}
+
+	base := f.Currency.Base
+	if base == "" {
+		if len(f.Currency.Rates) > 0 {
+			return nil, errorf("unit_file.currency_base")
+		}
+		return defs, nil
+	}
+	if _, ok := defs[base]; ok {
+		return nil, errorf("unit_file.duplicate", base)
+	}
+	defs[base] = "base"
+	for code, rate := range f.Currency.Rates {
+		if _, ok := defs[code]; ok {
+			return nil, errorf("unit_file.duplicate", code)
+		}
+		defs[code] = string(rate) + " " + base
+	}
+	return defs, nil
+}
+
+// registerUnits 注册一组可以互相引用的单位定义
+//
+// 定义按名称顺序反复尝试，直到全部成功或者一轮中没有任何进展；
+// 全部成功后才修改注册表。
+func (r *Registry) registerUnits(defs map[string]string) error {
+	pending := make([]string, 0, len(defs))
+	for name := range defs {
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+
+	scratch := r.Clone()
+	for len(pending) > 0 {
+		var rest []string
+		var firstErr error
+		for _, name := range pending {
+			if err := scratch.RegisterUnit(name, defs[name]); err != nil {
+				rest = append(rest, name)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+		if len(rest) == len(pending) {
+			return firstErr
+		}
+		pending = rest
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range defs {
+		r.units[name] = scratch.units[name]
+	}
+	return nil
+}
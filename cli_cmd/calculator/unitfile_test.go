@@ -0,0 +1,106 @@
+package calculator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeUnitFile 在临时目录中写入单位文件并返回路径
+func writeUnitFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// TestLoadUnits 测试从 YAML 和 JSON 文件加载单位和汇率
+func TestLoadUnits(t *testing.T) {
+	files := map[string]string{
+		"units.yaml": `
+# 机柜容量
+units:
+  rack: 42 U
+  U: 44.45 mm
+  req: base
+  rps: req/s
+currency:
+  base: USD
+  rates:
+    EUR: 1.08
+    CNY: "0.14"
+`,
+		"units.json": `{
+  "units": {"rack": "42 U", "U": "44.45 mm", "req": "base", "rps": "req/s"},
+  "currency": {"base": "USD", "rates": {"EUR": 1.08, "CNY": "0.14"}}
+}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			r := DefaultRegistry.Clone()
+			require.NoError(t, r.LoadUnits(writeUnitFile(t, name, content)))
+			env := NewEnvWithRegistry(r)
+			require.NoError(t, env.SetMode(ModeRat))
+
+			tests := []struct {
+				expression string
+				expected   string
+			}{
+				{"2 rack in m", "3.7338 m"},
+				{"100 EUR in USD", "108 USD"},
+				{"100 EUR + 100 CNY in USD", "122 USD"},
+				{"1 USD in EUR", "25/27 EUR"},
+				{"300 rps * 1 min in req", "18000 req"},
+			}
+			for _, test := range tests {
+				v, err := env.Evaluate(test.expression)
+				require.NoError(t, err, test.expression)
+				assert.Equal(t, test.expected, FormatValue(v), test.expression)
+			}
+
+			_, err := env.Evaluate("1 EUR + 1 m")
+			assert.ErrorIs(t, err, ErrDimension, "货币与长度量纲不同")
+		})
+	}
+}
+
+// TestLoadUnitsErrors 测试单位文件的错误
+func TestLoadUnitsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		desc    string
+	}{
+		{"units.toml", "", "不支持的格式"},
+		{"units.yaml", "units: [km]", "units 不是映射"},
+		{"units.yaml", "unit:\n  foo: 1 m", "未知的字段"},
+		{"units.yaml", "units:\n  foo: 1 bar", "未知的单位"},
+		{"units.yaml", "units:\n  a: 2 b\n  b: 3 a", "循环定义"},
+		{"units.yaml", "units:\n  foo: {x: 1}", "定义不是标量"},
+		{"units.yaml", "currency:\n  rates:\n    EUR: 1.08", "缺少基准货币"},
+		{"units.yaml", "units:\n  USD: base\ncurrency:\n  base: USD", "重复定义的货币"},
+		{"units.json", `{"units": {"foo": true}}`, "定义不是字符串或数字"},
+		{"units.json", `{"units": `, "JSON 格式错误"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			r := DefaultRegistry.Clone()
+			err := r.LoadUnits(writeUnitFile(t, test.name, test.content))
+			assert.Error(t, err)
+		})
+	}
+
+	r := DefaultRegistry.Clone()
+	err := r.LoadUnits(writeUnitFile(t, "units.yaml", "units:\n  good: 2 m\n  bad: 1 nope"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "units.yaml")
+	assert.False(t, r.HasUnit("good"), "出错时不修改注册表")
+
+	assert.Error(t, r.LoadUnits(filepath.Join(t.TempDir(), "missing.yaml")), "文件不存在")
+	assert.NoError(t, r.LoadUnits(writeUnitFile(t, "empty.yaml", "")), "空文件")
+}
@@ -0,0 +1,529 @@
+package calculator
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrDimension 量纲不匹配，例如 1 m + 1 s 或者把 MB 换算为 km
+var ErrDimension = errorf("dimension")
+
+// maxUnitExponent 单位指数的最大绝对值，例如 m^3
+const maxUnitExponent = 64
+
+// dimension 量纲：基本单位名称到指数的映射，无量纲时为空
+type dimension map[string]int
+
+func (d dimension) equal(o dimension) bool {
+	if len(d) != len(o) {
+		return false
+	}
+	for name, exp := range d {
+		if o[name] != exp {
+			return false
+		}
+	}
+	return true
+}
+
+// unitDef 注册表中的单位：一个该单位等于 factor 个基本单位的乘积
+type unitDef struct {
+	factor *big.Rat
+	dim    dimension
+}
+
+// unitTerm 复合单位中的一项，例如 s^2 中的 s 和 2
+type unitTerm struct {
+	name string
+	exp  int
+}
+
+// Unit 单位，可以是 km 这样的单个单位，也可以是 km/h、kg*m/s^2 这样的复合单位
+type Unit struct {
+	terms  []unitTerm // 按书写顺序排列，指数不为零
+	factor *big.Rat   // 一个该单位等于多少基本单位
+	dim    dimension
+}
+
+// dimensionless 无量纲、没有单位的数值对应的单位
+var dimensionless = &Unit{factor: big.NewRat(1, 1), dim: dimension{}}
+
+// String 输出单位，例如 km/h、kg*m/s^2；只有负指数时输出 s^-1
+func (u *Unit) String() string {
+	var num, den []string
+	for _, t := range u.terms {
+		switch {
+		case t.exp == 1:
+			num = append(num, t.name)
+		case t.exp > 1:
+			num = append(num, t.name+"^"+strconv.Itoa(t.exp))
+		case t.exp == -1:
+			den = append(den, t.name)
+		default:
+			den = append(den, t.name+"^"+strconv.Itoa(-t.exp))
+		}
+	}
+	if len(num) == 0 {
+		terms := make([]string, len(u.terms))
+		for i, t := range u.terms {
+			terms[i] = t.name + "^" + strconv.Itoa(t.exp)
+		}
+		return strings.Join(terms, "*")
+	}
+	if len(den) == 0 {
+		return strings.Join(num, "*")
+	}
+	return strings.Join(num, "*") + "/" + strings.Join(den, "/")
+}
+
+// mul 返回 u 乘以（sign 为 1）或除以（sign 为 -1）o 的单位，同名的项合并指数
+func (u *Unit) mul(o *Unit, sign int) *Unit {
+	result := &Unit{
+		terms:  append([]unitTerm(nil), u.terms...),
+		factor: new(big.Rat).Set(u.factor),
+		dim:    dimension{},
+	}
+	for _, t := range o.terms {
+		merged := false
+		for i := range result.terms {
+			if result.terms[i].name == t.name {
+				result.terms[i].exp += sign * t.exp
+				merged = true
+			}
+		}
+		if !merged {
+			result.terms = append(result.terms, unitTerm{t.name, sign * t.exp})
+		}
+	}
+	terms := result.terms[:0]
+	for _, t := range result.terms {
+		if t.exp != 0 {
+			terms = append(terms, t)
+		}
+	}
+	result.terms = terms
+
+	if sign > 0 {
+		result.factor.Mul(result.factor, o.factor)
+	} else {
+		result.factor.Quo(result.factor, o.factor)
+	}
+	for name, exp := range u.dim {
+		result.dim[name] = exp
+	}
+	for name, exp := range o.dim {
+		if result.dim[name] += sign * exp; result.dim[name] == 0 {
+			delete(result.dim, name)
+		}
+	}
+	return result
+}
+
+// pow 返回单位的 n 次幂
+func (u *Unit) pow(n int) *Unit {
+	result := dimensionless
+	if n == 0 {
+		return result
+	}
+	sign := 1
+	if n < 0 {
+		sign, n = -1, -n
+	}
+	for i := 0; i < n; i++ {
+		result = result.mul(u, sign)
+	}
+	return result
+}
+
+// Quantity 带单位的数量，例如 5 km、2.5 km/h
+//
+// Amount 是以 Unit 计的数量，类型为 Float、BigFloat 或 Rat。
+// 加减和比较要求两边量纲相同，右操作数先换算为左操作数的单位；
+// 乘除合并单位，结果无量纲时（例如 km/m）化为普通数值。
+type Quantity struct {
+	Amount Value
+	Unit   *Unit
+}
+
+// Float64 返回以 Unit 计的数量
+func (q Quantity) Float64() float64 { return q.Amount.Float64() }
+
+func (q Quantity) String() string { return FormatValue(q) }
+
+func (q Quantity) rank() int { return q.Amount.rank() }
+
+// splitQuantity 返回数值的数量和单位，普通数值的单位是无量纲的 1
+func splitQuantity(v Value) (Value, *Unit) {
+	if q, ok := v.(Quantity); ok {
+		return q.Amount, q.Unit
+	}
+	return v, dimensionless
+}
+
+// withUnit 返回以 u 计的数量；u 没有任何项时返回普通数值
+func withUnit(amount Value, u *Unit) Value {
+	if len(u.terms) == 0 {
+		return amount
+	}
+	return Quantity{Amount: amount, Unit: u}
+}
+
+// scale 把数值乘以比例 f，比例按数值的类型表示，以免 float 结果被提升为 Rat
+func scale(v Value, f *big.Rat, prec uint) (Value, error) {
+	if f.Cmp(big.NewRat(1, 1)) == 0 {
+		return v, nil
+	}
+	var factor Value
+	switch v.(type) {
+	case Float:
+		x, _ := f.Float64()
+		factor = Float(x)
+	case BigFloat:
+		factor = BigFloat{new(big.Float).SetPrec(prec).SetRat(f)}
+	default:
+		factor = Rat{f}
+	}
+	return arith(MULTIPLY, v, factor, prec)
+}
+
+// convertAmount 把以 from 计的数量换算为以 to 计的数量，两者的量纲必须相同
+func convertAmount(amount Value, from, to *Unit, prec uint) (Value, error) {
+	return scale(amount, new(big.Rat).Quo(from.factor, to.factor), prec)
+}
+
+// convertUnit 把数值换算为目标单位，例如 5 km/h in m/s
+func convertUnit(v Value, to *Unit, prec uint) (Value, error) {
+	amount, from := splitQuantity(v)
+	if !from.dim.equal(to.dim) {
+		return nil, errorf("dimension.convert", FormatValue(v), to)
+	}
+	converted, err := convertAmount(amount, from, to, prec)
+	if err != nil {
+		return nil, err
+	}
+	return Quantity{Amount: converted, Unit: to}, nil
+}
+
+// quantityArith 计算至少一个操作数带单位的二元运算
+func quantityArith(op TokenType, x, y Value, prec uint) (Value, error) {
+	a, ux := splitQuantity(x)
+	b, uy := splitQuantity(y)
+
+	switch op {
+	case PLUS, MINUS, MODULO, EQ, NEQ, LT, LE, GT, GE:
+		if !ux.dim.equal(uy.dim) {
+			return nil, errorf("dimension.operator", FormatValue(x), operatorSymbol(op), FormatValue(y))
+		}
+		b, err := convertAmount(b, uy, ux, prec)
+		if err != nil {
+			return nil, err
+		}
+		result, err := arith(op, a, b, prec)
+		if err != nil {
+			return nil, err
+		}
+		if op == PLUS || op == MINUS || op == MODULO {
+			return withUnit(result, ux), nil
+		}
+		return result, nil
+
+	case MULTIPLY, DIVIDE:
+		result, err := arith(op, a, b, prec)
+		if err != nil {
+			return nil, err
+		}
+		sign := 1
+		if op == DIVIDE {
+			sign = -1
+		}
+		u := ux.mul(uy, sign)
+		if len(u.dim) == 0 {
+			// 无量纲的结果化为普通数值，例如 1 km / 1 m = 1000
+			return scale(result, u.factor, prec)
+		}
+		return withUnit(result, u), nil
+
+	case POWER:
+		if _, ok := y.(Quantity); ok {
+			return nil, errorf("unit.exponent_quantity", FormatValue(y))
+		}
+		n, ok := toBigInt(b)
+		if !ok || !n.IsInt64() || n.Int64() < -maxUnitExponent || n.Int64() > maxUnitExponent {
+			return nil, errorf("unit.exponent", maxUnitExponent, FormatValue(y))
+		}
+		u := ux.pow(int(n.Int64()))
+		for _, t := range u.terms {
+			if t.exp < -maxUnitExponent || t.exp > maxUnitExponent {
+				return nil, errorf("unit.exponent_overflow", u, maxUnitExponent)
+			}
+		}
+		result, err := power(a, b, prec)
+		if err != nil {
+			return nil, err
+		}
+		return withUnit(result, u), nil
+	}
+	return nil, errorf("unit.operator",
+		operatorSymbol(op), FormatValue(x), operatorSymbol(op), FormatValue(y))
+}
+
+// hasQuantity 判断数值中是否有带单位的数量
+func hasQuantity(values ...Value) bool {
+	for _, v := range values {
+		if _, ok := v.(Quantity); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// callWithUnits 以带单位的参数调用内置函数
+//
+// 只有 keepUnit 的函数（abs、min、sum 等）接受带单位的参数：参数必须量纲相同，
+// 先换算为第一个参数的单位再调用，结果也以该单位计。
+func (e *evaluator) callWithUnits(fn *Function, args []Value) (Value, error) {
+	if !fn.keepUnit {
+		return nil, errorf("unit.function", fn.Name)
+	}
+	_, u := splitQuantity(args[0])
+	amounts := make([]Value, len(args))
+	for i, v := range args {
+		amount, from := splitQuantity(v)
+		if !from.dim.equal(u.dim) {
+			return nil, errorf("dimension.function", fn.Name, FormatValue(v), FormatValue(args[0]))
+		}
+		var err error
+		if amounts[i], err = convertAmount(amount, from, u, e.prec); err != nil {
+			return nil, err
+		}
+	}
+	result, err := e.callBuiltin(fn, amounts)
+	if err != nil {
+		return nil, err
+	}
+	return withUnit(result, u), nil
+}
+
+// RegisterUnit 注册单位，同名单位会被覆盖
+//
+// definition 用数字和已注册的单位定义新单位，例如 "1000 m"、"km/h"、"1.08 USD"、"1/s"；
+// 只有数字时定义无量纲的单位，例如 dozen 为 "12"；
+// 为 "base" 时注册一个新的基本单位，即新的量纲，例如货币的基准 USD。
+func (r *Registry) RegisterUnit(name, definition string) error {
+	if !isValidName(name) {
+		return errorf("definition.unit_name", name)
+	}
+	if _, ok := keywords[name]; ok {
+		return errorf("definition.unit_keyword", name)
+	}
+	if IsEtherUnit(name) {
+		return errorf("definition.unit_ether", name)
+	}
+
+	def := unitDef{factor: big.NewRat(1, 1), dim: dimension{name: 1}}
+	if strings.TrimSpace(definition) != "base" {
+		scale, u, err := r.scanUnit(definition, true)
+		if err != nil {
+			return errorf("definition.unit", name, err)
+		}
+		def = unitDef{factor: scale.Mul(scale, u.factor), dim: u.dim}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.units[name] = def
+	return nil
+}
+
+// HasUnit 判断是否注册了指定名称的单位
+func (r *Registry) HasUnit(name string) bool {
+	_, ok := r.unit(name)
+	return ok
+}
+
+func (r *Registry) unit(name string) (unitDef, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.units[name]
+	return u, ok
+}
+
+// UnitNames 返回按字母顺序排列的单位名，不包括以太坊单位
+func (r *Registry) UnitNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.units))
+	for name := range r.units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseUnit 解析单位表达式，例如 km、m/s、kg*m/s^2
+func (r *Registry) parseUnit(text string) (*Unit, error) {
+	_, u, err := r.scanUnit(text, false)
+	return u, err
+}
+
+// scanUnit 解析 [数字] 单位表达式，返回数字（没有时为 1）和单位
+//
+// 单位表达式由单位名组成，以 '*' 或 '/' 连接，单位名后可以跟 ^n 表示整数次幂；
+// '/' 只作用于紧跟其后的单位，因此 m/s/s 等于 m/s^2。
+func (r *Registry) scanUnit(text string, allowNumber bool) (*big.Rat, *Unit, error) {
+	lexer := NewLexer(text)
+	tok := lexer.NextToken()
+	number := big.NewRat(1, 1)
+	if allowNumber && tok.Type == NUMBER {
+		n, err := parseExactLiteral(tok.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		number = n
+		tok = lexer.NextToken()
+	} else if tok.Type == EOF {
+		return nil, nil, errorf("unit.empty")
+	}
+
+	u := dimensionless
+	for first := true; tok.Type != EOF; first = false {
+		sign := 1
+		switch {
+		case tok.Type == MULTIPLY || tok.Type == DIVIDE:
+			if tok.Type == DIVIDE {
+				sign = -1
+			}
+			tok = lexer.NextToken()
+		case !first:
+			return nil, nil, errorf("unit.unexpected", text, describeToken(tok))
+		}
+		if tok.Type != IDENT {
+			return nil, nil, errorf("unit.missing_name", text, describeToken(tok))
+		}
+		def, ok := r.unit(tok.Value)
+		if !ok {
+			return nil, nil, errorf("unknown_unit.name", tok.Value)
+		}
+		term := &Unit{terms: []unitTerm{{tok.Value, 1}}, factor: def.factor, dim: def.dim}
+
+		if tok = lexer.NextToken(); tok.Type == POWER {
+			exp, err := unitExponent(lexer, text)
+			if err != nil {
+				return nil, nil, err
+			}
+			term = term.pow(exp)
+			tok = lexer.NextToken()
+		}
+		u = u.mul(term, sign)
+	}
+	return number, u, nil
+}
+
+// unitExponent 读取单位名后 '^' 之后的整数指数
+func unitExponent(lexer *Lexer, text string) (int, error) {
+	tok := lexer.NextToken()
+	sign := 1
+	if tok.Type == MINUS {
+		sign = -1
+		tok = lexer.NextToken()
+	}
+	n, err := strconv.Atoi(tok.Value)
+	if tok.Type != NUMBER || err != nil || n == 0 || n > maxUnitExponent {
+		return 0, errorf("unit.definition_exponent", text, maxUnitExponent)
+	}
+	return sign * n, nil
+}
+
+// isUnitExponent 判断字面量能否作为单位的指数，即不带符号的十进制整数
+func isUnitExponent(lit string) bool {
+	for i := 0; i < len(lit); i++ {
+		if !isDigitOf(lit[i], 10) {
+			return false
+		}
+	}
+	return lit != ""
+}
+
+// builtinUnits 内置单位及其定义，按依赖顺序排列
+//
+// 基本单位为 m、kg、s、bit；货币没有内置单位，汇率随时间变化，需要通过单位文件提供。
+var builtinUnits = [][2]string{
+	{"m", "base"},
+	{"kg", "base"},
+	{"s", "base"},
+	{"bit", "base"},
+
+	// 长度
+	{"km", "1000 m"},
+	{"cm", "0.01 m"},
+	{"mm", "0.001 m"},
+	{"um", "1e-6 m"},
+	{"nm", "1e-9 m"},
+	{"mi", "1609.344 m"},
+	{"yd", "0.9144 m"},
+	{"ft", "0.3048 m"},
+	{"inch", "0.0254 m"},
+	{"nmi", "1852 m"},
+
+	// 质量
+	{"g", "0.001 kg"},
+	{"mg", "0.001 g"},
+	{"t", "1000 kg"},
+	{"lb", "0.45359237 kg"},
+
+	// 时间
+	{"ms", "0.001 s"},
+	{"us", "1e-6 s"},
+	{"ns", "1e-9 s"},
+	{"min", "60 s"},
+	{"h", "60 min"},
+	{"d", "24 h"},
+	{"wk", "7 d"},
+	{"yr", "365 d"},
+
+	// 数据量，kB、MB 等为十进制，KiB、MiB 等为二进制
+	{"B", "8 bit"},
+	{"kB", "1000 B"},
+	{"MB", "1000 kB"},
+	{"GB", "1000 MB"},
+	{"TB", "1000 GB"},
+	{"PB", "1000 TB"},
+	{"KiB", "1024 B"},
+	{"MiB", "1024 KiB"},
+	{"GiB", "1024 MiB"},
+	{"TiB", "1024 GiB"},
+	{"PiB", "1024 TiB"},
+	{"kbit", "1000 bit"},
+	{"Mbit", "1000 kbit"},
+	{"Gbit", "1000 Mbit"},
+	{"Tbit", "1000 Gbit"},
+
+	// 频率和速率
+	{"Hz", "1/s"},
+	{"kHz", "1000 Hz"},
+	{"MHz", "1000 kHz"},
+	{"GHz", "1000 MHz"},
+	{"bps", "bit/s"},
+	{"kbps", "kbit/s"},
+	{"Mbps", "Mbit/s"},
+	{"Gbps", "Gbit/s"},
+
+	// 力、能量和功率
+	{"N", "kg*m/s^2"},
+	{"J", "N*m"},
+	{"kJ", "1000 J"},
+	{"W", "J/s"},
+	{"kW", "1000 W"},
+	{"MW", "1000 kW"},
+	{"Wh", "W*h"},
+	{"kWh", "kW*h"},
+}
+
+// registerUnits 注册内置单位
+func registerUnits(r *Registry) {
+	for _, u := range builtinUnits {
+		r.RegisterUnit(u[0], u[1])
+	}
+}
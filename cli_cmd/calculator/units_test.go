@@ -0,0 +1,216 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUnits 测试带单位的数量和单位换算
+func TestUnits(t *testing.T) {
+	tests := []struct {
+		stmts    []string
+		expected string
+		desc     string
+	}{
+		{[]string{"5 km"}, "5 km", "带单位的字面量"},
+		{[]string{"60 km/h"}, "60 km/h", "复合单位"},
+		{[]string{"9.81 kg*m/s^2"}, "9.81 kg*m/s^2", "带指数的复合单位"},
+		{[]string{"5 km / 2 h in m/s"}, "0.694444 m/s", "速度换算"},
+		{[]string{"3 MB * 8 in Mbit"}, "192 Mbit", "数据量换算"},
+		{[]string{"1 GiB in MiB"}, "1024 MiB", "二进制数据量"},
+		{[]string{"100 Mbps * 1 h in GB"}, "45 GB", "带宽乘以时间"},
+		{[]string{"1 km + 500 m"}, "1.5 km", "加法换算为左操作数的单位"},
+		{[]string{"500 m + 1 km"}, "1500 m", "右操作数换算为左操作数的单位"},
+		{[]string{"5 km > 4900 m"}, "1", "比较不同单位的数量"},
+		{[]string{"1 h == 60 min"}, "1", "相等比较"},
+		{[]string{"2 km^2 in m^2"}, "2000000 m^2", "字面量的单位指数"},
+		{[]string{"(2 km) ^ 2"}, "4 km^2", "数量的整数次幂"},
+		{[]string{"2 km ^ 2"}, "4 km^2", "有空格时 ^ 是运算符"},
+		{[]string{"1 km / 1 m"}, "1000", "无量纲的结果化为普通数值"},
+		{[]string{"60 km/h * 30 min in km"}, "30 km", "乘法后换算"},
+		{[]string{"10 kg * 9.81 m/s^2 in N"}, "98.1 N", "导出单位"},
+		{[]string{"1 kWh in J"}, "3600000 J", "能量换算"},
+		{[]string{"1 / (2 s) in Hz"}, "0.5 Hz", "倒数的单位"},
+		{[]string{"-5 km"}, "-5 km", "负数量"},
+		{[]string{"5 km * 2"}, "10 km", "数量乘以普通数值"},
+		{[]string{"d = 42 km", "d / 2 h in km/h"}, "21 km/h", "带单位的变量"},
+		{[]string{"speed(d, t) = d / t in m/s", "speed(100 m, 10 s)"}, "10 m/s", "用户函数"},
+		{[]string{"sum([3 GB, 500 MB])"}, "3.5 GB", "聚合函数换算为第一个参数的单位"},
+		{[]string{"max(1 km, 1200 m)"}, "1.2 km", "max 保持单位"},
+		{[]string{"abs(-3 s)"}, "3 s", "abs 保持单位"},
+		{[]string{"map([1, 2], x => x * 1 km)"}, "[1 km, 2 km]", "带单位的列表"},
+		{[]string{"1 ether in gwei"}, "1000000000", "以太坊单位不受影响"},
+		{[]string{"1 m + 1 s"}, "", "量纲不匹配的加法"},
+		{[]string{"1 km in s"}, "", "量纲不匹配的换算"},
+		{[]string{"5 km + 1"}, "", "数量与普通数值相加"},
+		{[]string{"5 km in ether"}, "", "数量不能换算为以太坊单位"},
+		{[]string{"1 m in parsec"}, "", "未知的目标单位"},
+		{[]string{"sqrt(4 m)"}, "", "一般函数不接受带单位的参数"},
+		{[]string{"2 ^ (1 m)"}, "", "指数不能带单位"},
+		{[]string{"(1 m) ^ 0.5"}, "", "数量的指数必须是整数"},
+		{[]string{"((1 km) ^ 64) ^ 2"}, "", "单位的指数过大"},
+		{[]string{"1 m .. 3 m"}, "", "范围不能带单位"},
+		{[]string{"1 m & 1 m"}, "", "位运算不能带单位"},
+		{[]string{"sum(1 m, 1 s)"}, "", "聚合函数的参数量纲不同"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res := execAll(t, NewEnv(), test.stmts...)
+			if test.expected == "" {
+				assert.Error(t, res.Err)
+				return
+			}
+			require.NoError(t, res.Err)
+			assert.Equal(t, test.expected, FormatValue(res.Value))
+		})
+	}
+}
+
+// TestUnitModes 测试高精度模式下的单位换算
+func TestUnitModes(t *testing.T) {
+	assert.Equal(t, "25/36 m/s", evaluateIn(t, ModeRat, "5 km / 2 h in m/s"))
+	assert.Equal(t, "0.1 km", evaluateIn(t, ModeRat, "100 m in km"))
+	assert.Equal(t, "1.609344 km", evaluateIn(t, ModeBig, "1 mi in km"))
+	assert.Equal(t, "1.60934 km", evaluateIn(t, ModeFloat, "1 mi in km"))
+}
+
+// TestUnitSyntax 测试单位的解析：复合单位的各部分之间不能有空白
+func TestUnitSyntax(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"60 km/h", "60 km/h", "紧挨的 / 组成复合单位"},
+		{"60 km / h", "", "有空白时 / 是除法，h 不是变量"},
+		{"5 km/2", "2.5 km", "/ 后不是单位时是除法"},
+		{"2 m^-1", "2 m^-1", "负指数"},
+		{"2 s^-1 in Hz", "2 Hz", "负指数换算"},
+		{"2 m^ 2", "4 m^2", "^ 后有空白时是运算符"},
+		{"1 km*h", "1 km*h", "乘积单位"},
+		{"1e3 m in km", "1 km", "科学计数法的数量"},
+		{"1.5ether in gwei", "1500000000", "紧挨数字的以太坊单位"},
+		{"1 ether/h", "", "以太坊单位不能组成复合单位"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v, err := NewEnv().Evaluate(test.expression)
+			if test.expected == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, FormatValue(v))
+		})
+	}
+
+	node, err := Parse("5 km / 2 h in m/s")
+	require.NoError(t, err)
+	assert.Equal(t, "5 km / 2 h in m/s", FormatNode(node))
+	convert, ok := node.(*ConvertExpr)
+	require.True(t, ok)
+	assert.Equal(t, "m/s", convert.Unit.Name)
+	assert.Equal(t, len("5 km / 2 h in m/s"), convert.End())
+}
+
+// TestDimensionError 测试量纲不匹配的错误代码
+func TestDimensionError(t *testing.T) {
+	_, err := NewEnv().Evaluate("1 m + 1 s")
+	require.ErrorIs(t, err, ErrDimension)
+	assert.Equal(t, CodeType, ErrorCode(err))
+	assert.Equal(t, "量纲不匹配: 1 m + 1 s", err.Error())
+
+	_, err = NewEnv().Evaluate("3 MB in km")
+	require.ErrorIs(t, err, ErrDimension)
+	assert.Equal(t, "量纲不匹配: 3 MB 不能换算为 km", err.Error())
+
+	_, err = NewEnv().Evaluate("1 m in parsec")
+	assert.Equal(t, CodeEval, ErrorCode(err), "未知的单位不是类型错误")
+}
+
+// TestQuantityScalar 测试返回 float64 的接口拒绝带单位的结果
+func TestQuantityScalar(t *testing.T) {
+	env := NewEnv()
+	_, err := env.Calculate("5 km")
+	assert.EqualError(t, err, "结果带有单位 km，不是数值: 5 km")
+
+	_, err = env.Calculate("5 km / 1 m")
+	assert.NoError(t, err, "无量纲的结果是普通数值")
+
+	_, err = env.Calculate("d = 5 km")
+	require.Error(t, err)
+	_, ok := env.Get("d")
+	assert.False(t, ok)
+
+	_, err = Compile("5 km")
+	assert.Error(t, err)
+}
+
+// TestFormatQuantity 测试带单位的数量按选项输出
+func TestFormatQuantity(t *testing.T) {
+	v, err := NewEnv().Evaluate("255 MB")
+	require.NoError(t, err)
+
+	s, err := Format(v, FormatOptions{Base: 16})
+	require.NoError(t, err)
+	assert.Equal(t, "0xff MB", s)
+	s, err = Format(v, FormatOptions{Sci: true})
+	require.NoError(t, err)
+	assert.Equal(t, "2.55e2 MB", s)
+	_, err = Format(v, FormatOptions{Unit: "gwei"})
+	assert.Error(t, err, "带单位的数量不能按以太坊单位输出")
+	assert.Equal(t, ModeFloat, ModeOf(v))
+}
+
+// TestRegisterUnit 测试注册自定义单位
+func TestRegisterUnit(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	require.NoError(t, r.RegisterUnit("req", "base"))
+	require.NoError(t, r.RegisterUnit("rps", "req/s"))
+	require.NoError(t, r.RegisterUnit("krps", "1000 rps"))
+	require.NoError(t, r.RegisterUnit("dozen", "12"))
+
+	env := NewEnvWithRegistry(r)
+	v, err := env.Evaluate("3 krps * 1 h in req")
+	require.NoError(t, err)
+	assert.Equal(t, "10800000 req", FormatValue(v))
+	v, err = env.Evaluate("2 dozen * 2")
+	require.NoError(t, err)
+	assert.Equal(t, "48", FormatValue(v), "无量纲的单位相乘后化为普通数值")
+
+	_, err = NewEnv().Evaluate("3 krps")
+	assert.Error(t, err, "默认注册表中没有自定义单位")
+	assert.False(t, DefaultRegistry.HasUnit("req"))
+	assert.Contains(t, r.UnitNames(), "krps")
+
+	tests := []struct {
+		name, definition string
+		desc             string
+	}{
+		{"1x", "1 m", "无效的名称"},
+		{"in", "1 m", "关键字"},
+		{"gwei", "1 m", "以太坊单位"},
+		{"foo", "1 bar", "未知的单位"},
+		{"foo", "", "空的定义"},
+		{"foo", "m s", "缺少运算符"},
+		{"foo", "m^x", "无效的指数"},
+		{"foo", "m/", "缺少单位名称"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Error(t, r.RegisterUnit(test.name, test.definition))
+		})
+	}
+}
+
+// TestBuiltinUnits 测试内置单位都能注册成功
+func TestBuiltinUnits(t *testing.T) {
+	for _, u := range builtinUnits {
+		assert.True(t, DefaultRegistry.HasUnit(u[0]), "单位 %s = %s", u[0], u[1])
+	}
+	assert.Len(t, DefaultRegistry.UnitNames(), len(builtinUnits))
+}
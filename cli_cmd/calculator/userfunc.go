@@ -0,0 +1,90 @@
+package calculator
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxCallDepth 用户函数调用的最大嵌套深度，用于限制递归
+const MaxCallDepth = 1000
+
+// UserFunc 用户定义的函数，例如 f(x, y) = x^2 + y
+//
+// 函数体在调用时求值：参数优先于同名的全局变量和常量，
+// 函数体中引用的其他函数和全局变量使用调用时的定义，因此支持递归和相互递归。
+type UserFunc struct {
+	Name   string
+	Params []string
+	Body   Node
+}
+
+func (f *UserFunc) String() string {
+	return f.Name + "(" + strings.Join(f.Params, ", ") + ") = " + f.Body.String()
+}
+
+// Define 定义用户函数，同名的用户函数会被替换
+//
+// 内置函数和常量不能被重新定义。
+func (e *Env) Define(def *FuncDef) (*UserFunc, error) {
+	name := def.Name.Name
+	if _, ok := e.registry.Func(name); ok {
+		return nil, errorf("definition.builtin", name)
+	}
+	if _, ok := e.registry.Const(name); ok {
+		return nil, errorf("definition.constant_name", name)
+	}
+
+	fn := &UserFunc{Name: name, Params: make([]string, len(def.Params)), Body: def.Body}
+	for i, param := range def.Params {
+		fn.Params[i] = param.Name
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.funcs[name] = fn
+	return fn, nil
+}
+
+// DefineFunc 解析并定义用户函数，例如 DefineFunc("f(x, y) = x^2 + y")
+func (e *Env) DefineFunc(src string) (*UserFunc, error) {
+	node, err := e.parse(src)
+	if err != nil {
+		return nil, err
+	}
+	def, ok := node.(*FuncDef)
+	if !ok {
+		return nil, errorf("definition.not_func_def", src)
+	}
+	return e.Define(def)
+}
+
+// UserFunc 获取用户函数
+func (e *Env) UserFunc(name string) (*UserFunc, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	fn, ok := e.funcs[name]
+	return fn, ok
+}
+
+// UserFuncs 返回按名称排序的用户函数
+func (e *Env) UserFuncs() []*UserFunc {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	funcs := make([]*UserFunc, 0, len(e.funcs))
+	for _, fn := range e.funcs {
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].Name < funcs[j].Name })
+	return funcs
+}
+
+// Undefine 删除用户函数，函数不存在时返回 false
+func (e *Env) Undefine(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.funcs[name]; !ok {
+		return false
+	}
+	delete(e.funcs, name)
+	return true
+}
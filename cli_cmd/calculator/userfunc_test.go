@@ -0,0 +1,131 @@
+package calculator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// execAll 依次执行语句，返回最后一条语句的结果
+func execAll(t *testing.T, env *Env, stmts ...string) Result {
+	var res Result
+	for i, stmt := range stmts {
+		res = env.Exec(Statement{Line: i + 1, Text: stmt})
+		if i < len(stmts)-1 {
+			require.NoError(t, res.Err, "执行 %s 时不应该出错", stmt)
+		}
+	}
+	return res
+}
+
+// TestUserFunc 测试用户函数的定义和调用
+func TestUserFunc(t *testing.T) {
+	tests := []struct {
+		stmts    []string
+		expected string
+		desc     string
+	}{
+		{[]string{"f(x, y) = x^2 + y", "f(3, 1)"}, "10", "两个参数"},
+		{[]string{"five() = 5", "five() * 2"}, "10", "无参数"},
+		{[]string{"x = 100", "f(x) = x + 1", "f(1)"}, "2", "参数优先于全局变量"},
+		{[]string{"f(e) = e * 2", "f(3)"}, "6", "参数优先于常量"},
+		{[]string{"rate = 2", "f(x) = x * rate", "rate = 3", "f(5)"}, "15", "全局变量在调用时解析"},
+		{[]string{"fact(n) = n <= 1 ? 1 : n * fact(n - 1)", "fact(10)"}, "3628800", "递归"},
+		{[]string{"even(n) = n == 0 ? 1 : odd(n - 1)", "odd(n) = n == 0 ? 0 : even(n - 1)", "even(10)"}, "1", "相互递归"},
+		{[]string{"g(x) = x * 2", "f(x) = g(x + 1)", "f(1)"}, "4", "调用其他函数"},
+		{[]string{"f(x) = x + 1", "f(x) = x + 2", "f(1)"}, "3", "重新定义"},
+		{[]string{"fee(gas, price) = gas * price in ether", "fee(21000, 30 gwei)"}, "0.00063", "以太坊金额"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res := execAll(t, NewEnv(), test.stmts...)
+			require.NoError(t, res.Err)
+			assert.Equal(t, test.expected, FormatValue(res.Value))
+		})
+	}
+}
+
+// TestUserFuncScope 测试函数体看不到调用者的参数
+func TestUserFuncScope(t *testing.T) {
+	env := NewEnv()
+	res := execAll(t, env, "g() = y", "f(y) = g()", "f(1)")
+	assert.EqualError(t, res.Err, "第 3 行: 未定义的变量: y")
+
+	_, ok := env.GetValue("y")
+	assert.False(t, ok, "参数不应该泄漏为全局变量")
+}
+
+// TestUserFuncErrors 测试用户函数相关的错误
+func TestUserFuncErrors(t *testing.T) {
+	tests := []struct {
+		stmts []string
+		desc  string
+	}{
+		{[]string{"f(x) = x", "f(1, 2)"}, "参数过多"},
+		{[]string{"f(x, y) = x", "f(1)"}, "参数过少"},
+		{[]string{"sqrt(x) = x"}, "不能重新定义内置函数"},
+		{[]string{"pi(x) = x"}, "不能与常量重名"},
+		{[]string{"loop(n) = loop(n + 1)", "loop(1)"}, "递归深度超过限制"},
+		{[]string{"f(x, x) = x"}, "重复的参数名"},
+		{[]string{"f(1) = 2"}, "参数不是名称"},
+		{[]string{"f(x) = y = x"}, "函数体不能是赋值"},
+		{[]string{"a = f(x) = x"}, "函数定义不能作为赋值的值"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			res := execAll(t, NewEnv(), test.stmts...)
+			assert.Error(t, res.Err)
+		})
+	}
+
+	_, err := NewEnv().Evaluate("f(x) = x")
+	assert.Error(t, err, "Evaluate 不能执行函数定义")
+}
+
+// TestDefineFunc 测试通过 API 定义和删除函数
+func TestDefineFunc(t *testing.T) {
+	env := NewEnv()
+	fn, err := env.DefineFunc("area(w, h) = w * h")
+	require.NoError(t, err)
+	assert.Equal(t, "area", fn.Name)
+	assert.Equal(t, []string{"w", "h"}, fn.Params)
+	assert.Equal(t, "area(w, h) = w * h", fn.String())
+
+	v, err := env.Calculate("area(3, 4)")
+	require.NoError(t, err)
+	assert.Equal(t, 12.0, v)
+
+	_, err = env.DefineFunc("box(a) = a")
+	require.NoError(t, err)
+	funcs := env.UserFuncs()
+	require.Len(t, funcs, 2)
+	assert.Equal(t, "area", funcs[0].Name)
+
+	assert.True(t, env.Undefine("area"))
+	assert.False(t, env.Undefine("area"))
+	_, err = env.Calculate("area(3, 4)")
+	assert.Error(t, err)
+
+	_, err = env.DefineFunc("1 + 2")
+	assert.Error(t, err)
+	_, err = env.DefineFunc("f(x) = ")
+	var syntaxErr *SyntaxError
+	assert.True(t, errors.As(err, &syntaxErr))
+}
+
+// TestFuncDefNode 测试函数定义的语法树
+func TestFuncDefNode(t *testing.T) {
+	node, err := Parse("f(x, y) = x^2 + y")
+	require.NoError(t, err)
+	def, ok := node.(*FuncDef)
+	require.True(t, ok)
+	assert.Equal(t, "f(x, y) = x ^ 2 + y", def.String())
+	assert.Equal(t, 0, def.Pos())
+	assert.Equal(t, 17, def.End())
+	assert.Equal(t, 8, def.EqPos)
+	assert.Contains(t, Dump(def), "Func f(x, y) @8")
+}
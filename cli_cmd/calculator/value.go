@@ -0,0 +1,443 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Mode 数值计算模式，决定数字字面量和函数结果的表示方式
+type Mode int
+
+const (
+	ModeFloat Mode = iota // float64 双精度浮点数
+	ModeBig               // big.Float 任意精度浮点数
+	ModeRat               // big.Rat 精确有理数
+)
+
+// DefaultPrecision big.Float 模式的默认精度（二进制位数）
+const DefaultPrecision uint = 256
+
+// MaxPrecision 允许设置的最大精度（二进制位数）
+const MaxPrecision uint = 1 << 16
+
+var modeNames = map[Mode]string{
+	ModeFloat: "float",
+	ModeBig:   "big",
+	ModeRat:   "rat",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode 解析计算模式名称（float、big、rat）
+func ParseMode(s string) (Mode, error) {
+	for m, name := range modeNames {
+		if strings.EqualFold(s, name) {
+			return m, nil
+		}
+	}
+	return 0, errorf("setting.mode", s)
+}
+
+// Value 表示一个求值结果
+//
+// 具体类型为 Float、BigFloat、Rat、带单位的数量 Quantity 或者数值的列表 List。
+type Value interface {
+	// Float64 返回最接近的 float64 值
+	Float64() float64
+	// String 按默认格式输出
+	String() string
+	rank() int
+}
+
+// Float 双精度浮点数值
+type Float float64
+
+// BigFloat 任意精度浮点数值
+type BigFloat struct{ *big.Float }
+
+// Rat 精确有理数值
+type Rat struct{ *big.Rat }
+
+func (f Float) Float64() float64 { return float64(f) }
+
+func (f BigFloat) Float64() float64 {
+	v, _ := f.Float.Float64()
+	return v
+}
+
+func (r Rat) Float64() float64 {
+	v, _ := r.Rat.Float64()
+	return v
+}
+
+func (f Float) String() string    { return FormatValue(f) }
+func (f BigFloat) String() string { return FormatValue(f) }
+func (r Rat) String() string      { return FormatValue(r) }
+
+// rank 决定混合运算时的类型提升顺序：Float < Rat < BigFloat
+//
+// Rat 只来自精确模式或精确字面量，与 Float 混合时保持精确；
+// BigFloat 是显式选择的近似计算，与其他类型混合时结果为 BigFloat。
+func (Float) rank() int    { return 0 }
+func (Rat) rank() int      { return 1 }
+func (BigFloat) rank() int { return 2 }
+
+// ModeOf 返回数值类型对应的计算模式，例如 Rat 对应 ModeRat；带单位的数量按其数量部分的类型
+func ModeOf(v Value) Mode {
+	switch x := v.(type) {
+	case Quantity:
+		return ModeOf(x.Amount)
+	case BigFloat:
+		return ModeBig
+	case Rat:
+		return ModeRat
+	}
+	return ModeFloat
+}
+
+// NewBigFloat 创建指定精度的 BigFloat
+func NewBigFloat(x float64, prec uint) BigFloat {
+	return BigFloat{new(big.Float).SetPrec(prec).SetFloat64(x)}
+}
+
+// float64Prec float64 的尾数位数；按 float64 计算的结果只有这么多位可靠
+const float64Prec = 53
+
+// resultPrec 返回运算结果的精度：不超过环境精度，也不超过任何一个操作数的精度，
+// 以免按 float64 近似的数值参与运算后输出一长串没有意义的数字
+func resultPrec(prec uint, xs ...BigFloat) uint {
+	for _, x := range xs {
+		if x.Float != nil && x.Prec() < prec {
+			prec = x.Prec()
+		}
+	}
+	return prec
+}
+
+// NewRat 创建值为 a/b 的 Rat
+func NewRat(a, b int64) Rat {
+	return Rat{big.NewRat(a, b)}
+}
+
+// parseNumber 按计算模式解析数字字面量
+//
+// 0x、0b、0o 开头的字面量是精确整数：float 模式下也解析为 Rat，以免大额 wei 丢失精度。
+func parseNumber(lit string, mode Mode, prec uint) (Value, error) {
+	if mode == ModeFloat && !isPrefixedLiteral(lit) {
+		f, err := parseFloatLiteral(lit)
+		if err != nil {
+			return nil, err
+		}
+		return Float(f), nil
+	}
+
+	r, err := parseExactLiteral(lit)
+	if err != nil {
+		return nil, err
+	}
+	if mode == ModeBig {
+		if r.IsInt() {
+			return BigFloat{new(big.Float).SetPrec(prec).SetInt(r.Num())}, nil
+		}
+		// 小数按十进制文本解析，避免先转换为有理数再舍入两次
+		digits, _ := cleanLiteral(lit)
+		f, ok := new(big.Float).SetPrec(prec).SetString(digits)
+		if !ok {
+			return nil, errorf("invalid_number.literal", lit)
+		}
+		return BigFloat{f}, nil
+	}
+	return Rat{r}, nil
+}
+
+// fromFloat 把 float64 转换为计算模式对应的数值
+func fromFloat(f float64, mode Mode, prec uint) (Value, error) {
+	switch mode {
+	case ModeBig:
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, errorf("out_of_range.result", f)
+		}
+		// 结果按 float64 计算，只保留 float64 的精度
+		return NewBigFloat(f, min(prec, float64Prec)), nil
+	case ModeRat:
+		return floatToRat(f)
+	}
+	return Float(f), nil
+}
+
+// floatToRat 把浮点数按其最短十进制表示转换为有理数，例如 0.1 转换为 1/10
+func floatToRat(f float64) (Rat, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return Rat{}, errorf("invalid_number.exact", f)
+	}
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return Rat{r}, nil
+}
+
+// toRat 把数值转换为有理数
+func toRat(v Value) (Rat, error) {
+	switch x := v.(type) {
+	case Rat:
+		return x, nil
+	case Float:
+		return floatToRat(float64(x))
+	case BigFloat:
+		if x.IsInf() {
+			return Rat{}, errorf("invalid_number.exact", x)
+		}
+		r, _ := x.Float.Rat(nil)
+		return Rat{r}, nil
+	}
+	return Rat{}, errorf("invalid_number.value", v)
+}
+
+// toBigFloat 把数值转换为指定精度的 big.Float
+func toBigFloat(v Value, prec uint) (BigFloat, error) {
+	switch x := v.(type) {
+	case BigFloat:
+		return x, nil
+	case Float:
+		if math.IsInf(float64(x), 0) || math.IsNaN(float64(x)) {
+			return BigFloat{}, errorf("out_of_range.result", float64(x))
+		}
+		return NewBigFloat(float64(x), prec), nil
+	case Rat:
+		return BigFloat{new(big.Float).SetPrec(prec).SetRat(x.Rat)}, nil
+	}
+	return BigFloat{}, errorf("invalid_number.value", v)
+}
+
+// promote 把两个操作数提升为同一种类型
+func promote(x, y Value, prec uint) (Value, Value, error) {
+	if x.rank() == y.rank() {
+		return x, y, nil
+	}
+	target := x
+	if y.rank() > x.rank() {
+		target = y
+	}
+	conv := func(v Value) (Value, error) {
+		switch target.(type) {
+		case Rat:
+			return toRat(v)
+		case BigFloat:
+			return toBigFloat(v, prec)
+		}
+		return Float(v.Float64()), nil
+	}
+	x, err := conv(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	y, err = conv(y)
+	if err != nil {
+		return nil, nil, err
+	}
+	return x, y, nil
+}
+
+// sign 返回数值的符号
+func sign(v Value) int {
+	switch x := v.(type) {
+	case BigFloat:
+		return x.Sign()
+	case Rat:
+		return x.Sign()
+	}
+	f := v.Float64()
+	switch {
+	case f > 0:
+		return 1
+	case f < 0:
+		return -1
+	}
+	return 0
+}
+
+// truthy 判断数值在条件中是否为真（非零）
+func truthy(v Value) bool {
+	return sign(v) != 0
+}
+
+// compare 比较两个数值，返回 -1、0 或 1
+func compare(x, y Value, prec uint) (int, error) {
+	x, y, err := promote(x, y, prec)
+	if err != nil {
+		return 0, err
+	}
+	switch a := x.(type) {
+	case BigFloat:
+		return a.Cmp(y.(BigFloat).Float), nil
+	case Rat:
+		return a.Cmp(y.(Rat).Rat), nil
+	}
+	a, b := x.Float64(), y.Float64()
+	switch {
+	case a < b:
+		return -1, nil
+	case a > b:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// boolValue 返回与 like 同类型的 1 或 0
+func boolValue(b bool, like Value, prec uint) Value {
+	n := int64(0)
+	if b {
+		n = 1
+	}
+	switch x := like.(type) {
+	case Quantity:
+		return boolValue(b, x.Amount, prec)
+	case BigFloat:
+		return BigFloat{new(big.Float).SetPrec(prec).SetInt64(n)}
+	case Rat:
+		return Rat{big.NewRat(n, 1)}
+	}
+	return Float(n)
+}
+
+// isInteger 判断数值是否为整数
+func isInteger(v Value) bool {
+	switch x := v.(type) {
+	case BigFloat:
+		return x.IsInt()
+	case Rat:
+		return x.IsInt()
+	}
+	f := v.Float64()
+	return f == math.Trunc(f) && !math.IsInf(f, 0)
+}
+
+// toBigInt 把整数值转换为 big.Int
+func toBigInt(v Value) (*big.Int, bool) {
+	if !isInteger(v) {
+		return nil, false
+	}
+	switch x := v.(type) {
+	case BigFloat:
+		i, _ := x.Int(nil)
+		return i, true
+	case Rat:
+		return new(big.Int).Set(x.Num()), true
+	}
+	i, _ := big.NewFloat(v.Float64()).Int(nil)
+	return i, true
+}
+
+// FormatValue 按数值类型格式化输出
+//
+// Float 与 FormatResult 相同；BigFloat 按其精度输出有效数字；
+// Rat 为整数或有限小数时输出十进制，否则输出最简分数 a/b；
+// Quantity 输出数量和单位，例如 2.5 km/h；List 输出 [a, b, c]。
+func FormatValue(v Value) string {
+	switch x := v.(type) {
+	case Quantity:
+		return FormatValue(x.Amount) + " " + x.Unit.String()
+	case List:
+		s, _ := formatList(x, func(v Value) (string, error) { return FormatValue(v), nil })
+		return s
+	case Float:
+		return FormatResult(float64(x))
+	case BigFloat:
+		if x.Float == nil {
+			return "0"
+		}
+		// 只有整数部分完全落在精度内时才逐位输出，否则末尾的数字没有意义
+		if x.IsInt() && !x.IsInf() && x.MantExp(nil) <= int(x.Prec()) {
+			i, _ := x.Int(nil)
+			return i.String()
+		}
+		return x.Text('g', bigDigits(x.Prec()))
+	case Rat:
+		if x.Rat == nil {
+			return "0"
+		}
+		if x.IsInt() {
+			return x.Num().String()
+		}
+		if twos, fives, ok := terminatingFactors(x.Rat); ok {
+			if digits := max(twos, fives); digits <= maxDecimalDigits {
+				return x.FloatString(digits)
+			}
+			if s, ok := exactSci(x.Rat, twos, fives); ok {
+				return s
+			}
+		}
+		return x.RatString()
+	}
+	return fmt.Sprint(v)
+}
+
+// bigDigits 返回指定二进制精度下可靠的十进制有效位数
+//
+// 保留两位作为舍入误差的余量，使 0.1+0.2 这样的结果输出为 0.3。
+func bigDigits(prec uint) int {
+	digits := int(float64(prec)*math.Log10(2)) - 2
+	if digits < 1 {
+		digits = 1
+	}
+	return digits
+}
+
+// maxDecimalDigits 有理数按十进制展开时允许的最多小数位数，超过时输出精确的科学计数法或分数
+const maxDecimalDigits = 1000
+
+// terminatingFactors 如果有理数可以写成有限小数，返回分母中 2 和 5 的个数
+//
+// 2 的个数由 TrailingZeroBits 直接得到；去掉 2 之后的分母必须是 5 的幂，
+// 由位数算出唯一可能的指数后只需一次 Exp 验证，不必逐个除以 5。
+func terminatingFactors(r *big.Rat) (twos, fives int, ok bool) {
+	den := new(big.Int).Set(r.Denom())
+	twos = int(den.TrailingZeroBits())
+	den.Rsh(den, uint(twos))
+	if den.Cmp(big.NewInt(1)) == 0 {
+		return twos, 0, true
+	}
+	if new(big.Int).Mod(den, big.NewInt(5)).Sign() != 0 {
+		return 0, 0, false
+	}
+
+	// 5^f 的位数为 floor(f·log2(5)) + 1，与分母位数相同的 f 至多一个
+	bits := den.BitLen()
+	f := int(math.Ceil(float64(bits-1) / math.Log2(5)))
+	if int(float64(f)*math.Log2(5))+1 != bits {
+		return 0, 0, false
+	}
+	p := new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(f)), nil)
+	return twos, f, p.Cmp(den) == 0
+}
+
+// exactSci 把分母为 2^twos·5^fives 的有理数精确地写成科学计数法，例如 1e-999999
+//
+// 有效数字超过 maxDecimalDigits 位时返回 false。
+func exactSci(r *big.Rat, twos, fives int) (string, bool) {
+	// 有效数字为 num·2^(d-twos)·5^(d-fives)，先按位数估算，避免构造过大的整数
+	digits := max(twos, fives)
+	bits := r.Num().BitLen() + (digits - twos) + (digits-fives)*7/3
+	if bits > maxDecimalDigits*10/3 {
+		return "", false
+	}
+	n := new(big.Int).Abs(r.Num())
+	n.Lsh(n, uint(digits-twos))
+	n.Mul(n, new(big.Int).Exp(big.NewInt(5), big.NewInt(int64(digits-fives)), nil))
+
+	mantissa := n.String()
+	exp := len(mantissa) - 1 - digits
+	if len(mantissa) > 1 {
+		mantissa = mantissa[:1] + "." + mantissa[1:]
+	}
+	if r.Sign() < 0 {
+		mantissa = "-" + mantissa
+	}
+	return tidySci(mantissa + "e" + strconv.Itoa(exp)), true
+}
@@ -0,0 +1,299 @@
+package calculator
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// evaluateIn 在指定模式下计算表达式并格式化结果
+func evaluateIn(t *testing.T, mode Mode, expression string) string {
+	t.Helper()
+	env := NewEnv()
+	require.NoError(t, env.SetMode(mode))
+	v, err := env.Evaluate(expression)
+	require.NoError(t, err, "计算 %s 时不应该出错", expression)
+	return FormatValue(v)
+}
+
+// TestRatMode 测试精确有理数模式
+func TestRatMode(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"0.1+0.2", "0.3", "小数精确相加"},
+		{"1/3", "1/3", "分数"},
+		{"1/3+1/6", "0.5", "分数约分"},
+		{"1/4", "0.25", "有限小数"},
+		{"2^100", "1267650600228229401496703205376", "大整数幂"},
+		{"(3/2)^-2", "4/9", "负整数指数"},
+		{"123456789012345678901234567890 + 1", "123456789012345678901234567891", "大整数加法"},
+		{"7.5 % 2", "1.5", "小数取模"},
+		{"-7 % 3", "-1", "负数取模"},
+		{"1/3 == 2/6", "1", "精确比较"},
+		{"abs(-1/3)", "1/3", "精确绝对值"},
+		{"floor(-7/2)", "-4", "精确向下取整"},
+		{"ceil(7/2)", "4", "精确向上取整"},
+		{"round(5/2)", "3", "精确四舍五入"},
+		{"round(-5/2)", "-3", "负数四舍五入"},
+		{"max(1/3, 1/4)", "1/3", "精确最大值"},
+		{"sqrt(9/4)", "1.5", "完全平方数开方"},
+		{"1 << 100", "1267650600228229401496703205376", "大整数移位"},
+		{"(2^70) & (2^70 + 1)", "1180591620717411303424", "大整数按位与"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, evaluateIn(t, ModeRat, test.expression))
+		})
+	}
+}
+
+// TestBigMode 测试任意精度浮点数模式
+func TestBigMode(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"0.1+0.2", "0.3", "小数相加"},
+		{"2^200", "1606938044258990275541962092341162602522202993782792835301376", "大整数幂"},
+		{"10^30 + 1", "1000000000000000000000000000001", "超过 float64 精度的整数"},
+		{"7.5 % 2", "1.5", "小数取模"},
+		{"floor(2.5)", "2", "向下取整"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, evaluateIn(t, ModeBig, test.expression))
+		})
+	}
+}
+
+// TestBigModeFloatResults 测试 big 模式下按 float64 计算的结果只输出可靠的位数
+func TestBigModeFloatResults(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+		desc       string
+	}{
+		{"exp(1)", "2.718281828459", "指数函数"},
+		{"2^0.5", "1.414213562373", "非整数指数"},
+		{"ln(2)", "0.6931471805599", "对数"},
+		{"sin(1)", "0.8414709848079", "三角函数"},
+		{"exp(1) + 1", "3.718281828459", "参与运算后精度不变"},
+		{"exp(1)^2", "7.389056098931", "整数次幂"},
+		{"sqrt(2)", "1.41421356237309504880168872420969807856967187537694807317667973799073247846", "高精度开方不受影响"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, evaluateIn(t, ModeBig, test.expression))
+		})
+	}
+
+	env := NewEnv()
+	require.NoError(t, env.SetMode(ModeBig))
+	v, err := env.Evaluate("exp(1)")
+	require.NoError(t, err)
+	assert.Equal(t, uint(float64Prec), v.(BigFloat).Prec())
+}
+
+// TestBigModePrecision 测试精度设置
+func TestBigModePrecision(t *testing.T) {
+	env := NewEnv()
+	require.NoError(t, env.SetMode(ModeBig))
+	require.NoError(t, env.SetPrecision(512))
+
+	v, err := env.Evaluate("sqrt(2)")
+	require.NoError(t, err)
+	bf, ok := v.(BigFloat)
+	require.True(t, ok, "big 模式的结果应该是 BigFloat")
+	assert.Equal(t, uint(512), bf.Prec())
+	assert.Equal(t, "1.41421356237309504880168872420969807856967187537694807317667973799", FormatValue(v)[:67])
+
+	v, err = env.Evaluate("1/3")
+	require.NoError(t, err)
+	assert.Len(t, FormatValue(v), 2+bigDigits(512))
+
+	v, err = env.Evaluate("pi")
+	require.NoError(t, err)
+	assert.Equal(t, "3.14159265358979323846264338327950288419716939937510", FormatValue(v)[:52])
+
+	assert.Error(t, env.SetPrecision(0))
+	assert.Error(t, env.SetPrecision(MaxPrecision+1))
+}
+
+// TestFloatModulo 测试 float 模式的取模不再截断为整数
+func TestFloatModulo(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   float64
+	}{
+		{"7.5 % 2", 1.5},
+		{"1 % 0.5", 0},
+		{"-7 % 3", -1},
+		{"10 % 3", 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			result, err := Calculate(test.expression)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+// TestModeErrors 测试高精度模式下的错误
+func TestModeErrors(t *testing.T) {
+	for _, mode := range []Mode{ModeBig, ModeRat} {
+		for _, expression := range []string{"1/0", "1%0", "0^-1", "1.5 & 1", "1 << -1", "sqrt(-4)"} {
+			t.Run(mode.String()+" "+expression, func(t *testing.T) {
+				env := NewEnv()
+				require.NoError(t, env.SetMode(mode))
+				_, err := env.Evaluate(expression)
+				assert.Error(t, err)
+			})
+		}
+	}
+}
+
+// TestIntegerPower 测试高精度模式下整数次幂不再退回 float64
+func TestIntegerPower(t *testing.T) {
+	tests := []struct {
+		mode       Mode
+		expression string
+		expected   string
+		desc       string
+	}{
+		{ModeRat, "2^-20000 > 0", "1", "极小的正数不为零"},
+		{ModeRat, "2^-20000 == 0", "0", "极小的正数不等于零"},
+		{ModeRat, "2^20000 / 2^19999", "2", "超过 float64 范围的幂"},
+		{ModeRat, "2^524288 > 0", "1", "刚好在位数上限内"},
+		{ModeRat, "1^(10^30)", "1", "1 的巨大次幂"},
+		{ModeRat, "(-1)^(10^30 + 1)", "-1", "-1 的巨大奇数次幂"},
+		{ModeRat, "0^(10^30)", "0", "0 的巨大次幂"},
+		{ModeBig, "2^-20000 > 0", "1", "极小的正数不为零"},
+		{ModeBig, "2^20000 / 2^19999", "2", "超过 float64 范围的幂"},
+		{ModeBig, "(-1)^(10^30)", "1", "-1 的巨大偶数次幂"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode.String()+" "+test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, evaluateIn(t, test.mode, test.expression))
+		})
+	}
+
+	for _, test := range []struct {
+		mode       Mode
+		expression string
+		desc       string
+	}{
+		{ModeRat, "2^524289", "刚好超过位数上限"},
+		{ModeRat, "2^-524289", "负指数刚好超过位数上限"},
+		{ModeRat, "2^(2^70)", "指数超出 int64"},
+		{ModeBig, "2^(2^70)", "指数超出 int64"},
+		{ModeBig, "2^(2^40)", "结果超出 big.Float 指数范围"},
+	} {
+		t.Run(test.mode.String()+" "+test.desc, func(t *testing.T) {
+			env := NewEnv()
+			require.NoError(t, env.SetMode(test.mode))
+			_, err := env.Evaluate(test.expression)
+			assert.ErrorIs(t, err, ErrOutOfRange)
+		})
+	}
+}
+
+// TestMixedValues 测试不同表示的数值混合运算
+func TestMixedValues(t *testing.T) {
+	env := NewEnv()
+	env.SetValue("third", Rat{big.NewRat(1, 3)})
+
+	// float 模式下与精确数值运算，结果保持精确
+	v, err := env.Evaluate("third * 3 + 0.5")
+	require.NoError(t, err)
+	assert.Equal(t, "1.5", FormatValue(v))
+	_, isRat := v.(Rat)
+	assert.True(t, isRat)
+
+	// 切换模式后已有变量仍可使用
+	require.NoError(t, env.SetMode(ModeBig))
+	v, err = env.Evaluate("third * 3")
+	require.NoError(t, err)
+	assert.Equal(t, "1", FormatValue(v))
+}
+
+// TestParseMode 测试模式名称解析
+func TestParseMode(t *testing.T) {
+	for _, mode := range []Mode{ModeFloat, ModeBig, ModeRat} {
+		parsed, err := ParseMode(mode.String())
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+	parsed, err := ParseMode("RAT")
+	require.NoError(t, err)
+	assert.Equal(t, ModeRat, parsed)
+
+	_, err = ParseMode("decimal")
+	assert.Error(t, err)
+}
+
+// TestModeOf 测试数值类型对应的模式
+func TestModeOf(t *testing.T) {
+	assert.Equal(t, ModeFloat, ModeOf(Float(1)))
+	assert.Equal(t, ModeBig, ModeOf(NewBigFloat(1, DefaultPrecision)))
+	assert.Equal(t, ModeRat, ModeOf(NewRat(1, 3)))
+}
+
+// TestFormatValue 测试各类数值的格式化
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		value    Value
+		expected string
+		desc     string
+	}{
+		{Float(2.5), "2.5", "浮点数"},
+		{NewRat(-7, 2), "-3.5", "有限小数"},
+		{NewRat(2, 3), "2/3", "分数"},
+		{NewRat(1, 40), "0.025", "分母含 2 和 5"},
+		{NewRat(4, 1), "4", "整数"},
+		{NewBigFloat(0.5, 64), "0.5", "BigFloat 小数"},
+		{NewBigFloat(1e20, 128), "100000000000000000000", "BigFloat 整数"},
+		{NewRat(-3, 1250), "-0.0024", "分母中 5 多于 2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, FormatValue(test.value))
+		})
+	}
+}
+
+// TestFormatLongRat 测试小数位数很多的有理数的格式化
+func TestFormatLongRat(t *testing.T) {
+	pow := func(base, n int64) *big.Int {
+		return new(big.Int).Exp(big.NewInt(base), big.NewInt(n), nil)
+	}
+
+	// 刚好不超过小数位数上限时按十进制展开
+	r := new(big.Rat).SetFrac(big.NewInt(1), pow(2, maxDecimalDigits))
+	assert.Equal(t, r.FloatString(maxDecimalDigits), FormatValue(Rat{r}))
+
+	// 超过上限时输出精确的科学计数法
+	r = new(big.Rat).SetFrac(big.NewInt(-1), pow(10, maxDecimalDigits+1))
+	assert.Equal(t, "-1e-1001", FormatValue(Rat{r}))
+	r = new(big.Rat).SetFrac(big.NewInt(3), new(big.Int).Lsh(pow(10, maxDecimalDigits+1), 1))
+	assert.Equal(t, "1.5e-1001", FormatValue(Rat{r}))
+
+	// 有效数字也过多时输出分数
+	r = new(big.Rat).SetFrac(big.NewInt(1), pow(2, 4*maxDecimalDigits))
+	assert.Equal(t, r.RatString(), FormatValue(Rat{r}))
+
+	assert.Equal(t, "1e-999999", evaluateIn(t, ModeRat, "1e-999999"))
+	assert.Equal(t, "-2.5e-999999", evaluateIn(t, ModeRat, "-25e-1000000"))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cli_cmd/calculator"
+
+	"github.com/urfave/cli/v2"
+)
+
+// builtinCases 内置用例，与 calculator 包的 go test 共用
+//
+//go:embed calculator/testdata/cases
+var builtinCases embed.FS
+
+const builtinCasesDir = "calculator/testdata/cases"
+
+// testCommand 运行用例文件，没有指定目录时运行内置用例
+//
+// 参数可以是目录或单个 .calc 文件；有用例未通过时返回错误，以非零状态退出。
+func testCommand(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return errors.New(tr("cases.one_arg"))
+	}
+	env, err := newEnv(c)
+	if err != nil {
+		return err
+	}
+
+	fsys, dir, source := fs.FS(builtinCases), builtinCasesDir, tr("cases.builtin")
+	if c.NArg() == 1 {
+		source = c.Args().First()
+		info, err := os.Stat(source)
+		if err != nil {
+			return errors.New(tr("cases.open_failed", err))
+		}
+		fsys, dir = os.DirFS(source), "."
+		if !info.IsDir() {
+			fsys, dir = os.DirFS(filepath.Dir(source)), filepath.Base(source)
+		}
+	}
+	files, err := calculator.LoadCaseFiles(fsys, dir)
+	if err != nil {
+		return errors.New(tr("cases.load_failed", err))
+	}
+	if len(files) == 0 {
+		return errors.New(tr("cases.none", source, calculator.CaseExt))
+	}
+
+	fmt.Printf("%s\n\n", tr("cases.running", source, len(files)))
+	reports := calculator.RunCaseFiles(files, env.Registry())
+	total, failed := printCaseReports(reports, newPalette(c.Bool("no-color")))
+
+	fmt.Print("\n" + tr("cases.summary", total-failed, total))
+	if failed > 0 {
+		fmt.Println(tr("cases.failed_summary", failed))
+		return errors.New(tr("cases.failed", failed))
+	}
+	fmt.Println(tr("cases.all_passed"))
+	return nil
+}
+
+// printCaseReports 逐个文件打印结果，未通过的用例按 diff 的格式打印期望（-）和实际结果（+）
+//
+// 返回用例总数和未通过的用例数。
+func printCaseReports(reports []calculator.CaseReport, colors palette) (total, failed int) {
+	for _, r := range reports {
+		n := len(r.File.Cases)
+		total += n
+		failed += len(r.Failures)
+		if r.Passed() {
+			fmt.Println(tr("cases.file_passed", r.File.Name, n, r.Elapsed.Round(time.Microsecond)))
+			continue
+		}
+		fmt.Println(tr("cases.file_failed", r.File.Name, len(r.Failures), n))
+		for _, f := range r.Failures {
+			fmt.Printf("   --- %s:%d\n", r.File.Name, f.Line)
+			fmt.Printf("   %s\n", strings.TrimSpace(f.Text))
+			fmt.Println(colors.err("   - " + f.Expected()))
+			fmt.Println(colors.result("   + " + f.Got))
+		}
+	}
+	return total, failed
+}
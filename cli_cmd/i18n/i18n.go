@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Lang 消息的语言
+type Lang string
+
+// 支持的语言
+const (
+	Chinese Lang = "zh"
+	English Lang = "en"
+)
+
+// Default 没有指定语言或语言不受支持时使用的语言
+const Default = Chinese
+
+// Langs 返回支持的语言
+func Langs() []Lang {
+	return []Lang{Chinese, English}
+}
+
+// Parse 解析语言名称，接受 zh、en 以及 zh_CN.UTF-8、en-US 这样的区域设置
+func Parse(s string) (Lang, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, lang := range Langs() {
+		code := string(lang)
+		if s == code || strings.HasPrefix(s, code+"_") || strings.HasPrefix(s, code+"-") || strings.HasPrefix(s, code+".") {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// Detect 按 LC_ALL、LC_MESSAGES、LANG 的顺序检测语言
+//
+// 使用第一个非空的变量；其中的语言不受支持（例如 C、POSIX）时返回 Default。
+func Detect(getenv func(string) string) Lang {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := getenv(name); v != "" {
+			if lang, ok := Parse(v); ok {
+				return lang
+			}
+			return Default
+		}
+	}
+	return Default
+}
+
+var current atomic.Value
+
+// Current 返回当前语言，错误信息等没有指定语言的消息使用当前语言
+func Current() Lang {
+	if lang, ok := current.Load().(Lang); ok {
+		return lang
+	}
+	return Default
+}
+
+// SetCurrent 设置当前语言
+func SetCurrent(lang Lang) {
+	current.Store(lang)
+}
+
+// Localizer 可以按语言输出的值，作为 Catalog.Sprintf 的参数时按同一语言输出
+type Localizer interface {
+	Localize(lang Lang) string
+}
+
+// Text 按语言输出 v：Localizer 按 lang 输出，其他值按 fmt.Sprint 输出
+func Text(lang Lang, v interface{}) string {
+	if l, ok := v.(Localizer); ok {
+		return l.Localize(lang)
+	}
+	return fmt.Sprint(v)
+}
+
+// Catalog 消息目录，键为消息的标识，值为各语言的格式字符串
+type Catalog map[string]map[Lang]string
+
+// Format 返回 key 在 lang 中的格式字符串，没有该语言时使用 Default，没有该消息时返回 key
+func (c Catalog) Format(lang Lang, key string) string {
+	texts, ok := c[key]
+	if !ok {
+		return key
+	}
+	if text, ok := texts[lang]; ok {
+		return text
+	}
+	return texts[Default]
+}
+
+// Sprintf 按 lang 的格式字符串格式化消息，Localizer 参数按同一语言输出
+func (c Catalog) Sprintf(lang Lang, key string, args ...interface{}) string {
+	format := c.Format(lang, key)
+	if len(args) == 0 {
+		return format
+	}
+	localized := make([]interface{}, len(args))
+	for i, arg := range args {
+		if l, ok := arg.(Localizer); ok {
+			arg = l.Localize(lang)
+		}
+		localized[i] = arg
+	}
+	return fmt.Sprintf(format, localized...)
+}
@@ -0,0 +1,85 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParse 测试语言名称和区域设置的解析
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Lang
+		ok       bool
+		desc     string
+	}{
+		{"zh", Chinese, true, "语言代码"},
+		{"en", English, true, "英文"},
+		{"zh_CN.UTF-8", Chinese, true, "区域设置"},
+		{"en-US", English, true, "连字符"},
+		{"EN_gb", English, true, "忽略大小写"},
+		{"en.UTF-8", English, true, "只有编码"},
+		{"C", "", false, "C 区域"},
+		{"english", "", false, "不支持语言全称"},
+		{"", "", false, "空字符串"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			lang, ok := Parse(test.input)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, lang)
+		})
+	}
+}
+
+// TestDetect 测试从环境变量检测语言
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		env      map[string]string
+		expected Lang
+		desc     string
+	}{
+		{map[string]string{}, Default, "没有设置"},
+		{map[string]string{"LANG": "en_US.UTF-8"}, English, "LANG"},
+		{map[string]string{"LANG": "zh_CN.UTF-8", "LC_MESSAGES": "en_US"}, English, "LC_MESSAGES 优先于 LANG"},
+		{map[string]string{"LC_ALL": "zh_CN", "LC_MESSAGES": "en_US"}, Chinese, "LC_ALL 优先"},
+		{map[string]string{"LC_ALL": "C", "LANG": "en_US"}, Default, "第一个非空的变量不受支持时使用默认语言"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, Detect(func(name string) string { return test.env[name] }))
+		})
+	}
+}
+
+type word map[Lang]string
+
+func (w word) Localize(lang Lang) string { return w[lang] }
+
+// TestCatalog 测试消息的查找、回退和参数的本地化
+func TestCatalog(t *testing.T) {
+	c := Catalog{
+		"greet":   {Chinese: "你好, %v", English: "hello, %v"},
+		"zh_only": {Chinese: "只有中文"},
+	}
+	apple := word{Chinese: "苹果", English: "apple"}
+
+	assert.Equal(t, "hello, apple", c.Sprintf(English, "greet", apple))
+	assert.Equal(t, "你好, 苹果", c.Sprintf(Chinese, "greet", apple))
+	assert.Equal(t, "hello, 42", c.Sprintf(English, "greet", 42))
+	assert.Equal(t, "只有中文", c.Sprintf(English, "zh_only"), "缺少的语言使用默认语言")
+	assert.Equal(t, "missing", c.Sprintf(English, "missing"), "缺少的消息返回键")
+	assert.Equal(t, "apple", Text(English, apple))
+	assert.Equal(t, "3", Text(English, 3))
+}
+
+// TestCurrent 测试当前语言
+func TestCurrent(t *testing.T) {
+	assert.Equal(t, Default, Current())
+	SetCurrent(English)
+	defer SetCurrent(Default)
+	assert.Equal(t, English, Current())
+}
@@ -1,20 +1,27 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 
 	"cli_cmd/calculator"
+	"cli_cmd/i18n"
 
 	"github.com/urfave/cli/v2"
 )
 
 func main() {
+	// 标志和子命令的说明在创建 app 时按当前语言生成
+	i18n.SetCurrent(detectLang(os.Args[1:], os.Getenv))
+
 	app := &cli.App{
 		Name:    "calc",
-		Usage:   "一个简单的命令行计算器",
+		Usage:   tr("app.usage"),
 		Version: "1.0.0",
 		Authors: []*cli.Author{
 			{Name: "Calculator CLI Team"},
@@ -25,15 +32,76 @@ func main() {
 			&cli.BoolFlag{
 				Name:    "interactive",
 				Aliases: []string{"i"},
-				Usage:   "启动交互模式",
+				Usage:   tr("flag.interactive"),
 				Value:   false,
 			},
 			&cli.BoolFlag{
 				Name:    "verbose",
 				Aliases: []string{"V"}, // 改为大写V避免与默认version标志冲突
-				Usage:   "显示详细输出",
+				Usage:   tr("flag.verbose"),
 				Value:   false,
 			},
+			&cli.StringFlag{
+				Name:    "mode",
+				Aliases: []string{"m"},
+				Usage:   tr("flag.mode"),
+				Value:   "float",
+			},
+			&cli.UintFlag{
+				Name:    "precision",
+				Aliases: []string{"p"},
+				Usage:   tr("flag.precision"),
+				Value:   calculator.DefaultPrecision,
+			},
+			&cli.StringFlag{
+				Name:    "unit",
+				Aliases: []string{"u"},
+				Usage:   tr("flag.unit"),
+			},
+			&cli.IntFlag{
+				Name:  "base",
+				Usage: tr("flag.base"),
+				Value: 10,
+			},
+			&cli.BoolFlag{
+				Name:  "hex",
+				Usage: tr("flag.hex"),
+			},
+			&cli.BoolFlag{
+				Name:  "sci",
+				Usage: tr("flag.sci"),
+			},
+			&cli.StringSliceFlag{
+				Name:  "units",
+				Usage: tr("flag.units"),
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: tr("flag.no_color"),
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   tr("flag.output"),
+				Value:   outputPlain,
+			},
+			&cli.StringFlag{
+				Name:  "lang",
+				Usage: tr("flag.lang", langNames()),
+			},
+		},
+
+		// 校验 --lang，语言已经在创建 app 之前确定
+		Before: func(c *cli.Context) error {
+			if !c.IsSet("lang") {
+				return nil
+			}
+			lang, ok := i18n.Parse(c.String("lang"))
+			if !ok {
+				return errors.New(tr("lang.unknown", c.String("lang"), langNames()))
+			}
+			i18n.SetCurrent(lang)
+			return nil
 		},
 
 		// 默认动作 - 处理单个表达式或启动交互模式
@@ -41,16 +109,29 @@ func main() {
 			interactive := c.Bool("interactive")
 			verbose := c.Bool("verbose")
 
+			env, err := newEnv(c)
+			if err != nil {
+				return err
+			}
+			opts, err := formatOptions(c)
+			if err != nil {
+				return err
+			}
+
 			// 获取命令行参数（表达式）
 			args := c.Args().Slice()
 
 			if interactive || len(args) == 0 {
-				return runInteractiveMode(verbose)
+				return runInteractiveMode(env, verbose, opts, newPalette(c.Bool("no-color")))
 			}
 
 			// 将所有参数连接成一个表达式
-			expression := strings.Join(args, "")
-			return calculateAndPrint(expression, verbose)
+			expression := strings.Join(args, " ")
+			out, err := newRecordWriter(c, os.Stdout)
+			if err != nil {
+				return err
+			}
+			return calculateAndPrint(env, expression, verbose, opts, out)
 		},
 
 		// 子命令
@@ -58,41 +139,92 @@ func main() {
 			{
 				Name:      "eval",
 				Aliases:   []string{"e"},
-				Usage:     "计算单个表达式",
+				Usage:     tr("cmd.eval"),
 				ArgsUsage: "EXPRESSION",
 				Action: func(c *cli.Context) error {
 					verbose := c.Bool("verbose") // 从全局标志获取
 					args := c.Args().Slice()
 
 					if len(args) == 0 {
-						return fmt.Errorf("请提供一个数学表达式")
+						return errors.New(tr("calc.no_expression"))
+					}
+
+					env, err := newEnv(c)
+					if err != nil {
+						return err
+					}
+					opts, err := formatOptions(c)
+					if err != nil {
+						return err
 					}
 
-					expression := strings.Join(args, "")
-					return calculateAndPrint(expression, verbose)
+					expression := strings.Join(args, " ")
+					out, err := newRecordWriter(c, os.Stdout)
+					if err != nil {
+						return err
+					}
+					return calculateAndPrint(env, expression, verbose, opts, out)
 				},
 			},
 			{
 				Name:    "interactive",
 				Aliases: []string{"repl", "shell"},
-				Usage:   "启动交互式计算器",
+				Usage:   tr("cmd.interactive"),
 				Action: func(c *cli.Context) error {
 					verbose := c.Bool("verbose")
-					return runInteractiveMode(verbose)
+					env, err := newEnv(c)
+					if err != nil {
+						return err
+					}
+					opts, err := formatOptions(c)
+					if err != nil {
+						return err
+					}
+					return runInteractiveMode(env, verbose, opts, newPalette(c.Bool("no-color")))
 				},
 			},
 			{
-				Name:  "test",
-				Usage: "运行内置测试用例",
-				Action: func(c *cli.Context) error {
-					return runBuiltinTests()
-				},
+				Name:      "run",
+				Usage:     tr("cmd.run"),
+				ArgsUsage: "FILE",
+				Flags:     []cli.Flag{continueOnErrorFlag()},
+				Action:    runCommand,
+			},
+			{
+				Name:   "batch",
+				Usage:  tr("cmd.batch"),
+				Flags:  []cli.Flag{continueOnErrorFlag()},
+				Action: batchCommand,
+			},
+			{
+				Name:      "simplify",
+				Usage:     tr("cmd.simplify"),
+				ArgsUsage: "EXPRESSION",
+				Action:    simplifyCommand,
+			},
+			{
+				Name:      "diff",
+				Usage:     tr("cmd.diff"),
+				ArgsUsage: "EXPRESSION [VARIABLE]",
+				Action:    diffCommand,
+			},
+			{
+				Name:   "serve",
+				Usage:  tr("cmd.serve"),
+				Flags:  serveFlags(),
+				Action: serveCommand,
+			},
+			{
+				Name:      "test",
+				Usage:     tr("cmd.test"),
+				ArgsUsage: "[DIR|FILE]",
+				Action:    testCommand,
 			},
 		},
 
 		// 使用错误处理
 		OnUsageError: func(c *cli.Context, err error, isSubcommand bool) error {
-			fmt.Fprintf(c.App.ErrWriter, "错误: %v\n\n", err)
+			fmt.Fprintf(c.App.ErrWriter, "%s\n\n", tr("app.usage_error", err))
 			if !isSubcommand {
 				cli.ShowAppHelp(c)
 			}
@@ -101,56 +233,139 @@ func main() {
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "应用程序错误: %v\n", err)
+		fmt.Fprintln(os.Stderr, tr("app.error", err))
 		os.Exit(1)
 	}
 }
 
-// calculateAndPrint 计算表达式并打印结果
-func calculateAndPrint(expression string, verbose bool) error {
-	if verbose {
-		fmt.Printf("正在计算表达式: %s\n", expression)
+// newEnv 根据全局标志创建计算环境
+func newEnv(c *cli.Context) (*calculator.Env, error) {
+	mode, err := calculator.ParseMode(c.String("mode"))
+	if err != nil {
+		return nil, err
 	}
+	registry := calculator.DefaultRegistry
+	if files := c.StringSlice("units"); len(files) > 0 {
+		registry = registry.Clone()
+		for _, file := range files {
+			if err := registry.LoadUnits(file); err != nil {
+				return nil, fmt.Errorf("%s: %w", tr("units.load_failed"), err)
+			}
+		}
+	}
+	env := calculator.NewEnvWithRegistry(registry)
+	if err := env.SetMode(mode); err != nil {
+		return nil, err
+	}
+	if err := env.SetPrecision(c.Uint("precision")); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
 
-	result, err := calculator.Calculate(expression)
-	if err != nil {
-		return fmt.Errorf("计算错误: %v", err)
+// formatOptions 根据全局标志创建输出选项
+func formatOptions(c *cli.Context) (calculator.FormatOptions, error) {
+	opts := calculator.FormatOptions{Unit: c.String("unit")}
+	if opts.Unit != "" && !calculator.IsEtherUnit(opts.Unit) {
+		return opts, errors.New(tr("format.unknown_unit", opts.Unit, joinList(calculator.EtherUnits())))
 	}
+	opts.Base = c.Int("base")
+	if c.Bool("hex") {
+		if c.IsSet("base") && opts.Base != 16 {
+			return opts, errors.New(tr("format.hex_conflict", opts.Base))
+		}
+		opts.Base = 16
+	}
+	switch opts.Base {
+	case 2, 8, 10, 16:
+	default:
+		return opts, errors.New(tr("format.base", opts.Base))
+	}
+	opts.Sci = c.Bool("sci")
+	// 机器可读的输出保留 float64 的完整精度
+	switch c.String("output") {
+	case outputJSON, outputCSV:
+		opts.FullPrecision = true
+	}
+	if opts.Sci && opts.Base != 10 {
+		return opts, errors.New(tr("format.sci"))
+	}
+	return opts, nil
+}
 
+// calculateAndPrint 计算表达式并按输出格式打印结果
+//
+// 详细模式只在 plain 输出格式下生效。
+func calculateAndPrint(env *calculator.Env, expression string, verbose bool, opts calculator.FormatOptions, out recordWriter) error {
+	_, plain := out.(plainWriter)
+	verbose = verbose && plain
 	if verbose {
-		fmt.Printf("表达式: %s\n", expression)
-		fmt.Printf("结果: %s\n", calculator.FormatResult(result))
-	} else {
-		fmt.Println(calculator.FormatResult(result))
+		fmt.Println(tr("calc.evaluating", expression, env.Mode()))
 	}
 
-	return nil
+	rec := newRecord(context.Background(), env.Exec(calculator.Statement{Text: expression}), opts)
+
+	switch {
+	case rec.err != nil:
+		if err := out.Write(rec); err != nil {
+			return err
+		}
+		if rec.Code == calculator.CodeFormat {
+			return errors.New(tr("calc.output_error", rec.err))
+		}
+		return errors.New(tr("calc.eval_error", describeError(rec.err)))
+	case verbose:
+		fmt.Println(tr("calc.expression", expression))
+		fmt.Println(tr("calc.result", rec.Result))
+		return nil
+	}
+	return out.Write(rec)
+}
+
+// describeError 返回错误描述，语法错误附带输入和指向出错位置的 ^ 标记
+func describeError(err error) string {
+	var syntaxErr *calculator.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err.Error()
+	}
+	lines := strings.Split(syntaxErr.Snippet(), "\n")
+	for i, line := range lines {
+		lines[i] = "   " + line
+	}
+	return err.Error() + "\n" + strings.Join(lines, "\n")
 }
 
 // runInteractiveMode 运行交互模式
-func runInteractiveMode(verbose bool) error {
-	fmt.Println("🧮 欢迎使用命令行计算器!")
-	fmt.Println("支持的操作: +, -, *, /, %, ()")
-	fmt.Println("输入 'help' 查看帮助，'exit' 或 'quit' 退出")
-	fmt.Println("示例: 1+2*3, (1+2)*3, 10%3")
+func runInteractiveMode(env *calculator.Env, verbose bool, opts calculator.FormatOptions, colors palette) error {
+	fmt.Println(tr("repl.banner"))
 	fmt.Println(strings.Repeat("-", 50))
 
-	reader := bufio.NewReader(os.Stdin)
+	reader := newLineReader(env)
+	defer func() {
+		if err := reader.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}()
 
 	for {
-		fmt.Print("calc> ")
-
-		input, err := reader.ReadString('\n')
+		input, err := reader.ReadLine("calc> ")
+		if err == io.EOF {
+			fmt.Println("\n" + tr("repl.bye"))
+			return nil
+		}
 		if err != nil {
-			return fmt.Errorf("读取输入失败: %v", err)
+			return errors.New(tr("repl.read_failed", err))
 		}
 
 		input = strings.TrimSpace(input)
+		if input != "" {
+			reader.AddHistory(input)
+		}
 
 		// 处理特殊命令
 		switch strings.ToLower(input) {
 		case "exit", "quit", "q":
-			fmt.Println("再见! 👋")
+			fmt.Println(tr("repl.bye"))
 			return nil
 		case "help", "h":
 			printHelp()
@@ -159,102 +374,122 @@ func runInteractiveMode(verbose bool) error {
 			// 清屏
 			fmt.Print("\033[H\033[2J")
 			continue
+		case "vars":
+			printVars(env)
+			continue
+		case "funcs":
+			printFuncs(env)
+			continue
 		case "":
 			continue
 		}
 
+		if cmd, args, ok := argCommand(input); ok {
+			switch cmd {
+			case "unset":
+				unsetVars(env, args)
+			case "mode":
+				setMode(env, args)
+			}
+			continue
+		}
+
 		// 计算表达式
 		if verbose {
-			fmt.Printf("正在计算: %s\n", input)
+			fmt.Println(tr("repl.evaluating", input))
 		}
 
-		result, err := calculator.Calculate(input)
-		if err != nil {
-			fmt.Printf("❌ 错误: %v\n", err)
+		res := env.Exec(calculator.Statement{Text: input})
+		if res.Err != nil {
+			fmt.Println(colors.err(tr("repl.error", describeError(res.Err))))
+			continue
+		}
+		if res.Func != nil {
+			fmt.Println(tr("repl.defined", res.Func))
 			continue
 		}
 
-		formattedResult := calculator.FormatResult(result)
-		fmt.Printf("📊 %s = %s\n", input, formattedResult)
+		formattedResult, err := calculator.Format(res.Value, opts)
+		if err != nil {
+			fmt.Println(colors.err(tr("repl.error", err)))
+			continue
+		}
+		if node, err := calculator.Parse(input); err == nil {
+			if assign, ok := node.(*calculator.AssignExpr); ok {
+				input = assign.Name.Name
+			}
+		}
+		fmt.Printf("📊 %s = %s\n", input, colors.result(formattedResult))
 	}
 }
 
-// printHelp 打印帮助信息
-func printHelp() {
-	fmt.Println("\n📖 计算器帮助:")
-	fmt.Println("  支持的运算符:")
-	fmt.Println("    +  : 加法 (例: 1+2)")
-	fmt.Println("    -  : 减法 (例: 5-3)")
-	fmt.Println("    *  : 乘法 (例: 2*3)")
-	fmt.Println("    /  : 除法 (例: 8/2)")
-	fmt.Println("    %  : 取模 (例: 10%3)")
-	fmt.Println("    () : 括号 (例: (1+2)*3)")
-	fmt.Println("\n  特殊命令:")
-	fmt.Println("    help  : 显示此帮助")
-	fmt.Println("    clear : 清屏")
-	fmt.Println("    exit  : 退出程序")
-	fmt.Println("\n  运算优先级:")
-	fmt.Println("    1. 括号 ()")
-	fmt.Println("    2. 乘法 * 除法 / 取模 %")
-	fmt.Println("    3. 加法 + 减法 -")
-	fmt.Println()
+// printVars 打印当前会话中的所有变量
+func printVars(env *calculator.Env) {
+	names := env.Names()
+	if len(names) == 0 {
+		fmt.Println(tr("repl.no_vars"))
+		return
+	}
+	for _, name := range names {
+		value, _ := env.GetValue(name)
+		fmt.Printf("  %s = %s\n", name, calculator.FormatValue(value))
+	}
 }
 
-// runBuiltinTests 运行内置测试
-func runBuiltinTests() error {
-	fmt.Println("🧪 运行内置测试用例...")
-
-	testCases := []struct {
-		expression string
-		expected   string
-		desc       string
-	}{
-		{"1+2", "3", "基本加法"},
-		{"1+2*2", "5", "运算优先级"},
-		{"2*3+1", "7", "乘法优先级"},
-		{"(1+2)*3", "9", "括号优先级"},
-		{"10/2", "5", "除法"},
-		{"10%3", "1", "取模运算"},
-		{"5-3", "2", "减法"},
-		{"-5+3", "-2", "负数"},
-		{"2*3*4", "24", "连续乘法"},
-		{"100/10/2", "5", "连续除法"},
-		{"1+2+3+4", "10", "连续加法"},
-		{"10-3-2", "5", "连续减法"},
-		{"2*(3+4)", "14", "括号内加法"},
-		{"(10-6)/2", "2", "括号内减法"},
-		{"3.5+1.5", "5", "小数计算"},
-		{"7.5/2.5", "3", "小数除法"},
+// printFuncs 打印当前会话中定义的函数
+func printFuncs(env *calculator.Env) {
+	funcs := env.UserFuncs()
+	if len(funcs) == 0 {
+		fmt.Println(tr("repl.no_funcs"))
+		return
 	}
+	for _, fn := range funcs {
+		fmt.Printf("  %s\n", fn)
+	}
+}
 
-	passed := 0
-	total := len(testCases)
-
-	fmt.Printf("总共 %d 个测试用例:\n\n", total)
-
-	for i, tc := range testCases {
-		result, err := calculator.Calculate(tc.expression)
+// setMode 查看或切换计算模式
+func setMode(env *calculator.Env, args []string) {
+	if len(args) == 0 {
+		fmt.Println(tr("repl.mode", env.Mode(), env.Precision()))
+		return
+	}
+	mode, err := calculator.ParseMode(args[0])
+	if err != nil {
+		fmt.Println(tr("repl.error", err))
+		return
+	}
+	if len(args) > 1 {
+		prec, err := strconv.ParseUint(args[1], 10, 32)
 		if err != nil {
-			fmt.Printf("❌ 测试 %d: %s - 错误: %v\n", i+1, tc.desc, err)
-			continue
+			fmt.Println(tr("repl.invalid_precision", args[1]))
+			return
 		}
-
-		formattedResult := calculator.FormatResult(result)
-		if formattedResult == tc.expected {
-			fmt.Printf("✅ 测试 %d: %s - %s = %s\n", i+1, tc.desc, tc.expression, formattedResult)
-			passed++
-		} else {
-			fmt.Printf("❌ 测试 %d: %s - %s = %s (期望: %s)\n",
-				i+1, tc.desc, tc.expression, formattedResult, tc.expected)
+		if err := env.SetPrecision(uint(prec)); err != nil {
+			fmt.Println(tr("repl.error", err))
+			return
 		}
 	}
+	env.SetMode(mode)
+	fmt.Println(tr("repl.mode_switched", mode))
+}
 
-	fmt.Printf("\n📊 测试结果: %d/%d 通过", passed, total)
-	if passed == total {
-		fmt.Println(" 🎉 所有测试通过!")
-	} else {
-		fmt.Printf(" ⚠️  有 %d 个测试失败\n", total-passed)
+// unsetVars 删除指定的变量或函数
+func unsetVars(env *calculator.Env, names []string) {
+	if len(names) == 0 {
+		fmt.Println(tr("repl.unset_usage"))
+		return
+	}
+	for _, name := range names {
+		removedVar := env.Unset(name)
+		removedFunc := env.Undefine(name)
+		if !removedVar && !removedFunc {
+			fmt.Println(tr("repl.not_found", name))
+		}
 	}
+}
 
-	return nil
+// printHelp 打印帮助信息
+func printHelp() {
+	fmt.Println(tr("repl.help"))
 }
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"strings"
+
+	"cli_cmd/i18n"
+)
+
+// tr 按当前语言格式化消息，key 是 messages 中的键
+func tr(key string, args ...interface{}) string {
+	return messages.Sprintf(i18n.Current(), key, args...)
+}
+
+// joinList 按当前语言的习惯用顿号或逗号连接各项
+func joinList(items []string) string {
+	return strings.Join(items, tr("list.separator"))
+}
+
+// detectLang 确定界面语言：命令行中的 --lang 优先，否则按 LC_ALL、LC_MESSAGES、LANG 检测
+//
+// 标志和子命令的说明在创建 cli.App 时就要确定，因此在解析命令行之前先扫描 --lang；
+// 无效的 --lang 在这里忽略，由 Before 报告错误。
+func detectLang(args []string, getenv func(string) string) i18n.Lang {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		var value string
+		switch {
+		case arg == "--lang" || arg == "-lang":
+			if i+1 >= len(args) {
+				continue
+			}
+			value = args[i+1]
+		case strings.HasPrefix(arg, "--lang="):
+			value = strings.TrimPrefix(arg, "--lang=")
+		case strings.HasPrefix(arg, "-lang="):
+			value = strings.TrimPrefix(arg, "-lang=")
+		default:
+			continue
+		}
+		if lang, ok := i18n.Parse(value); ok {
+			return lang
+		}
+	}
+	return i18n.Detect(getenv)
+}
+
+// langNames 返回支持的语言，用于帮助和错误信息
+func langNames() string {
+	names := make([]string, 0, len(i18n.Langs()))
+	for _, lang := range i18n.Langs() {
+		names = append(names, string(lang))
+	}
+	return joinList(names)
+}
+
+// messages 命令行界面的消息目录
+var messages = i18n.Catalog{
+	"list.separator": {i18n.Chinese: "、", i18n.English: ", "},
+
+	// 应用和标志
+	"app.usage":              {i18n.Chinese: "一个简单的命令行计算器", i18n.English: "a simple command-line calculator"},
+	"app.error":              {i18n.Chinese: "应用程序错误: %v", i18n.English: "error: %v"},
+	"app.usage_error":        {i18n.Chinese: "错误: %v", i18n.English: "error: %v"},
+	"flag.interactive":       {i18n.Chinese: "启动交互模式", i18n.English: "start interactive mode"},
+	"flag.verbose":           {i18n.Chinese: "显示详细输出", i18n.English: "show verbose output"},
+	"flag.mode":              {i18n.Chinese: "计算模式: float（双精度浮点）、big（任意精度浮点）、rat（精确有理数）", i18n.English: "calculation mode: float (double precision), big (arbitrary precision float), rat (exact rational)"},
+	"flag.precision":         {i18n.Chinese: "big 模式的精度（二进制位数）", i18n.English: "precision of big mode (bits)"},
+	"flag.unit":              {i18n.Chinese: "把结果视为 wei 并按指定以太坊单位输出，例如 gwei、ether", i18n.English: "treat the result as wei and print it in the given Ethereum unit, e.g. gwei, ether"},
+	"flag.base":              {i18n.Chinese: "整数结果的输出进制: 2、8、10、16（分别以 0b、0o、0x 开头）", i18n.English: "output base of integer results: 2, 8, 10, 16 (prefixed with 0b, 0o, 0x)"},
+	"flag.hex":               {i18n.Chinese: "以 0x 开头的十六进制输出整数结果，等同于 --base=16", i18n.English: "print integer results in hexadecimal with 0x, same as --base=16"},
+	"flag.sci":               {i18n.Chinese: "以科学计数法输出结果，例如 1.5e18", i18n.English: "print results in scientific notation, e.g. 1.5e18"},
+	"flag.units":             {i18n.Chinese: "从 YAML 或 JSON 文件加载自定义单位和汇率，可以重复指定", i18n.English: "load custom units and exchange rates from a YAML or JSON file, can be repeated"},
+	"flag.no_color":          {i18n.Chinese: "交互模式不使用颜色（也可以设置 NO_COLOR 环境变量）", i18n.English: "disable colors in interactive mode (or set the NO_COLOR environment variable)"},
+	"flag.output":            {i18n.Chinese: "输出格式: plain（只输出结果）、json（每行一个 JSON 对象）、csv", i18n.English: "output format: plain (results only), json (one JSON object per line), csv"},
+	"flag.lang":              {i18n.Chinese: "界面和错误信息的语言: %s，默认按 LC_ALL、LC_MESSAGES、LANG 环境变量检测", i18n.English: "language of messages: %s, detected from LC_ALL, LC_MESSAGES, LANG by default"},
+	"flag.continue_on_error": {i18n.Chinese: "某一行出错后继续执行后续各行，最后仍以非零状态退出", i18n.English: "continue with the following lines after an error, still exiting with a non-zero status"},
+	"flag.addr":              {i18n.Chinese: "监听地址", i18n.English: "listen address"},
+	"flag.timeout":           {i18n.Chinese: "每个请求的计算超时时间", i18n.English: "calculation timeout of each request"},
+	"flag.max_length":        {i18n.Chinese: "单个表达式的最大长度（字节）", i18n.English: "maximum length of an expression (bytes)"},
+	"flag.max_depth":         {i18n.Chinese: "单个表达式语法树的最大嵌套深度", i18n.English: "maximum nesting depth of an expression's syntax tree"},
+	"flag.max_digits":        {i18n.Chinese: "单个结果的最大十进制位数，超出时不再格式化", i18n.English: "maximum number of decimal digits in a result; larger results are not formatted"},
+	"flag.max_batch":         {i18n.Chinese: "/batch 请求中表达式的最大数量", i18n.English: "maximum number of expressions in a /batch request"},
+	"lang.unknown":           {i18n.Chinese: "不支持的语言: %s（可选 %s）", i18n.English: "unsupported language: %s (choose from %s)"},
+
+	// 子命令
+	"cmd.eval":        {i18n.Chinese: "计算单个表达式", i18n.English: "evaluate a single expression"},
+	"cmd.interactive": {i18n.Chinese: "启动交互式计算器", i18n.English: "start the interactive calculator"},
+	"cmd.run":         {i18n.Chinese: "执行脚本文件，每行一条语句，支持赋值、空行和 # 注释", i18n.English: "run a script file with one statement per line, supporting assignments, blank lines and # comments"},
+	"cmd.batch":       {i18n.Chinese: "从标准输入逐行读取表达式并输出结果", i18n.English: "read expressions line by line from standard input and print the results"},
+	"cmd.simplify":    {i18n.Chinese: "化简表达式，输出化简后的表达式", i18n.English: "simplify an expression and print the result"},
+	"cmd.diff":        {i18n.Chinese: "对表达式求导，输出化简后的导数", i18n.English: "differentiate an expression and print the simplified derivative"},
+	"cmd.serve":       {i18n.Chinese: "启动 HTTP 计算服务（POST /eval、POST /batch、GET /healthz）", i18n.English: "start the HTTP calculation service (POST /eval, POST /batch, GET /healthz)"},
+	"cmd.test":        {i18n.Chinese: "运行目录中的 .calc 用例文件，没有指定目录时运行内置用例", i18n.English: "run the .calc case files in a directory, or the built-in cases when none is given"},
+
+	// 计算和输出
+	"calc.no_expression":  {i18n.Chinese: "请提供一个数学表达式", i18n.English: "please provide a math expression"},
+	"calc.evaluating":     {i18n.Chinese: "正在计算表达式: %s (模式: %s)", i18n.English: "evaluating expression: %s (mode: %s)"},
+	"calc.expression":     {i18n.Chinese: "表达式: %s", i18n.English: "expression: %s"},
+	"calc.result":         {i18n.Chinese: "结果: %s", i18n.English: "result: %s"},
+	"calc.output_error":   {i18n.Chinese: "输出错误: %v", i18n.English: "output error: %v"},
+	"calc.eval_error":     {i18n.Chinese: "计算错误: %s", i18n.English: "calculation error: %s"},
+	"units.load_failed":   {i18n.Chinese: "加载单位文件失败", i18n.English: "failed to load unit file"},
+	"format.unknown_unit": {i18n.Chinese: "未知的以太坊单位: %s（可选 %s）", i18n.English: "unknown Ethereum unit: %s (choose from %s)"},
+	"format.hex_conflict": {i18n.Chinese: "--hex 与 --base=%d 冲突", i18n.English: "--hex conflicts with --base=%d"},
+	"format.base":         {i18n.Chinese: "不支持的输出进制: %d（可选 2、8、10、16）", i18n.English: "unsupported output base: %d (choose from 2, 8, 10, 16)"},
+	"format.sci":          {i18n.Chinese: "--sci 只能用于十进制输出", i18n.English: "--sci can only be used with decimal output"},
+	"output.unknown":      {i18n.Chinese: "未知的输出格式: %s（可选 plain、json、csv）", i18n.English: "unknown output format: %s (choose from plain, json, csv)"},
+
+	// 交互模式
+	"repl.banner": {
+		i18n.Chinese: "🧮 欢迎使用命令行计算器!\n" +
+			"支持的操作: + - * / % ^ () 位运算 比较 逻辑运算 ?: 函数调用\n" +
+			"输入 'help' 查看帮助，'exit' 或 'quit' 退出\n" +
+			"示例: 1+2*3, (1+2)*3, 10%3, x = 3*4, ans+1, sqrt(2), max(1,2,3)",
+		i18n.English: "🧮 Welcome to the command-line calculator!\n" +
+			"Operations: + - * / % ^ () bitwise comparison logical ?: function calls\n" +
+			"Type 'help' for help, 'exit' or 'quit' to quit\n" +
+			"Examples: 1+2*3, (1+2)*3, 10%3, x = 3*4, ans+1, sqrt(2), max(1,2,3)",
+	},
+	"repl.bye":               {i18n.Chinese: "再见! 👋", i18n.English: "Bye! 👋"},
+	"repl.read_failed":       {i18n.Chinese: "读取输入失败: %v", i18n.English: "failed to read input: %v"},
+	"repl.evaluating":        {i18n.Chinese: "正在计算: %s", i18n.English: "evaluating: %s"},
+	"repl.error":             {i18n.Chinese: "❌ 错误: %s", i18n.English: "❌ error: %s"},
+	"repl.defined":           {i18n.Chinese: "✅ 已定义函数 %s", i18n.English: "✅ defined function %s"},
+	"repl.no_vars":           {i18n.Chinese: "（没有定义变量）", i18n.English: "(no variables defined)"},
+	"repl.no_funcs":          {i18n.Chinese: "（没有定义函数）", i18n.English: "(no functions defined)"},
+	"repl.mode":              {i18n.Chinese: "当前模式: %s（精度 %d 位）", i18n.English: "current mode: %s (precision %d bits)"},
+	"repl.invalid_precision": {i18n.Chinese: "❌ 无效的精度: %s", i18n.English: "❌ invalid precision: %s"},
+	"repl.mode_switched":     {i18n.Chinese: "已切换到 %s 模式", i18n.English: "switched to %s mode"},
+	"repl.unset_usage":       {i18n.Chinese: "用法: unset 名称 [名称...]", i18n.English: "usage: unset NAME [NAME...]"},
+	"repl.not_found":         {i18n.Chinese: "❌ 变量或函数不存在: %s", i18n.English: "❌ no such variable or function: %s"},
+	"repl.history_failed":    {i18n.Chinese: "保存历史记录失败: %v", i18n.English: "failed to save history: %v"},
+	"repl.help": {
+		i18n.Chinese: `
+📖 计算器帮助:
+  支持的运算符:
+    +  : 加法 (例: 1+2)
+    -  : 减法 (例: 5-3)
+    *  : 乘法 (例: 2*3)
+    /  : 除法 (例: 8/2)
+    %  : 取模 (例: 10%3)
+    ^  : 幂运算，右结合 (例: 2^10，也可写作 2**10)
+    () : 括号 (例: (1+2)*3)
+    & | xor << >>       : 整数位运算 (例: 6 xor 3)
+    == != < <= > >=     : 比较，结果为 1 或 0
+    && || !             : 逻辑运算，结果为 1 或 0
+    条件 ? 值1 : 值2    : 条件表达式 (例: x > 0 ? x : -x)
+
+  函数:
+    sqrt(2), pow(2,10), abs(-1), floor/ceil/round(x)
+    min(1,2,3), max(1,2,3), log(x), log(x,底数), ln(x), exp(x)
+    sin/cos/tan/asin/acos/atan(x), atan2(y,x)
+  常量: pi, e
+
+  列表:
+    [1, 2, 3], 1..10             : 列表字面量和整数范围（包含两端）
+    sum/count/avg/median/stddev(xs) : 聚合函数，列表参数展开为多个参数
+    percentile(xs, 90)           : 百分位数
+    map(xs, x => x * 2)          : 对每个元素计算
+    filter(xs, x => x > 0)       : 保留结果非零的元素
+
+  单位:
+    5 km, 60 km/h, 9.81 m/s^2    : 带单位的数量，复合单位中间不能有空格
+    5 km / 2 h in m/s            : 单位换算，量纲不同时报错
+    3 MB * 8 in Mbit             : 数据量（kB、MB 为十进制，KiB、MiB 为二进制）
+    --units units.yaml           : 从文件加载自定义单位和汇率
+
+  以太坊金额:
+    1.5ether, 30 gwei, 21000wei : 金额字面量，结果为整数 wei
+    2 ether in gwei             : 单位换算
+    0xde0b6b3a7640000           : 十六进制整数
+
+  数字:
+    1e18, 2.5e-3                : 科学计数法（1ether 中的 e 属于单位）
+    0x1f, 0b1010, 0o17          : 十六进制、二进制、八进制整数
+    1_000_000                   : 数字分隔符，只能用在数字之间
+
+  变量:
+    x = 3*4 : 赋值
+    x + 1   : 使用变量
+    ans, _  : 上一次计算的结果
+
+  自定义函数:
+    f(x, y) = x^2 + y             : 定义函数，参数优先于同名变量
+    fact(n) = n <= 1 ? 1 : n * fact(n - 1) : 支持递归
+
+  特殊命令:
+    help    : 显示此帮助
+    vars    : 列出所有变量
+    funcs   : 列出自定义函数
+    unset x : 删除变量或函数
+    mode    : 查看计算模式
+    mode rat / mode big 512 : 切换计算模式（及 big 模式精度）
+    clear   : 清屏
+    exit    : 退出程序
+
+  编辑:
+    ↑/↓     : 浏览历史记录（保存在 ~/.calc_history）
+    Ctrl-R  : 反向搜索历史记录
+    Tab     : 补全命令、函数、常量和变量名
+    Ctrl-D  : 退出程序
+
+  运算优先级:
+    1. 括号 ()、函数调用
+    2. 幂运算 ^
+    3. 一元运算 - + !
+    4. 乘法 * 除法 / 取模 %
+    5. 加法 + 减法 -
+    6. 移位 << >>
+    7. 范围 ..
+    8. 比较 < <= > >=，然后 == !=
+    9. 位运算 &，然后 xor，然后 |
+   10. 逻辑与 &&，然后逻辑或 ||
+   11. 条件表达式 ?:
+`,
+		i18n.English: `
+📖 Calculator help:
+  Operators:
+    +  : addition (e.g. 1+2)
+    -  : subtraction (e.g. 5-3)
+    *  : multiplication (e.g. 2*3)
+    /  : division (e.g. 8/2)
+    %  : modulo (e.g. 10%3)
+    ^  : power, right-associative (e.g. 2^10, also 2**10)
+    () : parentheses (e.g. (1+2)*3)
+    & | xor << >>       : integer bitwise operations (e.g. 6 xor 3)
+    == != < <= > >=     : comparison, result is 1 or 0
+    && || !             : logical operations, result is 1 or 0
+    cond ? a : b        : conditional expression (e.g. x > 0 ? x : -x)
+
+  Functions:
+    sqrt(2), pow(2,10), abs(-1), floor/ceil/round(x)
+    min(1,2,3), max(1,2,3), log(x), log(x,base), ln(x), exp(x)
+    sin/cos/tan/asin/acos/atan(x), atan2(y,x)
+  Constants: pi, e
+
+  Lists:
+    [1, 2, 3], 1..10             : list literals and integer ranges (inclusive)
+    sum/count/avg/median/stddev(xs) : aggregates, list arguments are spread
+    percentile(xs, 90)           : percentile
+    map(xs, x => x * 2)          : apply to each element
+    filter(xs, x => x > 0)       : keep elements with a non-zero result
+
+  Units:
+    5 km, 60 km/h, 9.81 m/s^2    : quantities, no spaces inside compound units
+    5 km / 2 h in m/s            : unit conversion, fails on dimension mismatch
+    3 MB * 8 in Mbit             : data sizes (kB, MB are decimal, KiB, MiB binary)
+    --units units.yaml           : load custom units and exchange rates from a file
+
+  Ethereum amounts:
+    1.5ether, 30 gwei, 21000wei : amount literals, result is an integer in wei
+    2 ether in gwei             : unit conversion
+    0xde0b6b3a7640000           : hexadecimal integer
+
+  Numbers:
+    1e18, 2.5e-3                : scientific notation (the e in 1ether is the unit)
+    0x1f, 0b1010, 0o17          : hexadecimal, binary and octal integers
+    1_000_000                   : digit separators, only between digits
+
+  Variables:
+    x = 3*4 : assignment
+    x + 1   : use a variable
+    ans, _  : result of the previous calculation
+
+  User functions:
+    f(x, y) = x^2 + y             : define a function, parameters shadow variables
+    fact(n) = n <= 1 ? 1 : n * fact(n - 1) : recursion is supported
+
+  Commands:
+    help    : show this help
+    vars    : list variables
+    funcs   : list user functions
+    unset x : remove a variable or function
+    mode    : show the calculation mode
+    mode rat / mode big 512 : switch mode (and precision of big mode)
+    clear   : clear the screen
+    exit    : quit
+
+  Editing:
+    ↑/↓     : browse history (saved in ~/.calc_history)
+    Ctrl-R  : reverse history search
+    Tab     : complete commands, functions, constants and variables
+    Ctrl-D  : quit
+
+  Precedence:
+    1. parentheses (), function calls
+    2. power ^
+    3. unary - + !
+    4. multiplication * division / modulo %
+    5. addition + subtraction -
+    6. shifts << >>
+    7. range ..
+    8. comparison < <= > >=, then == !=
+    9. bitwise &, then xor, then |
+   10. logical and &&, then logical or ||
+   11. conditional ?:
+`,
+	},
+
+	// 脚本和符号计算
+	"script.no_file":       {i18n.Chinese: "请提供一个脚本文件", i18n.English: "please provide a script file"},
+	"script.open_failed":   {i18n.Chinese: "打开脚本失败: %v", i18n.English: "failed to open script: %v"},
+	"script.write_failed":  {i18n.Chinese: "输出结果失败: %v", i18n.English: "failed to write results: %v"},
+	"script.failed_lines":  {i18n.Chinese: "%s: %d 行执行失败，第一个错误: %v", i18n.English: "%s: %d lines failed, first error: %v"},
+	"symbolic.simplify":    {i18n.Chinese: "请提供一个表达式，例如: calc simplify \"x*1+0\"", i18n.English: "please provide an expression, e.g. calc simplify \"x*1+0\""},
+	"symbolic.diff":        {i18n.Chinese: "请提供表达式和求导变量，例如: calc diff \"x^2*sin(x)\" x", i18n.English: "please provide an expression and a variable, e.g. calc diff \"x^2*sin(x)\" x"},
+	"cases.one_arg":        {i18n.Chinese: "只能指定一个目录或用例文件", i18n.English: "only one directory or case file can be given"},
+	"cases.builtin":        {i18n.Chinese: "内置用例", i18n.English: "built-in cases"},
+	"cases.open_failed":    {i18n.Chinese: "打开用例失败: %v", i18n.English: "failed to open cases: %v"},
+	"cases.load_failed":    {i18n.Chinese: "加载用例失败: %v", i18n.English: "failed to load cases: %v"},
+	"cases.none":           {i18n.Chinese: "%s 中没有 %s 用例文件", i18n.English: "no %[2]s case files in %[1]s"},
+	"cases.running":        {i18n.Chinese: "🧪 运行用例: %s（%d 个文件）", i18n.English: "🧪 running cases: %s (%d files)"},
+	"cases.summary":        {i18n.Chinese: "📊 测试结果: %d/%d 通过", i18n.English: "📊 results: %d/%d passed"},
+	"cases.failed_summary": {i18n.Chinese: " ⚠️  有 %d 个用例失败", i18n.English: " ⚠️  %d cases failed"},
+	"cases.failed":         {i18n.Chinese: "有 %d 个用例失败", i18n.English: "%d cases failed"},
+	"cases.all_passed":     {i18n.Chinese: " 🎉 所有测试通过!", i18n.English: " 🎉 all tests passed!"},
+	"cases.file_passed":    {i18n.Chinese: "✅ %s: %d 个用例通过 (%s)", i18n.English: "✅ %s: %d cases passed (%s)"},
+	"cases.file_failed":    {i18n.Chinese: "❌ %s: %d/%d 个用例失败", i18n.English: "❌ %s: %d/%d cases failed"},
+
+	// HTTP 计算服务
+	"serve.started":           {i18n.Chinese: "计算服务已启动: %s", i18n.English: "calculation service started: %s"},
+	"serve.start_failed":      {i18n.Chinese: "服务启动失败: %v", i18n.English: "failed to start service: %v"},
+	"serve.stopping":          {i18n.Chinese: "正在关闭计算服务...", i18n.English: "shutting down calculation service..."},
+	"serve.empty_expression":  {i18n.Chinese: "expression 不能为空", i18n.English: "expression must not be empty"},
+	"serve.empty_expressions": {i18n.Chinese: "expressions 不能为空", i18n.English: "expressions must not be empty"},
+	"serve.too_many":          {i18n.Chinese: "表达式数量 %d 超过上限 %d", i18n.English: "%d expressions exceed the limit of %d"},
+	"serve.get_only":          {i18n.Chinese: "只支持 GET 请求", i18n.English: "only GET requests are supported"},
+	"serve.post_only":         {i18n.Chinese: "只支持 POST 请求", i18n.English: "only POST requests are supported"},
+	"serve.invalid_body":      {i18n.Chinese: "无效的请求体: %v", i18n.English: "invalid request body: %v"},
+	"serve.write_failed":      {i18n.Chinese: "写出响应失败: %v", i18n.English: "failed to write response: %v"},
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"cli_cmd/calculator"
+
+	"github.com/urfave/cli/v2"
+)
+
+// 输出格式
+const (
+	outputPlain = "plain"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// record 一条计算记录，用于机器可读的输出
+type record struct {
+	Line       int    `json:"line,omitempty"` // 脚本中的行号，单个表达式时为 0
+	Expression string `json:"expression"`
+	Result     string `json:"result"`
+	Type       string `json:"type"` // 结果的数值类型：float、big、rat，列表为 list，函数定义为 func，符号计算为 expr
+	Error      string `json:"error,omitempty"`
+	Code       string `json:"code,omitempty"`     // 错误代码，见 calculator.ErrorCode
+	ErrorID    string `json:"error_id,omitempty"` // 稳定的错误标识，与 --lang 无关，见 calculator.ErrorID
+	ElapsedNS  int64  `json:"elapsed_ns"`
+
+	assign bool
+	err    error
+}
+
+// newRecord 根据语句的执行结果创建记录，并按输出选项格式化结果
+//
+// 赋值和函数定义语句标记为 assign，plain 格式下不输出。格式化超出 ctx 的期限时记录为超时。
+func newRecord(ctx context.Context, res calculator.Result, opts calculator.FormatOptions) record {
+	rec := record{
+		Line:       res.Line,
+		Expression: strings.TrimSpace(res.Text),
+		ElapsedNS:  res.Elapsed.Nanoseconds(),
+		assign:     res.Assign || res.Func != nil,
+	}
+	switch {
+	case res.Err != nil:
+		rec.setError(res.Err, calculator.ErrorCode(res.Err))
+	case res.Func != nil:
+		rec.Result, rec.Type = res.Func.String(), "func"
+	default:
+		result, err := calculator.FormatContext(ctx, res.Value, opts)
+		if err != nil {
+			code := calculator.CodeFormat
+			if calculator.ErrorCode(err) == calculator.CodeTimeout {
+				code = calculator.CodeTimeout
+			}
+			rec.setError(&calculator.ScriptError{Line: res.Line, Text: res.Text, Err: err}, code)
+			break
+		}
+		rec.Result, rec.Type = result, valueType(res.Value)
+	}
+	return rec
+}
+
+// valueType 返回结果的类型名称：列表为 list，数值为其计算模式
+func valueType(v calculator.Value) string {
+	if _, ok := v.(calculator.List); ok {
+		return "list"
+	}
+	return calculator.ModeOf(v).String()
+}
+
+// setError 记录错误及其代码和标识
+func (r *record) setError(err error, code string) {
+	if err == nil {
+		return
+	}
+	r.err = err
+	r.Error = err.Error()
+	r.Code = code
+	r.ErrorID = calculator.ErrorID(err)
+}
+
+// recordWriter 按输出格式写出计算记录
+type recordWriter interface {
+	Write(rec record) error
+}
+
+// newRecordWriter 根据 --output 标志创建记录输出
+func newRecordWriter(c *cli.Context, w io.Writer) (recordWriter, error) {
+	switch format := c.String("output"); format {
+	case outputPlain, "":
+		return plainWriter{w}, nil
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false) // 表达式中常见 <、>、&
+		return jsonWriter{enc}, nil
+	case outputCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, errors.New(tr("output.unknown", format))
+	}
+}
+
+// plainWriter 每行输出一个结果，赋值语句和出错的记录不输出
+type plainWriter struct{ w io.Writer }
+
+func (p plainWriter) Write(rec record) error {
+	if rec.err != nil || rec.assign {
+		return nil
+	}
+	_, err := fmt.Fprintln(p.w, rec.Result)
+	return err
+}
+
+// jsonWriter 每行输出一个 JSON 对象（JSON Lines）
+type jsonWriter struct{ enc *json.Encoder }
+
+func (j jsonWriter) Write(rec record) error { return j.enc.Encode(rec) }
+
+// csvWriter 输出带表头的 CSV，error_id 列在最后，保持已有各列的位置不变
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+var csvHeader = []string{"line", "expression", "result", "type", "error", "code", "elapsed_ns", "error_id"}
+
+func (c *csvWriter) Write(rec record) error {
+	if !c.header {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.header = true
+	}
+	line := ""
+	if rec.Line > 0 {
+		line = strconv.Itoa(rec.Line)
+	}
+	err := c.w.Write([]string{
+		line, rec.Expression, rec.Result, rec.Type, rec.Error, rec.Code,
+		strconv.FormatInt(rec.ElapsedNS, 10), rec.ErrorID,
+	})
+	if err != nil {
+		return err
+	}
+	// 逐行刷新，便于从管道读取批量计算的结果
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cli_cmd/calculator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// execRecord 在新环境中执行语句并创建记录
+func execRecord(t *testing.T, line int, text string, opts calculator.FormatOptions) record {
+	t.Helper()
+	env := calculator.NewEnv()
+	return newRecord(context.Background(), env.Exec(calculator.Statement{Line: line, Text: text}), opts)
+}
+
+// TestNewRecord 测试记录的结果、类型和错误字段
+func TestNewRecord(t *testing.T) {
+	tests := []struct {
+		text   string
+		opts   calculator.FormatOptions
+		result string
+		typ    string
+		code   string
+		assign bool
+		desc   string
+	}{
+		{"1/3", calculator.FormatOptions{}, "0.333333", "float", "", false, "plain 输出保留 6 位有效数字"},
+		{"1/3", calculator.FormatOptions{FullPrecision: true}, "0.3333333333333333", "float", "", false, "完整精度"},
+		{"2^60", calculator.FormatOptions{FullPrecision: true}, "1152921504606846976", "float", "", false, "完整精度的整数"},
+		{"1.5 ether", calculator.FormatOptions{Unit: "gwei", FullPrecision: true}, "1500000000 gwei", "rat", "", false, "按单位输出"},
+		{"[1, 2]", calculator.FormatOptions{}, "[1, 2]", "list", "", false, "列表"},
+		{"x = 2", calculator.FormatOptions{}, "2", "float", "", true, "赋值"},
+		{"f(x) = x + 1", calculator.FormatOptions{}, "f(x) = x + 1", "func", "", true, "函数定义"},
+		{"1/0", calculator.FormatOptions{}, "", "", calculator.CodeEval, false, "计算错误"},
+		{"1.5", calculator.FormatOptions{Base: 16}, "", "", calculator.CodeFormat, false, "格式化错误"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			rec := execRecord(t, 0, test.text, test.opts)
+			assert.Equal(t, test.result, rec.Result)
+			assert.Equal(t, test.typ, rec.Type)
+			assert.Equal(t, test.code, rec.Code)
+			assert.Equal(t, test.assign, rec.assign)
+			if test.code != "" {
+				assert.NotEmpty(t, rec.Error)
+				assert.NotEmpty(t, rec.ErrorID)
+			}
+		})
+	}
+}
+
+// TestJSONWriter 测试 JSON Lines 输出
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	w := jsonWriter{enc}
+
+	opts := calculator.FormatOptions{FullPrecision: true}
+	require.NoError(t, w.Write(execRecord(t, 0, "1 < 2 && 3 > 2", opts)))
+	require.NoError(t, w.Write(execRecord(t, 3, "1/0", opts)))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var first map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.NotContains(t, first, "line", "单个表达式不输出 line")
+	assert.NotContains(t, first, "error")
+	assert.Equal(t, "1", first["result"])
+	assert.Contains(t, lines[0], `"expression":"1 < 2 && 3 > 2"`, "不转义 HTML 字符")
+
+	var second map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, float64(3), second["line"])
+	assert.Equal(t, calculator.CodeEval, second["code"])
+	assert.NotEmpty(t, second["error_id"])
+}
+
+// TestCSVWriter 测试 CSV 输出的表头、引号和行号
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &csvWriter{w: csv.NewWriter(&buf)}
+
+	opts := calculator.FormatOptions{FullPrecision: true}
+	require.NoError(t, w.Write(execRecord(t, 0, "1/3", opts)))
+	require.NoError(t, w.Write(execRecord(t, 2, "max(1, 2)", opts)))
+	require.NoError(t, w.Write(execRecord(t, 5, `"a"`, opts)))
+
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4, "表头只输出一次")
+	assert.Equal(t, csvHeader, rows[0])
+
+	assert.Equal(t, "", rows[1][0], "单个表达式的行号为空")
+	assert.Equal(t, "0.3333333333333333", rows[1][2])
+	assert.Equal(t, "float", rows[1][3])
+
+	assert.Equal(t, "2", rows[2][0])
+	assert.Equal(t, "max(1, 2)", rows[2][1])
+	assert.Contains(t, buf.String(), `"max(1, 2)"`, "含逗号的字段加引号")
+
+	assert.Equal(t, "5", rows[3][0])
+	assert.Equal(t, `"a"`, rows[3][1], "字段中的引号转义后可以还原")
+	assert.Equal(t, calculator.CodeSyntax, rows[3][5])
+	assert.NotEmpty(t, rows[3][7])
+}
+
+// TestPlainWriter 测试 plain 输出跳过赋值和出错的记录
+func TestPlainWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := plainWriter{&buf}
+	opts := calculator.FormatOptions{}
+	for _, text := range []string{"x = 1", "1/0", "1/3"} {
+		require.NoError(t, w.Write(execRecord(t, 0, text, opts)))
+	}
+	assert.Equal(t, "0.333333\n", buf.String())
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cli_cmd/calculator"
+
+	"github.com/mattn/go-isatty"
+	"github.com/peterh/liner"
+)
+
+// historyFile 交互模式历史记录文件，位于用户主目录
+const historyFile = ".calc_history"
+
+// replCommands 交互模式的特殊命令，用于补全
+var replCommands = []string{"clear", "exit", "funcs", "help", "mode", "quit", "unset", "vars"}
+
+// argCommand 解析带参数的特殊命令 unset 和 mode
+//
+// 命令名后紧跟 = 时是对同名变量的赋值或比较，例如 mode = 3，不作为命令。
+func argCommand(input string) (string, []string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || (fields[0] != "unset" && fields[0] != "mode") {
+		return "", nil, false
+	}
+	if len(fields) > 1 && strings.HasPrefix(fields[1], "=") {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// lineReader 交互模式的输入来源
+type lineReader interface {
+	// ReadLine 显示提示符并读取一行，输入结束时返回 io.EOF
+	ReadLine(prompt string) (string, error)
+	// AddHistory 把一行输入加入历史记录
+	AddHistory(line string)
+	// Close 保存历史记录并恢复终端
+	Close() error
+}
+
+// newLineReader 创建输入来源
+//
+// 标准输入是终端时使用支持方向键、历史记录（Ctrl-R 反向搜索）和 Tab 补全的行编辑器；
+// 否则（例如通过管道输入）逐行读取标准输入。
+func newLineReader(env *calculator.Env) lineReader {
+	if !isatty.IsTerminal(os.Stdin.Fd()) || !liner.TerminalSupported() {
+		return &plainReader{reader: bufio.NewReader(os.Stdin)}
+	}
+
+	state := liner.NewLiner()
+	state.SetCtrlCAborts(true)
+	state.SetTabCompletionStyle(liner.TabPrints)
+	state.SetWordCompleter(func(line string, pos int) (string, []string, string) {
+		return completeWord(env, line, pos)
+	})
+
+	r := &editorReader{state: state, path: historyPath()}
+	if r.path != "" {
+		if f, err := os.Open(r.path); err == nil {
+			state.ReadHistory(f)
+			f.Close()
+		}
+	}
+	return r
+}
+
+// historyPath 返回历史记录文件路径，无法确定主目录时返回空字符串
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFile)
+}
+
+// editorReader 基于 liner 的行编辑器
+type editorReader struct {
+	state *liner.State
+	path  string
+}
+
+func (r *editorReader) ReadLine(prompt string) (string, error) {
+	for {
+		line, err := r.state.Prompt(prompt)
+		// Ctrl-C 放弃当前输入，重新显示提示符
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		}
+		return line, err
+	}
+}
+
+func (r *editorReader) AddHistory(line string) { r.state.AppendHistory(line) }
+
+func (r *editorReader) Close() error {
+	defer r.state.Close()
+	if r.path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(r.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return errors.New(tr("repl.history_failed", err))
+	}
+	defer f.Close()
+	if _, err := r.state.WriteHistory(f); err != nil {
+		return errors.New(tr("repl.history_failed", err))
+	}
+	return nil
+}
+
+// plainReader 不支持行编辑的输入，用于管道和非终端环境
+type plainReader struct {
+	reader *bufio.Reader
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	line, err := r.reader.ReadString('\n')
+	// 最后一行可能没有换行符
+	if err == io.EOF && line != "" {
+		return line, nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (r *plainReader) AddHistory(string) {}
+
+func (r *plainReader) Close() error { return nil }
+
+// completeWord 补全光标前的单词
+//
+// 行首的单词补全为特殊命令、函数、常量或变量，其他位置只补全函数、常量和变量；
+// 函数名补全后自动加上 "("。
+func completeWord(env *calculator.Env, line string, pos int) (string, []string, string) {
+	runes := []rune(line)
+	if pos > len(runes) {
+		pos = len(runes)
+	}
+	start := pos
+	for start > 0 && isWordRune(runes[start-1]) {
+		start--
+	}
+	head, prefix, tail := string(runes[:start]), string(runes[start:pos]), string(runes[pos:])
+
+	seen := make(map[string]bool)
+	var candidates []string
+	add := func(word string) {
+		if strings.HasPrefix(word, prefix) && !seen[word] {
+			seen[word] = true
+			candidates = append(candidates, word)
+		}
+	}
+	if strings.TrimSpace(head) == "" {
+		for _, cmd := range replCommands {
+			add(cmd)
+		}
+	}
+	registry := env.Registry()
+	for _, name := range registry.FuncNames() {
+		add(name + "(")
+	}
+	for _, fn := range env.UserFuncs() {
+		add(fn.Name + "(")
+	}
+	for _, name := range registry.ConstNames() {
+		add(name)
+	}
+	for _, name := range env.Names() {
+		add(name)
+	}
+	sort.Strings(candidates)
+	return head, candidates, tail
+}
+
+func isWordRune(ch rune) bool {
+	return ch == '_' || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') || ch > 0x7f
+}
+
+// palette 终端颜色，禁用时原样输出
+type palette struct {
+	enabled bool
+}
+
+// newPalette 根据 --no-color、NO_COLOR 环境变量和标准输出是否为终端决定是否启用颜色
+func newPalette(noColor bool) palette {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return palette{}
+	}
+	return palette{enabled: isatty.IsTerminal(os.Stdout.Fd())}
+}
+
+func (p palette) paint(code, s string) string {
+	if !p.enabled {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+func (p palette) result(s string) string { return p.paint("32", s) }
+func (p palette) err(s string) string    { return p.paint("31", s) }
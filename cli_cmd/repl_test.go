@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"cli_cmd/calculator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompleteWord 测试交互模式的补全
+func TestCompleteWord(t *testing.T) {
+	env := calculator.NewEnv()
+	env.SetValue("price", calculator.Float(1))
+	env.SetValue("价格", calculator.Float(2))
+	require.NoError(t, env.Exec(calculator.Statement{Text: "square(x) = x * x"}).Err)
+
+	tests := []struct {
+		line       string
+		pos        int
+		head       string
+		candidates []string
+		tail       string
+		desc       string
+	}{
+		{"mo", 2, "", []string{"mode"}, "", "行首补全命令"},
+		{"  un", 4, "  ", []string{"unset"}, "", "行首空白后补全命令"},
+		{"1 + mo", 6, "1 + ", nil, "", "其他位置不补全命令"},
+		{"sqr", 3, "", []string{"sqrt("}, "", "函数名加上左括号"},
+		{"squ", 3, "", []string{"square("}, "", "用户函数"},
+		{"2 * pr", 6, "2 * ", []string{"price"}, "", "变量"},
+		{"sqr(2) + 1", 3, "", []string{"sqrt("}, "(2) + 1", "光标在行中间"},
+		{"1 + 价", 5, "1 + ", []string{"价格"}, "", "多字节变量名"},
+		{"价格 + sqr", 8, "价格 + ", []string{"sqrt("}, "", "光标前有多字节字符"},
+		{"价格 + sqr", 2, "", []string{"价格"}, " + sqr", "光标在多字节单词末尾"},
+		{"sqr", 10, "", []string{"sqrt("}, "", "光标位置超出行尾"},
+		{"1 + zz", 6, "1 + ", nil, "", "没有匹配"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			head, candidates, tail := completeWord(env, test.line, test.pos)
+			assert.Equal(t, test.head, head)
+			assert.Equal(t, test.candidates, candidates)
+			assert.Equal(t, test.tail, tail)
+		})
+	}
+
+	// 行首空单词时补全所有命令
+	_, candidates, _ := completeWord(env, "", 0)
+	for _, cmd := range replCommands {
+		assert.Contains(t, candidates, cmd)
+	}
+	assert.Contains(t, candidates, "sqrt(")
+	assert.IsIncreasing(t, candidates)
+}
+
+// TestArgCommand 测试 unset、mode 只在后面不是 = 时作为命令
+func TestArgCommand(t *testing.T) {
+	tests := []struct {
+		input string
+		cmd   string
+		args  []string
+		ok    bool
+		desc  string
+	}{
+		{"mode", "mode", []string{}, true, "不带参数"},
+		{"mode big 512", "mode", []string{"big", "512"}, true, "带参数"},
+		{"unset x y", "unset", []string{"x", "y"}, true, "删除变量"},
+		{"mode = 3", "", nil, false, "赋值"},
+		{"unset = 1", "", nil, false, "赋值给 unset"},
+		{"mode =3", "", nil, false, "= 后没有空格"},
+		{"mode == 3", "", nil, false, "比较"},
+		{"mode=3", "", nil, false, "没有空格的赋值"},
+		{"mode + 1", "mode", []string{"+", "1"}, true, "其他运算符仍是命令"},
+		{"x = 1", "", nil, false, "普通表达式"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cmd, args, ok := argCommand(test.input)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.cmd, cmd)
+			assert.Equal(t, test.args, args)
+		})
+	}
+
+	// 不作为命令的输入可以正常求值
+	env := calculator.NewEnv()
+	for _, input := range []string{"mode = 3", "unset = 1", "mode + unset"} {
+		res := env.Exec(calculator.Statement{Text: input})
+		require.NoError(t, res.Err, input)
+	}
+	v, ok := env.GetValue("mode")
+	require.True(t, ok)
+	assert.Equal(t, "3", calculator.FormatValue(v))
+}
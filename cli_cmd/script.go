@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"cli_cmd/calculator"
+
+	"github.com/urfave/cli/v2"
+)
+
+// continueOnError 出错后继续执行后续语句的标志名
+const continueOnError = "continue-on-error"
+
+// continueOnErrorFlag 创建 --continue-on-error 标志，说明按当前语言输出
+func continueOnErrorFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  continueOnError,
+		Usage: tr("flag.continue_on_error"),
+	}
+}
+
+// runCommand 执行脚本文件
+func runCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New(tr("script.no_file"))
+	}
+	name := c.Args().First()
+	file, err := os.Open(name)
+	if err != nil {
+		return errors.New(tr("script.open_failed", err))
+	}
+	defer file.Close()
+	return runScript(c, name, file)
+}
+
+// batchCommand 从标准输入逐行读取表达式并计算
+func batchCommand(c *cli.Context) error {
+	return runScript(c, "stdin", os.Stdin)
+}
+
+// runScript 逐行执行脚本，表达式的结果输出到标准输出，错误输出到标准错误
+//
+// plain 格式下赋值语句不输出结果，json 和 csv 格式下每条语句输出一条记录。
+// 遇到错误时停止执行，除非指定了 --continue-on-error；
+// 只要有一行出错，返回的错误就指向第一个出错的行。
+func runScript(c *cli.Context, name string, r io.Reader) error {
+	env, err := newEnv(c)
+	if err != nil {
+		return err
+	}
+	opts, err := formatOptions(c)
+	if err != nil {
+		return err
+	}
+	out, err := newRecordWriter(c, os.Stdout)
+	if err != nil {
+		return err
+	}
+	_, plain := out.(plainWriter)
+	continueOnError := c.Bool(continueOnError)
+
+	var first error
+	failed := 0
+	fail := func(err error) bool {
+		if first == nil {
+			first = err
+		}
+		failed++
+		// json 和 csv 格式的错误已经写在记录中
+		if continueOnError && plain {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, describeError(err))
+		}
+		return continueOnError
+	}
+
+	var writeErr error
+	err = env.RunScript(r, func(res calculator.Result) bool {
+		rec := newRecord(c.Context, res, opts)
+		if writeErr = out.Write(rec); writeErr != nil {
+			return false
+		}
+		if rec.err != nil {
+			return fail(rec.err)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return errors.New(tr("script.write_failed", writeErr))
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case continueOnError:
+		return errors.New(tr("script.failed_lines", name, failed, first))
+	}
+	return fmt.Errorf("%s: %s", name, describeError(first))
+}
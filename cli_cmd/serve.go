@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cli_cmd/calculator"
+
+	"github.com/urfave/cli/v2"
+)
+
+// maxBodyBytes 请求体的最大字节数
+const maxBodyBytes = 1 << 20
+
+// serveFlags 创建 serve 子命令的标志
+func serveFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "addr",
+			Usage: tr("flag.addr"),
+			Value: ":8080",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: tr("flag.timeout"),
+			Value: 2 * time.Second,
+		},
+		&cli.IntFlag{
+			Name:  "max-length",
+			Usage: tr("flag.max_length"),
+			Value: 1024,
+		},
+		&cli.IntFlag{
+			Name:  "max-depth",
+			Usage: tr("flag.max_depth"),
+			Value: 64,
+		},
+		&cli.IntFlag{
+			Name:  "max-digits",
+			Usage: tr("flag.max_digits"),
+			Value: 100000,
+		},
+		&cli.IntFlag{
+			Name:  "max-batch",
+			Usage: tr("flag.max_batch"),
+			Value: 100,
+		},
+	}
+}
+
+// server 计算服务，每个请求使用独立的计算环境
+type server struct {
+	registry *calculator.Registry
+	mode     calculator.Mode
+	prec     uint
+	opts     calculator.FormatOptions
+	limits   calculator.Limits
+	timeout  time.Duration
+	maxBatch int
+}
+
+// evalRequest POST /eval 的请求体
+type evalRequest struct {
+	Expression string `json:"expression"`
+	Mode       string `json:"mode,omitempty"` // 可选，覆盖 --mode
+}
+
+// batchRequest POST /batch 的请求体，各表达式在同一个环境中依次计算
+type batchRequest struct {
+	Expressions []string `json:"expressions"`
+	Mode        string   `json:"mode,omitempty"`
+}
+
+// batchResponse POST /batch 的响应体
+type batchResponse struct {
+	Results []record `json:"results"`
+}
+
+// errorResponse 请求本身无效时的响应体
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// serveCommand 启动 HTTP 计算服务，收到 SIGINT 或 SIGTERM 时优雅退出
+func serveCommand(c *cli.Context) error {
+	env, err := newEnv(c)
+	if err != nil {
+		return err
+	}
+	opts, err := formatOptions(c)
+	if err != nil {
+		return err
+	}
+	opts.FullPrecision = true // 响应总是 JSON
+	s := &server{
+		registry: env.Registry(),
+		mode:     env.Mode(),
+		prec:     env.Precision(),
+		opts:     opts,
+		limits: calculator.Limits{
+			MaxLength: c.Int("max-length"),
+			MaxDepth:  c.Int("max-depth"),
+			MaxDigits: c.Int("max-digits"),
+		},
+		timeout:  c.Duration("timeout"),
+		maxBatch: c.Int("max-batch"),
+	}
+
+	srv := &http.Server{
+		Addr:              c.String("addr"),
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errCh := make(chan error, 1)
+	go func() {
+		log.Print(tr("serve.started", srv.Addr))
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.New(tr("serve.start_failed", err))
+	case <-ctx.Done():
+	}
+
+	log.Print(tr("serve.stopping"))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+// routes 注册路由
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eval", s.handleEval)
+	mux.HandleFunc("/batch", s.handleBatch)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	return mux
+}
+
+// newEnv 为一个请求创建计算环境
+func (s *server) newEnv(mode string) (*calculator.Env, error) {
+	env := calculator.NewEnvWithRegistry(s.registry)
+	env.SetLimits(s.limits)
+	if err := env.SetPrecision(s.prec); err != nil {
+		return nil, err
+	}
+	m := s.mode
+	if mode != "" {
+		var err error
+		if m, err = calculator.ParseMode(mode); err != nil {
+			return nil, err
+		}
+	}
+	if err := env.SetMode(m); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// handleEval 计算单个表达式
+//
+// 计算成功返回 200，表达式有误返回 422，超时返回 504，响应体都是一条记录。
+func (s *server) handleEval(w http.ResponseWriter, r *http.Request) {
+	var req evalRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Expression == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: tr("serve.empty_expression")})
+		return
+	}
+	env, err := s.newEnv(req.Mode)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	rec := newRecord(ctx, env.ExecContext(ctx, calculator.Statement{Text: req.Expression}), s.opts)
+
+	status := http.StatusOK
+	switch rec.Code {
+	case "":
+	case calculator.CodeTimeout:
+		status = http.StatusGatewayTimeout
+	default:
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, rec)
+}
+
+// handleBatch 在同一个环境中依次计算多个表达式，前面的赋值和函数定义对后面的表达式可见
+//
+// 单个表达式出错不影响其他表达式，错误写在对应的记录中；超时后剩余的表达式都记录为超时。
+func (s *server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if len(req.Expressions) == 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: tr("serve.empty_expressions")})
+		return
+	}
+	if len(req.Expressions) > s.maxBatch {
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{
+			Error: tr("serve.too_many", len(req.Expressions), s.maxBatch),
+		})
+		return
+	}
+	env, err := s.newEnv(req.Mode)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	resp := batchResponse{Results: make([]record, len(req.Expressions))}
+	for i, expr := range req.Expressions {
+		stmt := calculator.Statement{Line: i + 1, Text: expr}
+		resp.Results[i] = newRecord(ctx, env.ExecContext(ctx, stmt), s.opts)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleHealthz 健康检查
+func (s *server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: tr("serve.get_only")})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// decodeRequest 校验请求方法并解析 JSON 请求体，失败时写出错误响应并返回 false
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: tr("serve.post_only")})
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeJSON(w, status, errorResponse{Error: tr("serve.invalid_body", err)})
+		return false
+	}
+	return true
+}
+
+// writeJSON 写出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		log.Print(tr("serve.write_failed", err))
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"cli_cmd/calculator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer 创建与 serve 默认标志相同的计算服务
+func newTestServer() *server {
+	return &server{
+		registry: calculator.NewEnv().Registry(),
+		mode:     calculator.ModeFloat,
+		prec:     calculator.DefaultPrecision,
+		opts:     calculator.FormatOptions{FullPrecision: true},
+		limits:   calculator.Limits{MaxLength: 1024, MaxDepth: 64, MaxDigits: 100000},
+		timeout:  2 * time.Second,
+		maxBatch: 100,
+	}
+}
+
+// serve 向服务发送请求并返回响应
+func serve(t *testing.T, s *server, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.routes().ServeHTTP(w, req)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	return w
+}
+
+// decodeBody 解析 JSON 响应体
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), v), w.Body.String())
+}
+
+// TestServeEval 测试 POST /eval 的状态码和响应记录
+func TestServeEval(t *testing.T) {
+	tests := []struct {
+		body   string
+		status int
+		result string
+		typ    string
+		code   string
+		desc   string
+	}{
+		{`{"expression": "1 + 2"}`, http.StatusOK, "3", "float", "", "计算成功"},
+		{`{"expression": "1/3"}`, http.StatusOK, "0.3333333333333333", "float", "", "完整精度"},
+		{`{"expression": "1/3", "mode": "rat"}`, http.StatusOK, "1/3", "rat", "", "请求覆盖计算模式"},
+		{`{"expression": "2^200", "mode": "big"}`, http.StatusOK, "1606938044258990275541962092341162602522202993782792835301376", "big", "", "big 模式"},
+		{`{"expression": "1/0"}`, http.StatusUnprocessableEntity, "", "", calculator.CodeEval, "求值错误"},
+		{`{"expression": "1 +"}`, http.StatusUnprocessableEntity, "", "", calculator.CodeSyntax, "语法错误"},
+		{`{"expression": "1 m + 1 s"}`, http.StatusUnprocessableEntity, "", "", calculator.CodeType, "量纲错误"},
+	}
+
+	s := newTestServer()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := serve(t, s, http.MethodPost, "/eval", test.body)
+			assert.Equal(t, test.status, w.Code)
+			var rec map[string]interface{}
+			decodeBody(t, w, &rec)
+			if test.code == "" {
+				assert.Equal(t, test.result, rec["result"])
+				assert.Equal(t, test.typ, rec["type"])
+				assert.NotContains(t, rec, "error")
+			} else {
+				assert.Equal(t, test.code, rec["code"])
+				assert.NotEmpty(t, rec["error"])
+				assert.NotEmpty(t, rec["error_id"])
+			}
+			assert.NotContains(t, rec, "line", "单个表达式不输出 line")
+		})
+	}
+}
+
+// TestServeBadRequests 测试请求本身无效时的状态码
+func TestServeBadRequests(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		desc   string
+	}{
+		{http.MethodPost, "/eval", `{"expression": ""}`, http.StatusBadRequest, "空表达式"},
+		{http.MethodPost, "/eval", `{"expression": "1", "mode": "decimal"}`, http.StatusBadRequest, "未知的计算模式"},
+		{http.MethodPost, "/eval", `{"expression": `, http.StatusBadRequest, "JSON 不完整"},
+		{http.MethodPost, "/eval", `{"expr": "1 + 1"}`, http.StatusBadRequest, "未知字段"},
+		{http.MethodPost, "/batch", `{"expressions": ["1"], "extra": true}`, http.StatusBadRequest, "batch 未知字段"},
+		{http.MethodPost, "/batch", `{"expressions": []}`, http.StatusBadRequest, "空的表达式列表"},
+		{http.MethodPost, "/batch", `{"expressions": ["1"], "mode": "decimal"}`, http.StatusBadRequest, "batch 未知的计算模式"},
+		{http.MethodGet, "/eval", "", http.StatusMethodNotAllowed, "eval 只接受 POST"},
+		{http.MethodPut, "/batch", `{"expressions": ["1"]}`, http.StatusMethodNotAllowed, "batch 只接受 POST"},
+		{http.MethodPost, "/healthz", "", http.StatusMethodNotAllowed, "healthz 只接受 GET"},
+		{http.MethodPost, "/eval", `{"expression": "` + strings.Repeat("1", maxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, "请求体过大"},
+	}
+
+	s := newTestServer()
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := serve(t, s, test.method, test.path, test.body)
+			assert.Equal(t, test.status, w.Code)
+			var resp errorResponse
+			decodeBody(t, w, &resp)
+			assert.NotEmpty(t, resp.Error)
+			if test.status == http.StatusMethodNotAllowed {
+				assert.NotEmpty(t, w.Header().Get("Allow"))
+			}
+		})
+	}
+
+	w := serve(t, s, http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
+}
+
+// TestServeLimits 测试 --max-length、--max-depth、--max-digits 和 --max-batch
+func TestServeLimits(t *testing.T) {
+	s := newTestServer()
+	s.mode = calculator.ModeRat
+	s.limits = calculator.Limits{MaxLength: 20, MaxDepth: 5, MaxDigits: 30}
+	s.maxBatch = 3
+
+	tests := []struct {
+		expression string
+		status     int
+		desc       string
+	}{
+		{"1 + 2", http.StatusOK, "未超出限制"},
+		{strings.Repeat("1+", 10) + "1", http.StatusUnprocessableEntity, "超出长度限制"},
+		{"((((((1))))))", http.StatusUnprocessableEntity, "超出嵌套深度限制"},
+		{"2^90", http.StatusOK, "结果未超出位数限制"},
+		{"2^200", http.StatusUnprocessableEntity, "结果超出位数限制"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			body, err := json.Marshal(evalRequest{Expression: test.expression})
+			require.NoError(t, err)
+			w := serve(t, s, http.MethodPost, "/eval", string(body))
+			assert.Equal(t, test.status, w.Code)
+			if test.status != http.StatusOK {
+				var rec record
+				decodeBody(t, w, &rec)
+				assert.Equal(t, calculator.CodeLimit, rec.Code)
+			}
+		})
+	}
+
+	w := serve(t, s, http.MethodPost, "/batch", `{"expressions": ["1", "2", "3"]}`)
+	assert.Equal(t, http.StatusOK, w.Code, "刚好等于 --max-batch")
+	w = serve(t, s, http.MethodPost, "/batch", `{"expressions": ["1", "2", "3", "4"]}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "超出 --max-batch")
+	var resp errorResponse
+	decodeBody(t, w, &resp)
+	assert.NotEmpty(t, resp.Error)
+}
+
+// TestServeBatch 测试 /batch 的表达式在同一个环境中依次计算
+func TestServeBatch(t *testing.T) {
+	s := newTestServer()
+	w := serve(t, s, http.MethodPost, "/batch", `{
+		"expressions": ["x = 1/3", "x * 3", "f(a) = a + x", "f(2/3)", "y / 0", "x"],
+		"mode": "rat"
+	}`)
+	require.Equal(t, http.StatusOK, w.Code, "单个表达式出错不影响状态码")
+
+	var resp batchResponse
+	decodeBody(t, w, &resp)
+	require.Len(t, resp.Results, 6)
+	for i, rec := range resp.Results {
+		assert.Equal(t, i+1, rec.Line)
+	}
+	assert.Equal(t, "1/3", resp.Results[0].Result, "赋值也输出结果")
+	assert.Equal(t, "1", resp.Results[1].Result, "前面的赋值对后面的表达式可见")
+	assert.Equal(t, "func", resp.Results[2].Type)
+	assert.Equal(t, "1", resp.Results[3].Result, "函数定义对后面的表达式可见")
+	assert.Equal(t, calculator.CodeEval, resp.Results[4].Code)
+	assert.Equal(t, "1/3", resp.Results[5].Result, "出错之后继续计算")
+
+	// 每个请求使用独立的环境
+	w = serve(t, s, http.MethodPost, "/eval", `{"expression": "x"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+// hugeProduct 返回计算很快、但结果有几百万位、转换为十进制需要数秒的 rat 模式表达式
+func hugeProduct() string {
+	factors := make([]string, 0, 48)
+	for i := 1; i <= 95; i += 2 {
+		factors = append(factors, fmt.Sprintf("(2^524288+%d)", i))
+	}
+	return strings.Join(factors, "*")
+}
+
+// TestServeMaxDigits 测试过大的结果在格式化之前返回，不留下仍在转换的 goroutine
+func TestServeMaxDigits(t *testing.T) {
+	s := newTestServer()
+	s.timeout = 10 * time.Second
+	s.limits.MaxLength = 4096
+
+	serve(t, s, http.MethodPost, "/eval", `{"expression": "1"}`) // 先启动常驻的 goroutine
+	baseline := runtime.NumGoroutine()
+	start := time.Now()
+	body, err := json.Marshal(evalRequest{Expression: hugeProduct(), Mode: "rat"})
+	require.NoError(t, err)
+	w := serve(t, s, http.MethodPost, "/eval", string(body))
+	assert.Less(t, time.Since(start), time.Second, "不转换为十进制")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var rec record
+	decodeBody(t, w, &rec)
+	assert.Equal(t, calculator.CodeLimit, rec.Code)
+	assert.Empty(t, rec.Result)
+	// assert.Eventually 在单独的 goroutine 中检查条件，这里直接轮询
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > baseline && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline, "请求返回后没有继续格式化")
+
+	// batch 中过大的结果不影响后面的表达式
+	body, err = json.Marshal(batchRequest{Expressions: []string{hugeProduct(), "2 + 2"}, Mode: "rat"})
+	require.NoError(t, err)
+	w = serve(t, s, http.MethodPost, "/batch", string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp batchResponse
+	decodeBody(t, w, &resp)
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, calculator.CodeLimit, resp.Results[0].Code)
+	assert.Equal(t, "4", resp.Results[1].Result)
+}
+
+// TestServeTimeout 测试求值和格式化都受 --timeout 限制
+func TestServeTimeout(t *testing.T) {
+	s := newTestServer()
+	s.timeout = 300 * time.Millisecond
+	s.limits.MaxLength = 4096
+	s.limits.MaxDigits = 0 // 不限制位数时格式化仍受超时限制
+
+	start := time.Now()
+	body, err := json.Marshal(evalRequest{Expression: hugeProduct(), Mode: "rat"})
+	require.NoError(t, err)
+	w := serve(t, s, http.MethodPost, "/eval", string(body))
+	assert.Less(t, time.Since(start), 3*time.Second, "超时后不再等待格式化完成")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	var rec record
+	decodeBody(t, w, &rec)
+	assert.Equal(t, calculator.CodeTimeout, rec.Code)
+	assert.Empty(t, rec.Result)
+
+	// batch 超时后剩余的表达式都记录为超时
+	body, err = json.Marshal(batchRequest{Expressions: []string{"1 + 1", hugeProduct(), "2 + 2"}, Mode: "rat"})
+	require.NoError(t, err)
+	w = serve(t, s, http.MethodPost, "/batch", string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp batchResponse
+	decodeBody(t, w, &resp)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, "2", resp.Results[0].Result)
+	assert.Equal(t, calculator.CodeTimeout, resp.Results[1].Code)
+	assert.Equal(t, calculator.CodeTimeout, resp.Results[2].Code)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"cli_cmd/calculator"
+
+	"github.com/urfave/cli/v2"
+)
+
+// simplifyCommand 化简表达式并输出
+func simplifyCommand(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New(tr("symbolic.simplify"))
+	}
+	expr := c.Args().First()
+	return printSymbolic(c, expr, func(node calculator.Node) (calculator.Node, error) {
+		return calculator.Simplify(node), nil
+	})
+}
+
+// diffCommand 对表达式求导并输出化简后的导数，变量默认为 x
+func diffCommand(c *cli.Context) error {
+	if c.NArg() < 1 || c.NArg() > 2 {
+		return errors.New(tr("symbolic.diff"))
+	}
+	expr, name := c.Args().Get(0), "x"
+	if c.NArg() == 2 {
+		name = c.Args().Get(1)
+	}
+	return printSymbolic(c, expr, func(node calculator.Node) (calculator.Node, error) {
+		return calculator.Diff(node, name)
+	})
+}
+
+// printSymbolic 解析表达式，经 transform 变换后按 --output 格式输出
+func printSymbolic(c *cli.Context, expr string, transform func(calculator.Node) (calculator.Node, error)) error {
+	out, err := newRecordWriter(c, os.Stdout)
+	if err != nil {
+		return err
+	}
+	rec := record{Expression: expr, Type: "expr"}
+	node, err := calculator.Parse(expr)
+	if err == nil {
+		node, err = transform(node)
+	}
+	if err != nil {
+		rec.Type = ""
+		rec.setError(err, calculator.ErrorCode(err))
+		if err := out.Write(rec); err != nil {
+			return err
+		}
+		return errors.New(tr("calc.eval_error", describeError(err)))
+	}
+	rec.Result = calculator.FormatNode(node)
+	return out.Write(rec)
+}
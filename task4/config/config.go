@@ -0,0 +1,375 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv 指定配置文件路径的环境变量，-config 参数优先
+const ConfigEnv = "BLOG_CONFIG"
+
+// Config 服务配置
+//
+// 配置按 默认值 < YAML 文件 < 环境变量 < 命令行参数 的顺序覆盖，
+// 字段的 env 和 flag 标签分别是对应的环境变量和命令行参数名。
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	Log      LogConfig      `yaml:"log"`
+	CORS     CORSConfig     `yaml:"cors"`
+}
+
+// ServerConfig HTTP 服务配置
+type ServerConfig struct {
+	Addr string `yaml:"addr" env:"BLOG_ADDR" flag:"addr" usage:"监听地址"`
+	Mode string `yaml:"mode" env:"BLOG_MODE" flag:"mode" usage:"Gin 运行模式: debug、release、test"`
+}
+
+// 支持的数据库驱动
+const (
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// SQLiteMemory sqlite 驱动下 database.name 的特殊值，表示内存数据库
+const SQLiteMemory = ":memory:"
+
+// DatabaseConfig 数据库连接配置
+//
+// sqlite 驱动只使用 Name（数据库文件路径）和 Params，Host、Port、User、Password 被忽略。
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver" env:"BLOG_DB_DRIVER" flag:"db-driver" usage:"数据库驱动: mysql、sqlite、postgres"`
+	Host            string        `yaml:"host" env:"BLOG_DB_HOST" flag:"db-host" usage:"数据库主机"`
+	Port            int           `yaml:"port" env:"BLOG_DB_PORT" flag:"db-port" usage:"数据库端口，0 表示驱动的默认端口"`
+	User            string        `yaml:"user" env:"BLOG_DB_USER" flag:"db-user" usage:"数据库用户名"`
+	Password        string        `yaml:"password" env:"BLOG_DB_PASSWORD"` // 密码不提供命令行参数，避免出现在进程列表中
+	Name            string        `yaml:"name" env:"BLOG_DB_NAME" flag:"db-name" usage:"数据库名称，sqlite 为数据库文件路径"`
+	Params          string        `yaml:"params" env:"BLOG_DB_PARAMS"` // DSN 中 ? 之后的连接参数，为空时使用驱动的默认参数
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"BLOG_DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"最大打开连接数，0 表示不限制"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"BLOG_DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"最大空闲连接数"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"BLOG_DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"连接的最长使用时间，0 表示不限制"`
+}
+
+// JWTConfig JWT 签名配置
+type JWTConfig struct {
+	Secret     string        `yaml:"secret" env:"BLOG_JWT_SECRET"` // 密钥不提供命令行参数
+	TTL        time.Duration `yaml:"ttl" env:"BLOG_JWT_TTL" flag:"jwt-ttl" usage:"access token 有效期"`
+	RefreshTTL time.Duration `yaml:"refresh_ttl" env:"BLOG_JWT_REFRESH_TTL" flag:"jwt-refresh-ttl" usage:"refresh token 有效期"`
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level string `yaml:"level" env:"BLOG_LOG_LEVEL" flag:"log-level" usage:"日志级别: debug、info、warn、error"`
+}
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allow_origins" env:"BLOG_CORS_ORIGINS" flag:"cors-origins" usage:"允许跨域访问的来源，逗号分隔，* 表示任意来源"`
+}
+
+// MinReleaseSecretLength release 模式下 JWT 密钥的最小长度（字节）
+const MinReleaseSecretLength = 32
+
+// Default 返回默认配置，JWT 密钥没有默认值，必须在配置文件或环境变量中设置
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr: ":8080",
+			Mode: "release",
+		},
+		Database: DatabaseConfig{
+			Driver:          DriverMySQL,
+			Host:            "localhost",
+			User:            "root",
+			Name:            "blog_db",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: time.Hour,
+		},
+		JWT: JWTConfig{
+			TTL:        15 * time.Minute,
+			RefreshTTL: 7 * 24 * time.Hour,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"*"},
+		},
+	}
+}
+
+// 各驱动的默认端口和默认连接参数
+var (
+	defaultPorts = map[string]int{
+		DriverMySQL:    3306,
+		DriverPostgres: 5432,
+	}
+	defaultParams = map[string]string{
+		DriverMySQL:    "charset=utf8mb4&parseTime=True&loc=Local",
+		DriverSQLite:   "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+		DriverPostgres: "sslmode=disable",
+	}
+)
+
+// DSN 返回当前驱动的连接字符串
+func (d DatabaseConfig) DSN() string {
+	port := d.Port
+	if port == 0 {
+		port = defaultPorts[d.Driver]
+	}
+	params := d.Params
+	if params == "" {
+		params = defaultParams[d.Driver]
+	}
+
+	var dsn string
+	switch d.Driver {
+	case DriverSQLite:
+		dsn = d.Name
+		if d.IsMemory() {
+			// 内存数据库只存在于打开它的连接中，Open 时把连接池限制为一个连接
+			dsn = "file::memory:"
+		}
+	case DriverPostgres:
+		u := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(d.User, d.Password),
+			Host:   fmt.Sprintf("%s:%d", d.Host, port),
+			Path:   "/" + d.Name,
+		}
+		dsn = u.String()
+	default:
+		dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", d.User, d.Password, d.Host, port, d.Name)
+	}
+	if params != "" {
+		dsn += "?" + params
+	}
+	return dsn
+}
+
+// IsMemory 返回是否使用 sqlite 内存数据库
+func (d DatabaseConfig) IsMemory() bool {
+	return d.Driver == DriverSQLite && d.Name == SQLiteMemory
+}
+
+// Flags 命令行参数中的配置项
+//
+// 参数先于配置文件解析，因此只记录设置过的值，在 Load 中最后覆盖。
+type Flags struct {
+	path   string
+	values map[string]string
+}
+
+// RegisterFlags 在 fs 中注册 -config 以及各配置项的命令行参数
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{values: make(map[string]string)}
+	fs.StringVar(&f.path, "config", "", "YAML 配置文件路径，也可以用 "+ConfigEnv+" 环境变量指定")
+	walkFields(reflect.ValueOf(Default()).Elem(), "", func(path string, field reflect.StructField, v reflect.Value) {
+		name := field.Tag.Get("flag")
+		if name == "" {
+			return
+		}
+		usage := fmt.Sprintf("%s（环境变量 %s，默认 %s）", field.Tag.Get("usage"), field.Tag.Get("env"), formatValue(v))
+		fs.Func(name, usage, func(s string) error {
+			if err := setValue(reflect.New(v.Type()).Elem(), s); err != nil {
+				return err
+			}
+			f.values[path] = s
+			return nil
+		})
+	})
+	return f
+}
+
+// Load 加载配置：默认值、YAML 文件、环境变量、命令行参数依次覆盖，最后校验
+//
+// 配置文件路径取自 -config 参数或 ConfigEnv 环境变量，都没有时不读取文件；flags 可以为 nil。
+func Load(flags *Flags, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+
+	path := getenv(ConfigEnv)
+	if flags != nil && flags.path != "" {
+		path = flags.path
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		if err := cfg.decodeYAML(data); err != nil {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+	}
+
+	var errs []error
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.StructField, v reflect.Value) {
+		name := field.Tag.Get("env")
+		if name == "" {
+			return
+		}
+		if s := getenv(name); s != "" {
+			if err := setValue(v, s); err != nil {
+				errs = append(errs, fmt.Errorf("环境变量 %s: %w", name, err))
+			}
+		}
+		if flags == nil {
+			return
+		}
+		if s, ok := flags.values[path]; ok {
+			// 参数在解析时已经校验过格式
+			setValue(v, s)
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decodeYAML 解析 YAML 配置，未知的字段报告为错误，避免拼错的配置项被静默忽略
+func (c *Config) decodeYAML(data []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// Validate 校验配置，返回所有不合法的配置项
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, field, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr", "不能为空")
+	switch c.Server.Mode {
+	case "debug", "release", "test":
+	default:
+		check(false, "server.mode", "未知的运行模式 %q（可选 debug、release、test）", c.Server.Mode)
+	}
+
+	db := c.Database
+	switch db.Driver {
+	case DriverMySQL, DriverPostgres:
+		check(db.Host != "", "database.host", "不能为空")
+		check(db.Port >= 0 && db.Port <= 65535, "database.port", "端口 %d 不在 0 到 65535 之间（0 表示驱动的默认端口）", db.Port)
+		check(db.User != "", "database.user", "不能为空")
+	case DriverSQLite:
+	default:
+		check(false, "database.driver", "未知的数据库驱动 %q（可选 mysql、sqlite、postgres）", db.Driver)
+	}
+	check(db.Name != "", "database.name", "不能为空")
+	check(db.MaxOpenConns >= 0, "database.max_open_conns", "不能为负数")
+	check(db.MaxIdleConns >= 0, "database.max_idle_conns", "不能为负数")
+	check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns, "database.max_idle_conns",
+		"%d 大于 max_open_conns %d", db.MaxIdleConns, db.MaxOpenConns)
+	check(db.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "不能为负数")
+
+	check(c.JWT.Secret != "", "jwt.secret", "不能为空，请在配置文件或 BLOG_JWT_SECRET 环境变量中设置")
+	if c.Server.Mode == "release" && c.JWT.Secret != "" {
+		check(len(c.JWT.Secret) >= MinReleaseSecretLength, "jwt.secret",
+			"release 模式下至少需要 %d 字节，当前只有 %d 字节", MinReleaseSecretLength, len(c.JWT.Secret))
+	}
+	check(c.JWT.TTL > 0, "jwt.ttl", "必须大于 0")
+	check(c.JWT.RefreshTTL >= c.JWT.TTL, "jwt.refresh_ttl", "%s 小于 access token 的有效期 %s", c.JWT.RefreshTTL, c.JWT.TTL)
+
+	_, err := logrus.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level", "未知的日志级别 %q", c.Log.Level)
+
+	check(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins", "不能为空，允许任意来源时使用 *")
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "",
+			"cors.allow_origins", "无效的来源 %q，应为 * 或 https://example.com 的形式", origin)
+	}
+
+	return errors.Join(errs...)
+}
+
+// LogLevel 返回日志级别，配置已经过 Validate 校验
+func (c *Config) LogLevel() logrus.Level {
+	level, err := logrus.ParseLevel(c.Log.Level)
+	if err != nil {
+		return logrus.InfoLevel
+	}
+	return level
+}
+
+// walkFields 遍历结构体的叶子字段，path 为以 yaml 标签拼接的路径，例如 database.host
+func walkFields(v reflect.Value, prefix string, fn func(path string, field reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := prefix + field.Tag.Get("yaml")
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			walkFields(v.Field(i), path+".", fn)
+			continue
+		}
+		fn(path, field, v.Field(i))
+	}
+}
+
+// setValue 把字符串形式的配置值写入字段，列表以逗号分隔
+func setValue(v reflect.Value, s string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(s)
+	case int:
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("无效的整数 %q", s)
+		}
+		v.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("无效的时长 %q，例如 30s、15m、24h", s)
+		}
+		v.SetInt(int64(d))
+	case []string:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的配置类型 %s", v.Type())
+	}
+	return nil
+}
+
+// formatValue 返回配置值的字符串形式，用于参数说明
+func formatValue(v reflect.Value) string {
+	if items, ok := v.Interface().([]string); ok {
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// env 返回读取 vars 的 getenv
+func env(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+// writeConfig 把 YAML 写入临时文件并返回路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+// TestLoadPrecedence 测试默认值、配置文件、环境变量和命令行参数的覆盖顺序
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  addr: ":9000"
+database:
+  host: db.internal
+  port: 3307
+  max_open_conns: 50
+jwt:
+  secret: `+secret+`
+  ttl: 1h
+cors:
+  allow_origins: ["https://a.example.com"]
+`)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-config", path, "-db-port", "3309", "-log-level", "warn"}))
+
+	cfg, err := Load(flags, env(map[string]string{
+		"BLOG_DB_PORT":      "3308",
+		"BLOG_DB_PASSWORD":  "s3cret",
+		"BLOG_CORS_ORIGINS": "https://b.example.com, https://c.example.com",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.Server.Addr, "配置文件覆盖默认值")
+	assert.Equal(t, "release", cfg.Server.Mode, "未设置的项保留默认值")
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 3309, cfg.Database.Port, "命令行参数优先于环境变量和配置文件")
+	assert.Equal(t, "s3cret", cfg.Database.Password, "环境变量覆盖配置文件")
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, time.Hour, cfg.JWT.TTL)
+	assert.Equal(t, "warn", cfg.Log.Level)
+	assert.Equal(t, []string{"https://b.example.com", "https://c.example.com"}, cfg.CORS.AllowOrigins)
+	assert.Equal(t, "root:s3cret@tcp(db.internal:3309)/blog_db?charset=utf8mb4&parseTime=True&loc=Local", cfg.Database.DSN())
+}
+
+// TestLoadConfigEnv 测试用环境变量指定配置文件，没有配置文件时只使用默认值和环境变量
+func TestLoadConfigEnv(t *testing.T) {
+	path := writeConfig(t, "server:\n  mode: debug\njwt:\n  secret: dev\n")
+	cfg, err := Load(nil, env(map[string]string{ConfigEnv: path}))
+	require.NoError(t, err)
+	assert.Equal(t, "debug", cfg.Server.Mode)
+
+	cfg, err = Load(nil, env(map[string]string{"BLOG_JWT_SECRET": secret}))
+	require.NoError(t, err)
+	assert.Equal(t, Default().Database, cfg.Database)
+}
+
+// TestDSN 测试各驱动的连接字符串
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		db       DatabaseConfig
+		expected string
+		desc     string
+	}{
+		{
+			DatabaseConfig{Driver: DriverMySQL, Host: "db", User: "root", Password: "pw", Name: "blog_db"},
+			"root:pw@tcp(db:3306)/blog_db?charset=utf8mb4&parseTime=True&loc=Local",
+			"mysql 默认端口和参数",
+		},
+		{
+			DatabaseConfig{Driver: DriverMySQL, Host: "db", Port: 3307, User: "root", Name: "blog_db", Params: "parseTime=True"},
+			"root:@tcp(db:3307)/blog_db?parseTime=True",
+			"mysql 指定端口和参数",
+		},
+		{
+			DatabaseConfig{Driver: DriverPostgres, Host: "db", User: "blog", Password: "p@ss/word", Name: "blog_db"},
+			"postgres://blog:p%40ss%2Fword@db:5432/blog_db?sslmode=disable",
+			"postgres 转义密码",
+		},
+		{
+			DatabaseConfig{Driver: DriverSQLite, Host: "ignored", Name: "data/blog.db", Params: "_pragma=foreign_keys(1)"},
+			"data/blog.db?_pragma=foreign_keys(1)",
+			"sqlite 文件",
+		},
+		{
+			MemoryDatabase(),
+			"file::memory:?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+			"sqlite 内存数据库",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.db.DSN())
+		})
+	}
+}
+
+// TestLoadErrors 测试配置文件和环境变量的格式错误
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		vars     map[string]string
+		expected string
+		desc     string
+	}{
+		{"", map[string]string{"BLOG_DB_PORT": "abc"}, `环境变量 BLOG_DB_PORT: 无效的整数 "abc"`, "无效的整数"},
+		{"", map[string]string{"BLOG_JWT_TTL": "1d"}, "无效的时长", "无效的时长"},
+		{"databse:\n  host: x\n", nil, "field databse not found", "拼错的配置项"},
+		{"jwt:\n  ttl: forever\n", nil, "解析配置文件", "配置文件中无效的时长"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			vars := map[string]string{"BLOG_JWT_SECRET": secret}
+			if test.yaml != "" {
+				vars[ConfigEnv] = writeConfig(t, test.yaml)
+			}
+			for k, v := range test.vars {
+				vars[k] = v
+			}
+			_, err := Load(nil, env(vars))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expected)
+		})
+	}
+
+	_, err := Load(nil, env(map[string]string{ConfigEnv: filepath.Join(t.TempDir(), "missing.yaml")}))
+	assert.ErrorContains(t, err, "读取配置文件失败")
+}
+
+// TestValidate 测试配置校验
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		modify   func(c *Config)
+		expected string
+		desc     string
+	}{
+		{func(c *Config) {}, "", "合法的配置"},
+		{func(c *Config) { c.Server.Addr = "" }, "server.addr: 不能为空", "监听地址为空"},
+		{func(c *Config) { c.Server.Mode = "prod" }, `server.mode: 未知的运行模式 "prod"`, "未知的运行模式"},
+		{func(c *Config) { c.Database.Port = 70000 }, "database.port: 端口 70000 不在 0 到 65535 之间（0 表示驱动的默认端口）", "端口越界"},
+		{func(c *Config) { c.Database.Port = 0 }, "", "驱动的默认端口"},
+		{func(c *Config) { c.Database.Driver = "oracle" }, `database.driver: 未知的数据库驱动 "oracle"`, "未知的数据库驱动"},
+		{func(c *Config) { c.Database.Driver, c.Database.Host, c.Database.User = DriverPostgres, "", "" }, "database.host: 不能为空", "postgres 缺少主机"},
+		{func(c *Config) { c.Database.Driver, c.Database.Host, c.Database.User = DriverSQLite, "", "" }, "", "sqlite 不需要主机和用户"},
+		{func(c *Config) { c.Database.Driver, c.Database.Name = DriverSQLite, "" }, "database.name: 不能为空", "sqlite 缺少文件路径"},
+		{func(c *Config) { c.Database.MaxIdleConns = 30 }, "database.max_idle_conns: 30 大于 max_open_conns 25", "空闲连接数过多"},
+		{func(c *Config) { c.Database.MaxOpenConns, c.Database.MaxIdleConns = 0, 30 }, "", "不限制打开连接数"},
+		{func(c *Config) { c.JWT.Secret = "" }, "jwt.secret: 不能为空", "缺少密钥"},
+		{func(c *Config) { c.JWT.Secret = "short" }, "jwt.secret: release 模式下至少需要 32 字节", "release 模式的短密钥"},
+		{func(c *Config) { c.Server.Mode, c.JWT.Secret = "debug", "short" }, "", "debug 模式允许短密钥"},
+		{func(c *Config) { c.JWT.TTL = 0 }, "jwt.ttl: 必须大于 0", "有效期为零"},
+		{func(c *Config) { c.JWT.RefreshTTL = time.Minute }, "jwt.refresh_ttl: 1m0s 小于 access token 的有效期 15m0s", "refresh token 有效期过短"},
+		{func(c *Config) { c.Log.Level = "verbose" }, `log.level: 未知的日志级别 "verbose"`, "未知的日志级别"},
+		{func(c *Config) { c.CORS.AllowOrigins = nil }, "cors.allow_origins: 不能为空", "没有跨域来源"},
+		{func(c *Config) { c.CORS.AllowOrigins = []string{"example.com"} }, `无效的来源 "example.com"`, "来源缺少协议"},
+		{func(c *Config) { c.CORS.AllowOrigins = []string{"https://example.com/app"} }, `无效的来源 "https://example.com/app"`, "来源带路径"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := Default()
+			cfg.JWT.Secret = secret
+			test.modify(cfg)
+			err := cfg.Validate()
+			if test.expected == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, test.expected)
+		})
+	}
+}
+
+// TestValidateReportsAll 测试校验一次报告所有错误
+func TestValidateReportsAll(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = ""
+	cfg.Database.Port = -1
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.addr")
+	assert.Contains(t, err.Error(), "database.port")
+	assert.Contains(t, err.Error(), "jwt.secret")
+}
+
+// TestRegisterFlags 测试命令行参数的格式校验，密码和密钥没有对应的参数
+func TestRegisterFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	RegisterFlags(fs)
+
+	assert.Error(t, fs.Parse([]string{"-jwt-ttl", "soon"}))
+	assert.NotNil(t, fs.Lookup("addr"))
+	assert.NotNil(t, fs.Lookup("cors-origins"))
+	assert.Nil(t, fs.Lookup("db-password"))
+	assert.Nil(t, fs.Lookup("jwt-secret"))
+}
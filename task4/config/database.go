@@ -4,39 +4,75 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-// 数据库配置
-const (
-	DBUser     = "root"
-	DBPassword = "123456"
-	DBHost     = "localhost"
-	DBPort     = "3306"
-	DBName     = "blog_db"
-)
+// InitDB 按配置初始化全局数据库连接，debug 模式下记录所有 SQL
+func InitDB(cfg DatabaseConfig, debug bool) error {
+	db, err := OpenDB(cfg, debug)
+	if err != nil {
+		return err
+	}
+	DB = db
 
-// InitDB 初始化数据库连接
-func InitDB() {
-	// 构建DSN
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		DBUser, DBPassword, DBHost, DBPort, DBName)
+	log.Printf("数据库连接成功！（%s）", cfg.Driver)
+	return nil
+}
+
+// OpenDB 按配置的驱动打开数据库并设置连接池
+func OpenDB(cfg DatabaseConfig, debug bool) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverMySQL:
+		dialector = mysql.Open(cfg.DSN())
+	case DriverSQLite:
+		dialector = sqlite.Open(cfg.DSN())
+	case DriverPostgres:
+		dialector = postgres.Open(cfg.DSN())
+	default:
+		return nil, fmt.Errorf("未知的数据库驱动 %q", cfg.Driver)
+	}
 
-	var err error
-	// 连接数据库
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	logLevel := logger.Warn
+	if debug {
+		logLevel = logger.Info
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+		// 把各驱动的唯一约束等错误统一为 gorm.ErrDuplicatedKey 等错误
+		TranslateError: true,
 	})
+	if err != nil {
+		return nil, err
+	}
 
+	// 设置连接池
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatal("数据库连接失败:", err)
+		return nil, err
 	}
+	if cfg.IsMemory() {
+		// 每个连接都有独立的内存数据库，只保留一个永不过期的连接
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		return db, nil
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
+}
 
-	log.Println("数据库连接成功！")
+// MemoryDatabase 返回 sqlite 内存数据库的配置，供测试使用
+func MemoryDatabase() DatabaseConfig {
+	return DatabaseConfig{Driver: DriverSQLite, Name: SQLiteMemory}
 }
 
 // GetDB 获取数据库实例
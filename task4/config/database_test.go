@@ -0,0 +1,47 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOpenDBMemory 测试内存数据库在多次查询之间保留数据，且每次打开互相独立
+func TestOpenDBMemory(t *testing.T) {
+	db, err := OpenDB(MemoryDatabase(), false)
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)").Error)
+	require.NoError(t, db.Exec("INSERT INTO notes (body) VALUES (?)", "hello").Error)
+
+	var count int64
+	require.NoError(t, db.Table("notes").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	var foreignKeys int
+	require.NoError(t, db.Raw("PRAGMA foreign_keys").Scan(&foreignKeys).Error)
+	assert.Equal(t, 1, foreignKeys, "默认开启外键约束")
+
+	other, err := OpenDB(MemoryDatabase(), false)
+	require.NoError(t, err)
+	assert.False(t, other.Migrator().HasTable("notes"), "另一个内存数据库看不到这张表")
+}
+
+// TestOpenDBFile 测试 sqlite 文件数据库
+func TestOpenDBFile(t *testing.T) {
+	cfg := Default().Database
+	cfg.Driver = DriverSQLite
+	cfg.Name = filepath.Join(t.TempDir(), "blog.db")
+
+	db, err := OpenDB(cfg, false)
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY)").Error)
+	assert.FileExists(t, cfg.Name)
+}
+
+// TestOpenDBUnknownDriver 测试未知的驱动
+func TestOpenDBUnknownDriver(t *testing.T) {
+	_, err := OpenDB(DatabaseConfig{Driver: "oracle"}, false)
+	assert.EqualError(t, err, `未知的数据库驱动 "oracle"`)
+}
@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
-	"task4/config"
 	"task4/models"
+	"task4/repository"
 	"task4/utils"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +14,15 @@ import (
 )
 
 // AuthController 认证控制器
-type AuthController struct{}
+type AuthController struct {
+	users  repository.UserRepository
+	tokens repository.TokenRepository
+}
+
+// NewAuthController 创建认证控制器，tokens 保存 refresh token 和撤销列表
+func NewAuthController(users repository.UserRepository, tokens repository.TokenRepository) *AuthController {
+	return &AuthController{users: users, tokens: tokens}
+}
 
 // Register 用户注册
 func (ac *AuthController) Register(c *gin.Context) {
@@ -27,8 +37,15 @@ func (ac *AuthController) Register(c *gin.Context) {
 	}
 
 	// 检查用户名是否已存在
-	var existingUser models.User
-	if err := config.GetDB().Where("username = ? OR email = ?", req.Username, req.Email).First(&existingUser).Error; err == nil {
+	exists, err := ac.users.ExistsByUsernameOrEmail(req.Username, req.Email)
+	if err != nil {
+		logrus.WithError(err).Error("查询用户失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "创建用户失败",
+		})
+		return
+	}
+	if exists {
 		c.JSON(http.StatusConflict, gin.H{
 			"error": "用户名或邮箱已存在",
 		})
@@ -42,7 +59,14 @@ func (ac *AuthController) Register(c *gin.Context) {
 		Email:    req.Email,
 	}
 
-	if err := config.GetDB().Create(&user).Error; err != nil {
+	if err := ac.users.Create(&user); err != nil {
+		// 并发注册同一个用户名时由唯一约束兜底
+		if errors.Is(err, repository.ErrDuplicate) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "用户名或邮箱已存在",
+			})
+			return
+		}
 		logrus.WithError(err).Error("创建用户失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "创建用户失败",
@@ -74,13 +98,20 @@ func (ac *AuthController) Login(c *gin.Context) {
 	}
 
 	// 查找用户
-	var user models.User
-	if err := config.GetDB().Where("username = ?", req.Username).First(&user).Error; err != nil {
+	user, err := ac.users.FindByUsername(req.Username)
+	if errors.Is(err, repository.ErrNotFound) {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": "用户名或密码错误",
 		})
 		return
 	}
+	if err != nil {
+		logrus.WithError(err).Error("查询用户失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "登录失败",
+		})
+		return
+	}
 
 	// 验证密码
 	if !user.CheckPassword(req.Password) {
@@ -90,8 +121,16 @@ func (ac *AuthController) Login(c *gin.Context) {
 		return
 	}
 
-	// 生成JWT token
-	token, err := utils.GenerateToken(user.ID, user.Username)
+	// 开始新的登录会话，签发 access token 和 refresh token
+	familyID, err := utils.RandomID()
+	if err != nil {
+		logrus.WithError(err).Error("生成会话ID失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "登录失败",
+		})
+		return
+	}
+	tokens, err := ac.issueTokens(user, familyID, nil)
 	if err != nil {
 		logrus.WithError(err).Error("生成token失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -101,13 +140,173 @@ func (ac *AuthController) Login(c *gin.Context) {
 	}
 
 	logrus.WithField("user_id", user.ID).Info("用户登录成功")
+	tokens["message"] = "登录成功"
+	tokens["user"] = gin.H{
+		"id":       user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Refresh 用 refresh token 换取新的 access token 和 refresh token，旧的 refresh token 随即失效
+func (ac *AuthController) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "参数验证失败",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	old, err := ac.tokens.FindRefreshToken(utils.HashToken(req.RefreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "无效的刷新令牌",
+		})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("查询刷新令牌失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "刷新失败",
+		})
+		return
+	}
+
+	if old.RevokedAt != nil {
+		ac.revokeReusedFamily(old)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "刷新令牌已失效，请重新登录",
+		})
+		return
+	}
+	if !old.Active(time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "刷新令牌已过期，请重新登录",
+		})
+		return
+	}
+
+	user, err := ac.users.FindByID(old.UserID)
+	if err != nil {
+		logrus.WithError(err).Error("查询用户失败")
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "无效的刷新令牌",
+		})
+		return
+	}
+
+	tokens, err := ac.issueTokens(user, old.FamilyID, old)
+	if errors.Is(err, repository.ErrRevoked) {
+		// 另一个请求刚刚使用了同一个 refresh token
+		ac.revokeReusedFamily(old)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "刷新令牌已失效，请重新登录",
+		})
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("生成token失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "刷新失败",
+		})
+		return
+	}
+
+	tokens["message"] = "刷新成功"
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout 注销当前登录会话：撤销当前 access token 和会话的所有 refresh token
+func (ac *AuthController) Logout(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+	if err := ac.tokens.RevokeFamily(claims.SessionID); err != nil {
+		logrus.WithError(err).Error("撤销会话失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "注销失败",
+		})
+		return
+	}
+	if err := ac.tokens.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		logrus.WithError(err).Error("撤销token失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "注销失败",
+		})
+		return
+	}
+
+	logrus.WithField("user_id", claims.UserID).Info("用户注销成功")
 	c.JSON(http.StatusOK, gin.H{
-		"message": "登录成功",
-		"token":   token,
-		"user": gin.H{
-			"id":       user.ID,
-			"username": user.Username,
-			"email":    user.Email,
-		},
+		"message": "注销成功",
 	})
 }
+
+// LogoutAll 注销所有设备：撤销用户的所有 refresh token，所有会话的 access token 随之失效
+func (ac *AuthController) LogoutAll(c *gin.Context) {
+	claims := c.MustGet("claims").(*utils.Claims)
+	if err := ac.tokens.RevokeUserTokens(claims.UserID); err != nil {
+		logrus.WithError(err).Error("撤销用户的token失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "注销失败",
+		})
+		return
+	}
+	if err := ac.tokens.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time); err != nil {
+		logrus.WithError(err).Error("撤销token失败")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "注销失败",
+		})
+		return
+	}
+
+	logrus.WithField("user_id", claims.UserID).Info("用户已注销所有设备")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已注销所有设备",
+	})
+}
+
+// issueTokens 为会话签发 access token 和 refresh token；old 不为空时轮换 old
+func (ac *AuthController) issueTokens(user *models.User, familyID string, old *models.RefreshToken) (gin.H, error) {
+	refreshToken, hash, err := utils.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	next := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if old == nil {
+		err = ac.tokens.CreateRefreshToken(next)
+	} else {
+		err = ac.tokens.RotateRefreshToken(old, next)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateToken(user.ID, user.Username, familyID)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":         token,
+		"token_type":    "Bearer",
+		"expires_in":    int(utils.TokenTTL().Seconds()),
+		"refresh_token": refreshToken,
+	}, nil
+}
+
+// revokeReusedFamily 已撤销的 refresh token 被再次使用，说明可能已被盗用，撤销整个会话
+func (ac *AuthController) revokeReusedFamily(token *models.RefreshToken) {
+	logrus.WithFields(logrus.Fields{
+		"user_id":   token.UserID,
+		"family_id": token.FamilyID,
+	}).Warn("检测到已撤销的刷新令牌被重复使用，撤销整个会话")
+	if err := ac.tokens.RevokeFamily(token.FamilyID); err != nil {
+		logrus.WithError(err).Error("撤销会话失败")
+	}
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"task4/internal/apitest/fixture"
+	"task4/models"
+	"task4/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRegister 测试用户注册
+func TestRegister(t *testing.T) {
+	runRequests(t, []request{
+		{"POST", "/api/v1/auth/register", `{"username":"carol","password":"secret1","email":"carol@example.com"}`, 0, http.StatusCreated, `"username":"carol"`, "注册成功"},
+		{"POST", "/api/v1/auth/register", `{"username":"alice","password":"secret1","email":"new@example.com"}`, 0, http.StatusConflict, "用户名或邮箱已存在", "用户名已存在"},
+		{"POST", "/api/v1/auth/register", `{"username":"carol","password":"secret1","email":"alice@example.com"}`, 0, http.StatusConflict, "用户名或邮箱已存在", "邮箱已存在"},
+		{"POST", "/api/v1/auth/register", `{"username":"ca","password":"secret1","email":"carol@example.com"}`, 0, http.StatusBadRequest, "参数验证失败", "用户名太短"},
+		{"POST", "/api/v1/auth/register", `{"username":"carol","password":"123","email":"carol@example.com"}`, 0, http.StatusBadRequest, "参数验证失败", "密码太短"},
+		{"POST", "/api/v1/auth/register", `{"username":"carol","password":"secret1","email":"carol"}`, 0, http.StatusBadRequest, "参数验证失败", "邮箱格式错误"},
+		{"POST", "/api/v1/auth/register", `{`, 0, http.StatusBadRequest, "参数验证失败", "无效的 JSON"},
+	})
+
+	// 响应中不包含密码
+	w := serve(newRouter(seed(t)), "POST", "/api/v1/auth/register", `{"username":"carol","password":"secret1","email":"carol@example.com"}`, 0)
+	assert.NotContains(t, w.Body.String(), "password")
+	assert.NotContains(t, w.Body.String(), "secret1")
+}
+
+// TestLogin 测试用户登录
+func TestLogin(t *testing.T) {
+	runRequests(t, []request{
+		{"POST", "/api/v1/auth/login", `{"username":"alice","password":"` + fixture.Password + `"}`, 0, http.StatusOK, `"token"`, "登录成功"},
+		{"POST", "/api/v1/auth/login", `{"username":"alice","password":"wrong"}`, 0, http.StatusUnauthorized, "用户名或密码错误", "密码错误"},
+		{"POST", "/api/v1/auth/login", `{"username":"nobody","password":"secret1"}`, 0, http.StatusUnauthorized, "用户名或密码错误", "用户不存在"},
+		{"POST", "/api/v1/auth/login", `{"username":"alice"}`, 0, http.StatusBadRequest, "参数验证失败", "缺少密码"},
+	})
+
+	w := serve(newRouter(seed(t)), "POST", "/api/v1/auth/login", `{"username":"alice","password":"`+fixture.Password+`"}`, 0)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Token string `json:"token"`
+	}
+	decode(t, w, &resp)
+	claims, err := utils.ParseToken(resp.Token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+	assert.Equal(t, "alice", claims.Username)
+}
+
+// session 登录返回的 token
+type session struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// login 登录并返回 token
+func login(t *testing.T, r http.Handler, username string) session {
+	t.Helper()
+	w := serve(r, "POST", "/api/v1/auth/login", `{"username":"`+username+`","password":"`+fixture.Password+`"}`, 0)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var s session
+	decode(t, w, &s)
+	return s
+}
+
+// serveToken 发送带 Bearer token 的请求
+func serveToken(r http.Handler, method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// refreshBody 返回刷新请求的请求体
+func refreshBody(token string) string {
+	return `{"refresh_token":"` + token + `"}`
+}
+
+// TestLoginSession 测试登录签发的 access token 和 refresh token
+func TestLoginSession(t *testing.T) {
+	repos := seed(t)
+	s := login(t, newRouter(repos), "alice")
+	assert.Equal(t, 15*60, s.ExpiresIn)
+	require.NotEmpty(t, s.RefreshToken)
+
+	claims, err := utils.ParseToken(s.Token)
+	require.NoError(t, err)
+	assert.NotEmpty(t, claims.ID, "带有 jti")
+
+	stored, err := repos.Tokens.FindRefreshToken(utils.HashToken(s.RefreshToken))
+	require.NoError(t, err, "服务端保存 refresh token 的哈希")
+	assert.Equal(t, claims.SessionID, stored.FamilyID, "access token 属于 refresh token 的会话")
+	assert.Equal(t, uint(1), stored.UserID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+}
+
+// TestRefresh 测试 refresh token 的轮换和重复使用检测
+func TestRefresh(t *testing.T) {
+	repos := seed(t)
+	r := newRouter(repos)
+	first := login(t, r, "alice")
+
+	w := serve(r, "POST", "/api/v1/auth/refresh", refreshBody(first.RefreshToken), 0)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var second session
+	decode(t, w, &second)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken, "每次刷新签发新的 refresh token")
+	claims, err := utils.ParseToken(second.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+
+	// 旧 token 被再次使用，整个会话都被撤销
+	w = serve(r, "POST", "/api/v1/auth/refresh", refreshBody(first.RefreshToken), 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "刷新令牌已失效")
+	w = serve(r, "POST", "/api/v1/auth/refresh", refreshBody(second.RefreshToken), 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "同一会话的新 token 也失效")
+	assert.Equal(t, http.StatusUnauthorized, serveToken(r, "POST", "/api/v1/auth/logout", "", second.Token).Code, "会话的 access token 失效")
+
+	// 过期的 refresh token
+	expired, hash, err := utils.NewRefreshToken()
+	require.NoError(t, err)
+	require.NoError(t, repos.Tokens.CreateRefreshToken(&models.RefreshToken{UserID: 1, FamilyID: "f", TokenHash: hash, ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	runRequests(t, []request{
+		{"POST", "/api/v1/auth/refresh", refreshBody("unknown"), 0, http.StatusUnauthorized, "无效的刷新令牌", "未知的 token"},
+		{"POST", "/api/v1/auth/refresh", `{}`, 0, http.StatusBadRequest, "参数验证失败", "缺少 token"},
+	})
+	w = serve(r, "POST", "/api/v1/auth/refresh", refreshBody(expired), 0)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "刷新令牌已过期")
+}
+
+// TestLogout 测试注销当前会话，其他会话不受影响
+func TestLogout(t *testing.T) {
+	r := newRouter(seed(t))
+	phone := login(t, r, "alice")
+	laptop := login(t, r, "alice")
+
+	w := serveToken(r, "POST", "/api/v1/auth/logout", "", phone.Token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = serveToken(r, "POST", "/api/v1/auth/logout", "", phone.Token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "注销后 access token 失效")
+	assert.Contains(t, w.Body.String(), "Token has been revoked")
+	assert.Equal(t, http.StatusUnauthorized, serve(r, "POST", "/api/v1/auth/refresh", refreshBody(phone.RefreshToken), 0).Code, "注销后 refresh token 失效")
+
+	assert.Equal(t, http.StatusOK, serve(r, "POST", "/api/v1/auth/refresh", refreshBody(laptop.RefreshToken), 0).Code, "其他会话不受影响")
+	assert.Equal(t, http.StatusUnauthorized, serve(r, "POST", "/api/v1/auth/logout", "", 0).Code, "需要认证")
+}
+
+// TestLogoutAll 测试注销所有设备，其他用户不受影响
+func TestLogoutAll(t *testing.T) {
+	r := newRouter(seed(t))
+	phone := login(t, r, "alice")
+	laptop := login(t, r, "alice")
+	bob := login(t, r, "bob")
+
+	w := serveToken(r, "POST", "/api/v1/auth/logout-all", "", phone.Token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "已注销所有设备")
+
+	for _, s := range []session{phone, laptop} {
+		assert.Equal(t, http.StatusUnauthorized, serveToken(r, "POST", "/api/v1/auth/logout", "", s.Token).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(r, "POST", "/api/v1/auth/refresh", refreshBody(s.RefreshToken), 0).Code)
+	}
+	assert.Equal(t, http.StatusOK, serve(r, "POST", "/api/v1/auth/refresh", refreshBody(bob.RefreshToken), 0).Code, "其他用户不受影响")
+
+	// 重新登录后可以正常使用
+	again := login(t, r, "alice")
+	assert.Equal(t, http.StatusOK, serveToken(r, "POST", "/api/v1/auth/logout", "", again.Token).Code)
+}
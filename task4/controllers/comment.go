@@ -4,15 +4,23 @@ import (
 	"net/http"
 	"strconv"
 
-	"task4/config"
 	"task4/models"
+	"task4/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 // CommentController 评论控制器
-type CommentController struct{}
+type CommentController struct {
+	comments repository.CommentRepository
+	posts    repository.PostRepository
+}
+
+// NewCommentController 创建评论控制器，posts 用于检查评论的文章是否存在
+func NewCommentController(comments repository.CommentRepository, posts repository.PostRepository) *CommentController {
+	return &CommentController{comments: comments, posts: posts}
+}
 
 // CreateComment 创建评论
 func (cc *CommentController) CreateComment(c *gin.Context) {
@@ -36,11 +44,8 @@ func (cc *CommentController) CreateComment(c *gin.Context) {
 	}
 
 	// 检查文章是否存在
-	var post models.Post
-	if err := config.GetDB().First(&post, req.PostID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "文章不存在",
-		})
+	if _, err := cc.posts.FindByID(req.PostID); err != nil {
+		postNotFound(c, err)
 		return
 	}
 
@@ -51,7 +56,7 @@ func (cc *CommentController) CreateComment(c *gin.Context) {
 		PostID:  req.PostID,
 	}
 
-	if err := config.GetDB().Create(&comment).Error; err != nil {
+	if err := cc.comments.Create(&comment); err != nil {
 		logrus.WithError(err).Error("创建评论失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "创建评论失败",
@@ -59,9 +64,6 @@ func (cc *CommentController) CreateComment(c *gin.Context) {
 		return
 	}
 
-	// 预加载用户信息
-	config.GetDB().Preload("User").First(&comment, comment.ID)
-
 	logrus.WithField("comment_id", comment.ID).Info("评论创建成功")
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "评论创建成功",
@@ -80,28 +82,17 @@ func (cc *CommentController) GetCommentsByPost(c *gin.Context) {
 	}
 
 	// 检查文章是否存在
-	var post models.Post
-	if err := config.GetDB().First(&post, postID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "文章不存在",
-		})
+	if _, err := cc.posts.FindByID(uint(postID)); err != nil {
+		postNotFound(c, err)
 		return
 	}
 
 	// 分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	offset := (page - 1) * pageSize
+	page, pageSize := pagination(c, 20)
 
 	// 查询评论列表
-	var comments []models.Comment
-	if err := config.GetDB().
-		Where("post_id = ?", postID).
-		Preload("User").
-		Order("created_at ASC").
-		Limit(pageSize).
-		Offset(offset).
-		Find(&comments).Error; err != nil {
+	comments, total, err := cc.comments.ListByPost(uint(postID), (page-1)*pageSize, pageSize)
+	if err != nil {
 		logrus.WithError(err).Error("获取评论列表失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取评论列表失败",
@@ -109,10 +100,6 @@ func (cc *CommentController) GetCommentsByPost(c *gin.Context) {
 		return
 	}
 
-	// 获取总数
-	var total int64
-	config.GetDB().Model(&models.Comment{}).Where("post_id = ?", postID).Count(&total)
-
 	c.JSON(http.StatusOK, gin.H{
 		"comments": comments,
 		"pagination": gin.H{
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"task4/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreateComment 测试创建评论
+func TestCreateComment(t *testing.T) {
+	runRequests(t, []request{
+		{"POST", "/api/v1/comments", `{"content":"写得好","post_id":1}`, 1, http.StatusCreated, `"username":"alice"`, "创建成功"},
+		{"POST", "/api/v1/comments", `{"content":"写得好","post_id":1}`, 0, http.StatusUnauthorized, "用户未认证", "未认证"},
+		{"POST", "/api/v1/comments", `{"content":"写得好","post_id":42}`, 1, http.StatusNotFound, "文章不存在", "文章不存在"},
+		{"POST", "/api/v1/comments", `{"content":"","post_id":1}`, 1, http.StatusBadRequest, "参数验证失败", "缺少内容"},
+		{"POST", "/api/v1/comments", `{"content":"写得好"}`, 1, http.StatusBadRequest, "参数验证失败", "缺少文章"},
+	})
+}
+
+// TestGetCommentsByPost 测试文章的评论列表和分页
+func TestGetCommentsByPost(t *testing.T) {
+	runRequests(t, []request{
+		{"GET", "/api/v1/comments/post/42", "", 0, http.StatusNotFound, "文章不存在", "文章不存在"},
+		{"GET", "/api/v1/comments/post/abc", "", 0, http.StatusBadRequest, "无效的文章ID", "无效的 ID"},
+	})
+
+	repos := seed(t)
+	for i := 3; i <= 5; i++ {
+		require.NoError(t, repos.Comments.Create(&models.Comment{Content: fmt.Sprintf("第%d条", i), UserID: 1, PostID: 1}))
+	}
+	r := newRouter(repos)
+
+	tests := []struct {
+		query    string
+		pageSize int
+		contents []string
+		desc     string
+	}{
+		{"", 20, []string{"沙发", "板凳", "第3条", "第4条", "第5条"}, "默认分页，按时间排列"},
+		{"?page=2&page_size=2", 2, []string{"第3条", "第4条"}, "第二页"},
+		{"?page=9&page_size=2", 2, []string{}, "超出范围"},
+		{"?page=9223372036854775807", 20, []string{}, "页码过大"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := serve(r, "GET", "/api/v1/comments/post/1"+test.query, "", 0)
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp struct {
+				Comments   []models.Comment `json:"comments"`
+				Pagination struct {
+					PageSize int   `json:"page_size"`
+					Total    int64 `json:"total"`
+				} `json:"pagination"`
+			}
+			decode(t, w, &resp)
+			contents := []string{}
+			for _, comment := range resp.Comments {
+				contents = append(contents, comment.Content)
+				assert.NotEmpty(t, comment.User.Username, "包含作者信息")
+			}
+			assert.Equal(t, test.contents, contents)
+			assert.Equal(t, test.pageSize, resp.Pagination.PageSize)
+			assert.Equal(t, int64(5), resp.Pagination.Total)
+		})
+	}
+}
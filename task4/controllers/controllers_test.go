@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"task4/internal/apitest/fixture"
+	"task4/middleware"
+	"task4/models"
+	"task4/repository"
+	"task4/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	logrus.SetOutput(io.Discard)
+	utils.InitJWT("controllers-test-secret", 15*time.Minute, time.Hour)
+}
+
+// userHeader 测试中代替 JWT 的请求头，值为当前用户的 ID
+const userHeader = "X-Test-User"
+
+// testAuth 按 userHeader 设置当前用户，没有该请求头时不设置，用于测试控制器的未认证分支
+func testAuth(c *gin.Context) {
+	if id, err := strconv.ParseUint(c.GetHeader(userHeader), 10, 32); err == nil {
+		c.Set("user_id", uint(id))
+	}
+	c.Next()
+}
+
+// newRouter 返回注册了所有控制器的路由，路径与 routes.SetupRoutes 一致
+func newRouter(repos repository.Repositories) *gin.Engine {
+	auth := NewAuthController(repos.Users, repos.Tokens)
+	posts := NewPostController(repos.Posts)
+	comments := NewCommentController(repos.Comments, repos.Posts)
+
+	r := gin.New()
+	v1 := r.Group("/api/v1")
+	v1.POST("/auth/register", auth.Register)
+	v1.POST("/auth/login", auth.Login)
+	v1.POST("/auth/refresh", auth.Refresh)
+	v1.POST("/auth/logout", middleware.AuthMiddleware(repos.Tokens), auth.Logout)
+	v1.POST("/auth/logout-all", middleware.AuthMiddleware(repos.Tokens), auth.LogoutAll)
+	v1.GET("/posts", posts.GetPosts)
+	v1.GET("/posts/:id", posts.GetPost)
+	v1.POST("/posts", testAuth, posts.CreatePost)
+	v1.PUT("/posts/:id", testAuth, posts.UpdatePost)
+	v1.DELETE("/posts/:id", testAuth, posts.DeletePost)
+	v1.GET("/comments/post/:post_id", comments.GetCommentsByPost)
+	v1.POST("/comments", testAuth, comments.CreateComment)
+	return r
+}
+
+// seed 测试数据：alice（ID 1）的一篇文章（ID 1）带两条评论，bob（ID 2）没有文章，密码都是 fixture.Password
+func seed(t *testing.T) repository.Repositories {
+	t.Helper()
+	repos := repository.NewMemoryRepositories()
+	for _, name := range []string{"alice", "bob"} {
+		fixture.User(t, repos.Users, name)
+	}
+	require.NoError(t, repos.Posts.Create(&models.Post{Title: "第一篇", Content: "内容", UserID: 1}))
+	for _, content := range []string{"沙发", "板凳"} {
+		require.NoError(t, repos.Comments.Create(&models.Comment{Content: content, UserID: 2, PostID: 1}))
+	}
+	return repos
+}
+
+// request 描述一次请求和期望的响应
+type request struct {
+	method   string
+	path     string
+	body     string
+	user     uint // 0 表示未认证
+	status   int
+	expected string // 响应中应包含的内容
+	desc     string
+}
+
+// serve 发送请求并返回响应
+func serve(r http.Handler, method, path, body string, user uint) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if user != 0 {
+		req.Header.Set(userHeader, strconv.FormatUint(uint64(user), 10))
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// runRequests 对每个请求使用新的测试数据，检查状态码和响应内容
+func runRequests(t *testing.T, tests []request) {
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := serve(newRouter(seed(t)), test.method, test.path, test.body, test.user)
+			assert.Equal(t, test.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), test.expected)
+		})
+	}
+}
+
+// decode 解析 JSON 响应
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), v), w.Body.String())
+}
+
+var errBroken = errors.New("数据库不可用")
+
+// brokenPosts 所有操作都失败的文章仓库，用于测试 500 响应
+type brokenPosts struct{ repository.PostRepository }
+
+func (brokenPosts) Create(*models.Post) error                   { return errBroken }
+func (brokenPosts) FindByID(uint) (*models.Post, error)         { return nil, errBroken }
+func (brokenPosts) FindWithComments(uint) (*models.Post, error) { return nil, errBroken }
+func (brokenPosts) List(int, int) ([]models.Post, int64, error) { return nil, 0, errBroken }
+func (brokenPosts) Update(*models.Post) error                   { return errBroken }
+func (brokenPosts) Delete(uint) error                           { return errBroken }
+
+// brokenTokens 所有操作都失败的 token 仓库
+type brokenTokens struct{ repository.TokenRepository }
+
+func (brokenTokens) FindRefreshToken(string) (*models.RefreshToken, error) { return nil, errBroken }
+func (brokenTokens) IsAccessTokenRevoked(string, string) (bool, error)     { return false, errBroken }
+
+// brokenUsers 所有操作都失败的用户仓库
+type brokenUsers struct{ repository.UserRepository }
+
+func (brokenUsers) FindByUsername(string) (*models.User, error)          { return nil, errBroken }
+func (brokenUsers) ExistsByUsernameOrEmail(string, string) (bool, error) { return false, errBroken }
+
+// TestRepositoryErrors 测试仓库出错时返回 500，且不把内部错误暴露给客户端
+func TestRepositoryErrors(t *testing.T) {
+	repos := repository.Repositories{
+		Users:    brokenUsers{},
+		Posts:    brokenPosts{},
+		Comments: repository.NewMemoryRepositories().Comments,
+		Tokens:   brokenTokens{},
+	}
+	r := newRouter(repos)
+
+	tests := []request{
+		{"POST", "/api/v1/auth/register", `{"username":"carol","password":"secret1","email":"carol@example.com"}`, 0, 500, "创建用户失败", "注册"},
+		{"POST", "/api/v1/auth/login", `{"username":"alice","password":"secret1"}`, 0, 500, "登录失败", "登录"},
+		{"POST", "/api/v1/auth/refresh", `{"refresh_token":"abc"}`, 0, 500, "刷新失败", "刷新"},
+		{"GET", "/api/v1/posts", "", 0, 500, "获取文章列表失败", "文章列表"},
+		{"GET", "/api/v1/posts/1", "", 0, 500, "查询文章失败", "文章详情"},
+		{"POST", "/api/v1/posts", `{"title":"标题","content":"内容"}`, 1, 500, "创建文章失败", "创建文章"},
+		{"PUT", "/api/v1/posts/1", `{"title":"标题","content":"内容"}`, 1, 500, "查询文章失败", "更新文章"},
+		{"DELETE", "/api/v1/posts/1", "", 1, 500, "查询文章失败", "删除文章"},
+		{"GET", "/api/v1/comments/post/1", "", 0, 500, "查询文章失败", "评论列表"},
+		{"POST", "/api/v1/comments", `{"content":"评论","post_id":1}`, 1, 500, "查询文章失败", "创建评论"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := serve(r, test.method, test.path, test.body, test.user)
+			assert.Equal(t, test.status, w.Code)
+			assert.Contains(t, w.Body.String(), test.expected)
+			assert.NotContains(t, w.Body.String(), errBroken.Error())
+		})
+	}
+}
+
+// TestPagination 测试分页参数的解析
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query    string
+		page     int
+		pageSize int
+		desc     string
+	}{
+		{"", 1, 10, "默认值"},
+		{"page=3&page_size=5", 3, 5, "指定页码和条数"},
+		{"page=0&page_size=-1", 1, 10, "非正数"},
+		{"page=abc&page_size=x", 1, 10, "不是数字"},
+		{"page_size=1000", 1, maxPageSize, "超过最大条数"},
+		{"page=9223372036854775807&page_size=10", math.MaxInt / 10, 10, "页码过大"},
+		{"page=99999999999999999999", 1, 10, "页码超出 int 范围"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/?"+test.query, nil)
+			page, pageSize := pagination(c, 10)
+			assert.Equal(t, test.page, page)
+			assert.Equal(t, test.pageSize, pageSize)
+		})
+	}
+}
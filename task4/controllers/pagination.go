@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPageSize 每页的最大条数
+const maxPageSize = 100
+
+// pagination 解析 page 和 page_size 查询参数
+//
+// 无效的页码按第 1 页处理，无效的每页条数使用 defaultSize，超过 maxPageSize 时按 maxPageSize 处理。
+// 页码过大时限制在偏移量 (page-1)*pageSize 不溢出的范围内，这样的页总是空的。
+func pagination(c *gin.Context, defaultSize int) (page, pageSize int) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err = strconv.Atoi(c.Query("page_size"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	if maxPage := math.MaxInt / pageSize; page > maxPage {
+		page = maxPage
+	}
+	return page, pageSize
+}
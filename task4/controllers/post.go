@@ -1,18 +1,26 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
-	"task4/config"
 	"task4/models"
+	"task4/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 // PostController 文章控制器
-type PostController struct{}
+type PostController struct {
+	posts repository.PostRepository
+}
+
+// NewPostController 创建文章控制器
+func NewPostController(posts repository.PostRepository) *PostController {
+	return &PostController{posts: posts}
+}
 
 // CreatePost 创建文章
 func (pc *PostController) CreatePost(c *gin.Context) {
@@ -42,7 +50,7 @@ func (pc *PostController) CreatePost(c *gin.Context) {
 		UserID:  userID.(uint),
 	}
 
-	if err := config.GetDB().Create(&post).Error; err != nil {
+	if err := pc.posts.Create(&post); err != nil {
 		logrus.WithError(err).Error("创建文章失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "创建文章失败",
@@ -50,9 +58,6 @@ func (pc *PostController) CreatePost(c *gin.Context) {
 		return
 	}
 
-	// 预加载用户信息
-	config.GetDB().Preload("User").First(&post, post.ID)
-
 	logrus.WithField("post_id", post.ID).Info("文章创建成功")
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "文章创建成功",
@@ -62,20 +67,12 @@ func (pc *PostController) CreatePost(c *gin.Context) {
 
 // GetPosts 获取文章列表
 func (pc *PostController) GetPosts(c *gin.Context) {
-	var posts []models.Post
-
 	// 分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	offset := (page - 1) * pageSize
+	page, pageSize := pagination(c, 10)
 
 	// 查询文章列表
-	if err := config.GetDB().
-		Preload("User").
-		Order("created_at DESC").
-		Limit(pageSize).
-		Offset(offset).
-		Find(&posts).Error; err != nil {
+	posts, total, err := pc.posts.List((page-1)*pageSize, pageSize)
+	if err != nil {
 		logrus.WithError(err).Error("获取文章列表失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "获取文章列表失败",
@@ -83,10 +80,6 @@ func (pc *PostController) GetPosts(c *gin.Context) {
 		return
 	}
 
-	// 获取总数
-	var total int64
-	config.GetDB().Model(&models.Post{}).Count(&total)
-
 	c.JSON(http.StatusOK, gin.H{
 		"posts": posts,
 		"pagination": gin.H{
@@ -107,14 +100,9 @@ func (pc *PostController) GetPost(c *gin.Context) {
 		return
 	}
 
-	var post models.Post
-	if err := config.GetDB().
-		Preload("User").
-		Preload("Comments.User").
-		First(&post, postID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "文章不存在",
-		})
+	post, err := pc.posts.FindWithComments(uint(postID))
+	if err != nil {
+		postNotFound(c, err)
 		return
 	}
 
@@ -153,11 +141,9 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 	}
 
 	// 查找文章
-	var post models.Post
-	if err := config.GetDB().First(&post, postID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "文章不存在",
-		})
+	post, err := pc.posts.FindByID(uint(postID))
+	if err != nil {
+		postNotFound(c, err)
 		return
 	}
 
@@ -173,7 +159,7 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 	post.Title = req.Title
 	post.Content = req.Content
 
-	if err := config.GetDB().Save(&post).Error; err != nil {
+	if err := pc.posts.Update(post); err != nil {
 		logrus.WithError(err).Error("更新文章失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "更新文章失败",
@@ -181,9 +167,6 @@ func (pc *PostController) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	// 预加载用户信息
-	config.GetDB().Preload("User").First(&post, post.ID)
-
 	logrus.WithField("post_id", post.ID).Info("文章更新成功")
 	c.JSON(http.StatusOK, gin.H{
 		"message": "文章更新成功",
@@ -211,11 +194,9 @@ func (pc *PostController) DeletePost(c *gin.Context) {
 	}
 
 	// 查找文章
-	var post models.Post
-	if err := config.GetDB().First(&post, postID).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "文章不存在",
-		})
+	post, err := pc.posts.FindByID(uint(postID))
+	if err != nil {
+		postNotFound(c, err)
 		return
 	}
 
@@ -227,8 +208,8 @@ func (pc *PostController) DeletePost(c *gin.Context) {
 		return
 	}
 
-	// 删除文章（GORM会自动删除关联的评论）
-	if err := config.GetDB().Delete(&post).Error; err != nil {
+	// 删除文章及其评论
+	if err := pc.posts.Delete(post.ID); err != nil {
 		logrus.WithError(err).Error("删除文章失败")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "删除文章失败",
@@ -241,3 +222,17 @@ func (pc *PostController) DeletePost(c *gin.Context) {
 		"message": "文章删除成功",
 	})
 }
+
+// postNotFound 响应查找文章的错误，文章不存在时返回 404，其他错误返回 500
+func postNotFound(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "文章不存在",
+		})
+		return
+	}
+	logrus.WithError(err).Error("查询文章失败")
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "查询文章失败",
+	})
+}
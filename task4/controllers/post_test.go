@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+
+	"task4/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCreatePost 测试创建文章
+func TestCreatePost(t *testing.T) {
+	runRequests(t, []request{
+		{"POST", "/api/v1/posts", `{"title":"新文章","content":"内容"}`, 2, http.StatusCreated, `"username":"bob"`, "创建成功"},
+		{"POST", "/api/v1/posts", `{"title":"新文章","content":"内容"}`, 0, http.StatusUnauthorized, "用户未认证", "未认证"},
+		{"POST", "/api/v1/posts", `{"title":"","content":"内容"}`, 2, http.StatusBadRequest, "参数验证失败", "缺少标题"},
+		{"POST", "/api/v1/posts", `{"title":"新文章"}`, 2, http.StatusBadRequest, "参数验证失败", "缺少内容"},
+	})
+}
+
+// TestGetPosts 测试文章列表和分页
+func TestGetPosts(t *testing.T) {
+	repos := seed(t)
+	for i := 2; i <= 5; i++ {
+		require.NoError(t, repos.Posts.Create(&models.Post{Title: fmt.Sprintf("第%d篇", i), Content: "内容", UserID: 2}))
+	}
+	r := newRouter(repos)
+
+	tests := []struct {
+		query    string
+		page     int
+		pageSize int
+		ids      []uint
+		desc     string
+	}{
+		{"", 1, 10, []uint{5, 4, 3, 2, 1}, "默认分页，新的在前"},
+		{"?page=1&page_size=2", 1, 2, []uint{5, 4}, "第一页"},
+		{"?page=3&page_size=2", 3, 2, []uint{1}, "最后一页"},
+		{"?page=4&page_size=2", 4, 2, []uint{}, "超出范围"},
+		{"?page=-1&page_size=0", 1, 10, []uint{5, 4, 3, 2, 1}, "无效的分页参数"},
+		{"?page=9223372036854775807&page_size=2", math.MaxInt / 2, 2, []uint{}, "页码过大"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			w := serve(r, "GET", "/api/v1/posts"+test.query, "", 0)
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp struct {
+				Posts      []models.Post `json:"posts"`
+				Pagination struct {
+					Page     int   `json:"page"`
+					PageSize int   `json:"page_size"`
+					Total    int64 `json:"total"`
+				} `json:"pagination"`
+			}
+			decode(t, w, &resp)
+			ids := []uint{}
+			for _, post := range resp.Posts {
+				ids = append(ids, post.ID)
+				assert.NotEmpty(t, post.User.Username, "包含作者信息")
+			}
+			assert.Equal(t, test.ids, ids)
+			assert.Equal(t, test.page, resp.Pagination.Page)
+			assert.Equal(t, test.pageSize, resp.Pagination.PageSize)
+			assert.Equal(t, int64(5), resp.Pagination.Total)
+		})
+	}
+}
+
+// TestGetPost 测试文章详情
+func TestGetPost(t *testing.T) {
+	runRequests(t, []request{
+		{"GET", "/api/v1/posts/1", "", 0, http.StatusOK, `"content":"沙发"`, "包含评论"},
+		{"GET", "/api/v1/posts/42", "", 0, http.StatusNotFound, "文章不存在", "文章不存在"},
+		{"GET", "/api/v1/posts/abc", "", 0, http.StatusBadRequest, "无效的文章ID", "无效的 ID"},
+	})
+
+	w := serve(newRouter(seed(t)), "GET", "/api/v1/posts/1", "", 0)
+	var resp struct {
+		Post models.Post `json:"post"`
+	}
+	decode(t, w, &resp)
+	assert.Equal(t, "alice", resp.Post.User.Username)
+	require.Len(t, resp.Post.Comments, 2)
+	assert.Equal(t, "沙发", resp.Post.Comments[0].Content)
+	assert.Equal(t, "bob", resp.Post.Comments[0].User.Username)
+}
+
+// TestUpdatePost 测试更新文章，只有作者可以更新
+func TestUpdatePost(t *testing.T) {
+	runRequests(t, []request{
+		{"PUT", "/api/v1/posts/1", `{"title":"新标题","content":"新内容"}`, 1, http.StatusOK, `"title":"新标题"`, "作者更新"},
+		{"PUT", "/api/v1/posts/1", `{"title":"新标题","content":"新内容"}`, 2, http.StatusForbidden, "只有文章作者才能更新文章", "非作者"},
+		{"PUT", "/api/v1/posts/1", `{"title":"新标题","content":"新内容"}`, 0, http.StatusUnauthorized, "用户未认证", "未认证"},
+		{"PUT", "/api/v1/posts/42", `{"title":"新标题","content":"新内容"}`, 1, http.StatusNotFound, "文章不存在", "文章不存在"},
+		{"PUT", "/api/v1/posts/abc", `{"title":"新标题","content":"新内容"}`, 1, http.StatusBadRequest, "无效的文章ID", "无效的 ID"},
+		{"PUT", "/api/v1/posts/1", `{"title":"新标题"}`, 1, http.StatusBadRequest, "参数验证失败", "缺少内容"},
+	})
+
+	repos := seed(t)
+	serve(newRouter(repos), "PUT", "/api/v1/posts/1", `{"title":"新标题","content":"新内容"}`, 1)
+	post, err := repos.Posts.FindByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "新标题", post.Title, "保存到仓库")
+	assert.Equal(t, "新内容", post.Content)
+}
+
+// TestDeletePost 测试删除文章，只有作者可以删除，评论一起删除
+func TestDeletePost(t *testing.T) {
+	runRequests(t, []request{
+		{"DELETE", "/api/v1/posts/1", "", 1, http.StatusOK, "文章删除成功", "作者删除"},
+		{"DELETE", "/api/v1/posts/1", "", 2, http.StatusForbidden, "只有文章作者才能删除文章", "非作者"},
+		{"DELETE", "/api/v1/posts/1", "", 0, http.StatusUnauthorized, "用户未认证", "未认证"},
+		{"DELETE", "/api/v1/posts/42", "", 1, http.StatusNotFound, "文章不存在", "文章不存在"},
+		{"DELETE", "/api/v1/posts/abc", "", 1, http.StatusBadRequest, "无效的文章ID", "无效的 ID"},
+	})
+
+	repos := seed(t)
+	r := newRouter(repos)
+	require.Equal(t, http.StatusOK, serve(r, "DELETE", "/api/v1/posts/1", "", 1).Code)
+	assert.Equal(t, http.StatusNotFound, serve(r, "GET", "/api/v1/posts/1", "", 0).Code)
+	_, total, err := repos.Comments.ListByPost(1, 0, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
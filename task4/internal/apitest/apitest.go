@@ -0,0 +1,236 @@
+// Package apitest 提供端到端测试使用的服务和数据准备函数
+//
+// NewServer 用 routes.SetupRoutes 启动完整的 API，数据保存在每个测试独立的 SQLite 内存数据库中，
+// 不需要任何外部服务。
+package apitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"task4/config"
+	"task4/internal/apitest/fixture"
+	"task4/models"
+	"task4/repository"
+	"task4/routes"
+	"task4/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Secret 测试使用的 JWT 密钥
+const Secret = "apitest-secret-0123456789abcdef"
+
+// 测试使用的 access token 和 refresh token 有效期
+const (
+	TokenTTL        = 15 * time.Minute
+	RefreshTokenTTL = 24 * time.Hour
+)
+
+// Password 数据准备函数创建的用户的密码
+const Password = fixture.Password
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	logrus.SetOutput(io.Discard)
+}
+
+// Server 测试用的 API 服务
+type Server struct {
+	t       *testing.T
+	handler http.Handler
+	// DB 服务使用的数据库，用于检查或直接准备数据
+	DB *gorm.DB
+}
+
+// NewServer 启动使用新的 SQLite 内存数据库的服务
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+	db, err := config.OpenDB(config.MemoryDatabase(), false)
+	require.NoError(t, err)
+	require.NoError(t, models.Migrate(db))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	utils.InitJWT(Secret, TokenTTL, RefreshTokenTTL)
+	return &Server{
+		t:       t,
+		handler: routes.SetupRoutes(repository.NewGormRepositories(db), []string{"*"}),
+		DB:      db,
+	}
+}
+
+// Response 响应
+type Response struct {
+	t      *testing.T
+	Code   int
+	Header http.Header
+	Body   string
+}
+
+// JSON 把响应解析到 v
+func (r *Response) JSON(v interface{}) {
+	r.t.Helper()
+	require.NoError(r.t, json.Unmarshal([]byte(r.Body), v), r.Body)
+}
+
+// Error 返回响应中的 error 字段
+func (r *Response) Error() string {
+	r.t.Helper()
+	var body struct {
+		Error string `json:"error"`
+	}
+	r.JSON(&body)
+	return body.Error
+}
+
+// Do 发送请求，body 为 string 时原样发送，否则编码为 JSON；token 不为空时添加 Bearer 认证头
+func (s *Server) Do(method, path string, body interface{}, token string) *Response {
+	s.t.Helper()
+	headers := map[string]string{}
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+	return s.DoWithHeaders(method, path, body, headers)
+}
+
+// DoWithHeaders 发送带指定请求头的请求，body 的处理与 Do 相同
+func (s *Server) DoWithHeaders(method, path string, body interface{}, headers map[string]string) *Response {
+	s.t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	default:
+		data, err := json.Marshal(b)
+		require.NoError(s.t, err)
+		reader = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+	return &Response{t: s.t, Code: w.Code, Header: w.Header(), Body: w.Body.String()}
+}
+
+// User 已注册并登录的用户
+type User struct {
+	ID           uint
+	Username     string
+	Email        string
+	Token        string // access token
+	RefreshToken string
+}
+
+// Register 通过 API 注册用户并登录，密码为 Password
+func (s *Server) Register(username string) *User {
+	s.t.Helper()
+	email := username + "@example.com"
+	resp := s.Do("POST", "/api/v1/auth/register", map[string]string{
+		"username": username,
+		"password": Password,
+		"email":    email,
+	}, "")
+	require.Equal(s.t, http.StatusCreated, resp.Code, resp.Body)
+
+	user := s.Login(username, Password)
+	user.Email = email
+	return user
+}
+
+// Login 通过 API 登录
+func (s *Server) Login(username, password string) *User {
+	s.t.Helper()
+	resp := s.Do("POST", "/api/v1/auth/login", map[string]string{
+		"username": username,
+		"password": password,
+	}, "")
+	require.Equal(s.t, http.StatusOK, resp.Code, resp.Body)
+
+	var body struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+		User         struct {
+			ID       uint   `json:"id"`
+			Username string `json:"username"`
+			Email    string `json:"email"`
+		} `json:"user"`
+	}
+	resp.JSON(&body)
+	return &User{
+		ID:           body.User.ID,
+		Username:     body.User.Username,
+		Email:        body.User.Email,
+		Token:        body.Token,
+		RefreshToken: body.RefreshToken,
+	}
+}
+
+// Refresh 用 refresh token 换取新的 token 并更新 user，返回响应；刷新失败时 user 不变
+func (s *Server) Refresh(user *User) *Response {
+	s.t.Helper()
+	resp := s.Do("POST", "/api/v1/auth/refresh", map[string]string{"refresh_token": user.RefreshToken}, "")
+	if resp.Code == http.StatusOK {
+		var body struct {
+			Token        string `json:"token"`
+			RefreshToken string `json:"refresh_token"`
+		}
+		resp.JSON(&body)
+		user.Token, user.RefreshToken = body.Token, body.RefreshToken
+	}
+	return resp
+}
+
+// CreatePost 通过 API 创建文章，返回文章 ID
+func (s *Server) CreatePost(author *User, title, content string) uint {
+	s.t.Helper()
+	resp := s.Do("POST", "/api/v1/posts", map[string]string{"title": title, "content": content}, author.Token)
+	require.Equal(s.t, http.StatusCreated, resp.Code, resp.Body)
+	var body struct {
+		Post models.Post `json:"post"`
+	}
+	resp.JSON(&body)
+	return body.Post.ID
+}
+
+// CreatePosts 创建 n 篇标题为 "文章 1" 到 "文章 n" 的文章，返回按创建顺序排列的 ID
+func (s *Server) CreatePosts(author *User, n int) []uint {
+	s.t.Helper()
+	ids := make([]uint, n)
+	for i := range ids {
+		ids[i] = s.CreatePost(author, fmt.Sprintf("文章 %d", i+1), "内容")
+	}
+	return ids
+}
+
+// CreateComment 通过 API 创建评论，返回评论 ID
+func (s *Server) CreateComment(author *User, postID uint, content string) uint {
+	s.t.Helper()
+	resp := s.Do("POST", "/api/v1/comments", map[string]interface{}{"content": content, "post_id": postID}, author.Token)
+	require.Equal(s.t, http.StatusCreated, resp.Code, resp.Body)
+	var body struct {
+		Comment models.Comment `json:"comment"`
+	}
+	resp.JSON(&body)
+	return body.Comment.ID
+}
@@ -0,0 +1,34 @@
+// Package fixture 提供仓库、控制器和端到端测试共用的数据准备函数
+//
+// 本包只依赖 models，repository、controllers 包内的测试导入它不会形成循环引用。
+// 导入本包的测试使用 bcrypt.MinCost 加密密码，创建和登录用户不再是测试的主要耗时。
+package fixture
+
+import (
+	"testing"
+
+	"task4/models"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password 数据准备函数创建的用户的密码
+const Password = "secret123"
+
+func init() {
+	models.PasswordCost = bcrypt.MinCost
+}
+
+// UserCreator 创建用户的仓库，repository.UserRepository 满足该接口
+type UserCreator interface {
+	Create(user *models.User) error
+}
+
+// User 创建密码为 Password、邮箱为 username@example.com 的用户
+func User(t *testing.T, users UserCreator, username string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Password: Password, Email: username + "@example.com"}
+	require.NoError(t, users.Create(user))
+	return user
+}
@@ -1,25 +1,41 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
+	"time"
 
 	"task4/config"
 	"task4/models"
+	"task4/repository"
 	"task4/routes"
+	"task4/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 func init() {
-	// 设置日志格式
+	// 设置日志格式，日志级别由配置决定
 	logrus.SetFormatter(&logrus.JSONFormatter{})
-	logrus.SetLevel(logrus.InfoLevel)
 }
 
 func main() {
+	// 加载配置：配置文件、环境变量、命令行参数依次覆盖
+	flags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	cfg, err := config.Load(flags, os.Getenv)
+	if err != nil {
+		log.Fatal("配置加载失败:\n", err)
+	}
+	logrus.SetLevel(cfg.LogLevel())
+	utils.InitJWT(cfg.JWT.Secret, cfg.JWT.TTL, cfg.JWT.RefreshTTL)
+
 	// 初始化数据库连接
-	config.InitDB()
+	if err := config.InitDB(cfg.Database, cfg.LogLevel() >= logrus.DebugLevel); err != nil {
+		log.Fatal("数据库连接失败:", err)
+	}
 
 	// 自动迁移数据库表
 	if err := migrateDatabase(); err != nil {
@@ -27,33 +43,38 @@ func main() {
 	}
 
 	// 设置Gin模式
-	gin.SetMode(gin.ReleaseMode)
+	gin.SetMode(cfg.Server.Mode)
 
 	// 设置路由
-	r := routes.SetupRoutes()
+	repos := repository.NewGormRepositories(config.GetDB())
+	r := routes.SetupRoutes(repos, cfg.CORS.AllowOrigins)
+
+	// 定期清理过期的 refresh token 和撤销记录
+	go purgeExpiredTokens(repos.Tokens, time.Hour)
 
 	// 启动服务器
-	logrus.Info("博客API服务器启动在端口 8080")
-	if err := r.Run(":8080"); err != nil {
+	logrus.WithField("addr", cfg.Server.Addr).Info("博客API服务器启动")
+	if err := r.Run(cfg.Server.Addr); err != nil {
 		log.Fatal("服务器启动失败:", err)
 	}
 }
 
 // migrateDatabase 数据库迁移
 func migrateDatabase() error {
-	db := config.GetDB()
-
 	// 自动迁移所有模型
-	err := db.AutoMigrate(
-		&models.User{},
-		&models.Post{},
-		&models.Comment{},
-	)
-
-	if err != nil {
+	if err := models.Migrate(config.GetDB()); err != nil {
 		return err
 	}
 
 	logrus.Info("数据库表迁移完成")
 	return nil
 }
+
+// purgeExpiredTokens 每隔 interval 删除已过期的 refresh token 和撤销记录
+func purgeExpiredTokens(tokens repository.TokenRepository, interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := tokens.DeleteExpired(time.Now()); err != nil {
+			logrus.WithError(err).Error("清理过期token失败")
+		}
+	}
+}
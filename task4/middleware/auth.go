@@ -4,13 +4,15 @@ import (
 	"net/http"
 	"strings"
 
+	"task4/repository"
 	"task4/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-// AuthMiddleware JWT认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware JWT认证中间件，tokens 用于检查 token 是否已被撤销
+func AuthMiddleware(tokens repository.TokenRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取token
 		authHeader := c.GetHeader("Authorization")
@@ -42,9 +44,28 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查 token 或其登录会话是否已被撤销
+		revoked, err := tokens.IsAccessTokenRevoked(claims.ID, claims.SessionID)
+		if err != nil {
+			logrus.WithError(err).Error("查询 token 撤销状态失败")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "认证失败",
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("claims", claims)
 
 		c.Next()
 	}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"task4/models"
+	"task4/repository"
+	"task4/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	logrus.SetOutput(io.Discard)
+}
+
+// brokenTokens 查询撤销状态总是失败的 token 仓库
+type brokenTokens struct{ repository.TokenRepository }
+
+func (brokenTokens) IsAccessTokenRevoked(string, string) (bool, error) {
+	return false, errors.New("数据库不可用")
+}
+
+// newSession 创建会话并返回其 access token 和 claims
+func newSession(t *testing.T, tokens repository.TokenRepository, family string) (string, *utils.Claims) {
+	t.Helper()
+	require.NoError(t, tokens.CreateRefreshToken(&models.RefreshToken{
+		UserID: 1, FamilyID: family, TokenHash: family, ExpiresAt: time.Now().Add(time.Hour),
+	}))
+	token, err := utils.GenerateToken(1, "alice", family)
+	require.NoError(t, err)
+	claims, err := utils.ParseToken(token)
+	require.NoError(t, err)
+	return token, claims
+}
+
+// TestAuthMiddleware 测试 token 的校验和撤销检查
+func TestAuthMiddleware(t *testing.T) {
+	utils.InitJWT("middleware-test-secret", time.Minute, time.Hour)
+	tokens := repository.NewMemoryRepositories().Tokens
+
+	valid, _ := newSession(t, tokens, "valid")
+	revokedJTI, claims := newSession(t, tokens, "jti")
+	require.NoError(t, tokens.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time))
+	revokedSession, _ := newSession(t, tokens, "session")
+	require.NoError(t, tokens.RevokeFamily("session"))
+
+	tests := []struct {
+		header   string
+		tokens   repository.TokenRepository
+		status   int
+		expected string
+		desc     string
+	}{
+		{"Bearer " + valid, tokens, http.StatusOK, `{"user_id":1,"username":"alice"}`, "有效的 token"},
+		{"", tokens, http.StatusUnauthorized, "Authorization header is required", "缺少认证头"},
+		{valid, tokens, http.StatusUnauthorized, "Authorization header format must be Bearer {token}", "缺少 Bearer 前缀"},
+		{"Bearer invalid", tokens, http.StatusUnauthorized, "Invalid token", "无效的 token"},
+		{"Bearer " + revokedJTI, tokens, http.StatusUnauthorized, "Token has been revoked", "jti 已撤销"},
+		{"Bearer " + revokedSession, tokens, http.StatusUnauthorized, "Token has been revoked", "会话已注销"},
+		{"Bearer " + valid, brokenTokens{}, http.StatusInternalServerError, "认证失败", "查询撤销状态失败"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", AuthMiddleware(test.tokens), func(c *gin.Context) {
+				claims := c.MustGet("claims").(*utils.Claims)
+				assert.Equal(t, c.GetUint("user_id"), claims.UserID)
+				c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id"), "username": c.GetString("username")})
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, test.status, w.Code)
+			assert.Contains(t, w.Body.String(), test.expected)
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// Migrate 自动迁移所有模型的表结构，mysql、sqlite、postgres 通用
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&User{},
+		&Post{},
+		&Comment{},
+		&RefreshToken{},
+		&RevokedToken{},
+	)
+}
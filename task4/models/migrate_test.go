@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"task4/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// openTestDB 打开迁移过的内存数据库
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.OpenDB(config.MemoryDatabase(), false)
+	require.NoError(t, err)
+	require.NoError(t, Migrate(db))
+	return db
+}
+
+// TestMigrate 测试迁移后的表结构和约束
+func TestMigrate(t *testing.T) {
+	db := openTestDB(t)
+	for _, table := range []string{"users", "posts", "comments"} {
+		assert.True(t, db.Migrator().HasTable(table), table)
+	}
+	require.NoError(t, Migrate(db), "重复迁移")
+
+	user := User{Username: "alice", Password: "secret1", Email: "alice@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	assert.True(t, user.CheckPassword("secret1"), "保存的是密码的哈希")
+	assert.False(t, user.CheckPassword("secret2"))
+	cost, err := bcrypt.Cost([]byte(user.Password))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.DefaultCost, cost, "默认使用 bcrypt.DefaultCost")
+
+	dup := User{Username: "alice", Password: "secret1", Email: "other@example.com"}
+	assert.Error(t, db.Create(&dup).Error, "用户名唯一")
+
+	post := Post{Title: "标题", Content: "内容", UserID: user.ID}
+	require.NoError(t, db.Create(&post).Error)
+	require.NoError(t, db.Create(&Comment{Content: "评论", UserID: user.ID, PostID: post.ID}).Error)
+
+	assert.Error(t, db.Create(&Comment{Content: "评论", UserID: user.ID, PostID: post.ID + 1}).Error, "文章不存在")
+
+	require.NoError(t, db.Delete(&post).Error)
+	var count int64
+	require.NoError(t, db.Model(&Comment{}).Count(&count).Error)
+	assert.Zero(t, count, "删除文章时级联删除评论")
+}
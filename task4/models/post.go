@@ -11,7 +11,7 @@ type Post struct {
 	Content   string    `json:"content" gorm:"type:text;not null"`
 	UserID    uint      `json:"user_id" gorm:"not null"`
 	User      User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	Comments  []Comment `json:"comments,omitempty" gorm:"foreignKey:PostID"`
+	Comments  []Comment `json:"comments,omitempty" gorm:"foreignKey:PostID;constraint:OnDelete:CASCADE"` // 删除文章时级联删除评论
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken 服务端保存的 refresh token
+//
+// 每次刷新都会撤销旧 token 并签发同一系列（FamilyID）的新 token。
+// 系列即一次登录的会话，已撤销的 token 再次被使用时说明可能被盗用，整个系列随之撤销。
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index"`
+	FamilyID  string     `gorm:"size:64;not null;index"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"` // token 的 SHA-256 哈希，不保存原文
+	ExpiresAt time.Time  `gorm:"not null"`
+	RevokedAt *time.Time // 为空表示未撤销
+	CreatedAt time.Time
+}
+
+// Active 返回 token 在 now 时是否未撤销且未过期
+func (t *RefreshToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// RevokedToken 已撤销的 access token，按 jti 记录，过期后可以删除
+type RevokedToken struct {
+	JTI       string    `gorm:"size:64;primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// RefreshRequest 刷新 token 请求
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
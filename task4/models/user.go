@@ -32,9 +32,12 @@ type UserLoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// PasswordCost 加密密码使用的 bcrypt 计算成本，测试中调低以加快创建用户
+var PasswordCost = bcrypt.DefaultCost
+
 // BeforeCreate GORM钩子：创建用户前加密密码
 func (u *User) BeforeCreate(tx *gorm.DB) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), PasswordCost)
 	if err != nil {
 		return err
 	}
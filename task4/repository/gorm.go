@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"task4/models"
+
+	"gorm.io/gorm"
+)
+
+// NewGormRepositories 返回基于 GORM 的仓库，db 需要已经迁移过表结构
+func NewGormRepositories(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:    NewGormUserRepository(db),
+		Posts:    NewGormPostRepository(db),
+		Comments: NewGormCommentRepository(db),
+		Tokens:   NewGormTokenRepository(db),
+	}
+}
+
+// translate 把 GORM 的错误转换为仓库的错误
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrNotFound
+	default:
+		return err
+	}
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+// NewGormUserRepository 返回基于 GORM 的用户仓库
+func NewGormUserRepository(db *gorm.DB) UserRepository {
+	return &gormUserRepository{db: db}
+}
+
+func (r *gormUserRepository) Create(user *models.User) error {
+	return translate(r.db.Create(user).Error)
+}
+
+func (r *gormUserRepository) FindByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) FindByUsername(username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) ExistsByUsernameOrEmail(username, email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("username = ? OR email = ?", username, email).Count(&count).Error
+	return count > 0, err
+}
+
+type gormPostRepository struct {
+	db *gorm.DB
+}
+
+// NewGormPostRepository 返回基于 GORM 的文章仓库
+func NewGormPostRepository(db *gorm.DB) PostRepository {
+	return &gormPostRepository{db: db}
+}
+
+func (r *gormPostRepository) Create(post *models.Post) error {
+	if err := r.db.Create(post).Error; err != nil {
+		return translate(err)
+	}
+	return translate(r.db.Preload("User").First(post, post.ID).Error)
+}
+
+func (r *gormPostRepository) FindByID(id uint) (*models.Post, error) {
+	var post models.Post
+	if err := r.db.Preload("User").First(&post, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &post, nil
+}
+
+func (r *gormPostRepository) FindWithComments(id uint) (*models.Post, error) {
+	var post models.Post
+	err := r.db.
+		Preload("User").
+		Preload("Comments", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, id ASC") }).
+		Preload("Comments.User").
+		First(&post, id).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &post, nil
+}
+
+func (r *gormPostRepository) List(offset, limit int) ([]models.Post, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Post{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	posts := []models.Post{}
+	err := r.db.
+		Preload("User").
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&posts).Error
+	return posts, total, err
+}
+
+func (r *gormPostRepository) Update(post *models.Post) error {
+	err := r.db.Model(&models.Post{ID: post.ID}).Updates(map[string]interface{}{
+		"title":   post.Title,
+		"content": post.Content,
+	}).Error
+	if err != nil {
+		return translate(err)
+	}
+	// MySQL 在内容没有变化时报告 0 行受影响，因此用重新查询判断文章是否存在
+	return translate(r.db.Preload("User").First(post, post.ID).Error)
+}
+
+func (r *gormPostRepository) Delete(id uint) error {
+	// 显式删除评论，不依赖数据库的级联约束（已有的表可能没有这个约束）
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("post_id = ?", id).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Post{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+type gormCommentRepository struct {
+	db *gorm.DB
+}
+
+// NewGormCommentRepository 返回基于 GORM 的评论仓库
+func NewGormCommentRepository(db *gorm.DB) CommentRepository {
+	return &gormCommentRepository{db: db}
+}
+
+func (r *gormCommentRepository) Create(comment *models.Comment) error {
+	if err := r.db.Create(comment).Error; err != nil {
+		return translate(err)
+	}
+	return translate(r.db.Preload("User").First(comment, comment.ID).Error)
+}
+
+func (r *gormCommentRepository) ListByPost(postID uint, offset, limit int) ([]models.Comment, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.Comment{}).Where("post_id = ?", postID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	comments := []models.Comment{}
+	err := r.db.
+		Where("post_id = ?", postID).
+		Preload("User").
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&comments).Error
+	return comments, total, err
+}
+
+type gormTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewGormTokenRepository 返回基于 GORM 的 token 仓库
+func NewGormTokenRepository(db *gorm.DB) TokenRepository {
+	return &gormTokenRepository{db: db}
+}
+
+func (r *gormTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return translate(r.db.Create(token).Error)
+}
+
+func (r *gormTokenRepository) FindRefreshToken(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *gormTokenRepository) RotateRefreshToken(old, next *models.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 只有仍未撤销的 token 才能轮换，两个请求同时使用同一个 token 时只有一个成功
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRevoked
+		}
+		return translate(tx.Create(next).Error)
+	})
+}
+
+func (r *gormTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormTokenRepository) RevokeUserTokens(userID uint) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	err := r.db.Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// 重复撤销同一个 token
+		return nil
+	}
+	return err
+}
+
+func (r *gormTokenRepository) IsAccessTokenRevoked(jti, familyID string) (bool, error) {
+	var revoked int64
+	if err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&revoked).Error; err != nil {
+		return false, err
+	}
+	if revoked > 0 {
+		return true, nil
+	}
+	var active int64
+	err := r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL AND expires_at > ?", familyID, time.Now()).
+		Count(&active).Error
+	return active == 0, err
+}
+
+func (r *gormTokenRepository) DeleteExpired(before time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", before).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("expires_at < ?", before).Delete(&models.RevokedToken{}).Error
+	})
+}
@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"task4/models"
+)
+
+// memoryStore 内存仓库共享的数据，用于测试和演示
+//
+// 仓库返回数据的副本，调用方修改返回值不会影响已保存的数据。
+type memoryStore struct {
+	mu       sync.RWMutex
+	users    map[uint]models.User
+	posts    map[uint]models.Post
+	comments map[uint]models.Comment
+	refresh  map[uint]models.RefreshToken
+	revoked  map[string]models.RevokedToken
+	lastIDs  map[string]uint
+	now      func() time.Time
+}
+
+// NewMemoryRepositories 返回共享同一份内存数据的仓库
+func NewMemoryRepositories() Repositories {
+	s := &memoryStore{
+		users:    make(map[uint]models.User),
+		posts:    make(map[uint]models.Post),
+		comments: make(map[uint]models.Comment),
+		refresh:  make(map[uint]models.RefreshToken),
+		revoked:  make(map[string]models.RevokedToken),
+		lastIDs:  make(map[string]uint),
+		now:      time.Now,
+	}
+	return Repositories{
+		Users:    &memoryUserRepository{s},
+		Posts:    &memoryPostRepository{s},
+		Comments: &memoryCommentRepository{s},
+		Tokens:   &memoryTokenRepository{s},
+	}
+}
+
+// nextID 返回表的下一个自增 ID
+func (s *memoryStore) nextID(table string) uint {
+	s.lastIDs[table]++
+	return s.lastIDs[table]
+}
+
+// withUser 返回填充了作者信息的文章
+func (s *memoryStore) withUser(post models.Post) models.Post {
+	post.User = s.users[post.UserID]
+	post.Comments = nil
+	return post
+}
+
+// commentsOf 按创建时间返回文章的评论
+func (s *memoryStore) commentsOf(postID uint) []models.Comment {
+	comments := []models.Comment{}
+	for _, c := range s.comments {
+		if c.PostID == postID {
+			c.User = s.users[c.UserID]
+			comments = append(comments, c)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return comments
+}
+
+// page 返回 [offset, offset+limit) 范围内的元素
+func page[T any](items []T, offset, limit int) []T {
+	if offset < 0 || offset >= len(items) {
+		return []T{}
+	}
+	end := len(items)
+	// 比较剩余的条数，offset+limit 可能溢出
+	if limit >= 0 && limit < end-offset {
+		end = offset + limit
+	}
+	return items[offset:end]
+}
+
+type memoryUserRepository struct {
+	*memoryStore
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Username == user.Username || u.Email == user.Email {
+			return ErrDuplicate
+		}
+	}
+	if err := user.BeforeCreate(nil); err != nil {
+		return err
+	}
+	user.ID = r.nextID("users")
+	user.CreatedAt = r.now()
+	user.UpdatedAt = user.CreatedAt
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByUsername(username string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.users {
+		if user.Username == username {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) ExistsByUsernameOrEmail(username, email string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.users {
+		if user.Username == username || user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryPostRepository struct {
+	*memoryStore
+}
+
+func (r *memoryPostRepository) Create(post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[post.UserID]; !ok {
+		return ErrNotFound
+	}
+	post.ID = r.nextID("posts")
+	post.CreatedAt = r.now()
+	post.UpdatedAt = post.CreatedAt
+	*post = r.withUser(*post)
+	r.posts[post.ID] = *post
+	return nil
+}
+
+func (r *memoryPostRepository) FindByID(id uint) (*models.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	post = r.withUser(post)
+	return &post, nil
+}
+
+func (r *memoryPostRepository) FindWithComments(id uint) (*models.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	post, ok := r.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	post = r.withUser(post)
+	post.Comments = r.commentsOf(id)
+	return &post, nil
+}
+
+func (r *memoryPostRepository) List(offset, limit int) ([]models.Post, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	posts := make([]models.Post, 0, len(r.posts))
+	for _, post := range r.posts {
+		posts = append(posts, r.withUser(post))
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		a, b := posts[i], posts[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID > b.ID
+	})
+	return page(posts, offset, limit), int64(len(posts)), nil
+}
+
+func (r *memoryPostRepository) Update(post *models.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved, ok := r.posts[post.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	saved.Title = post.Title
+	saved.Content = post.Content
+	saved.UpdatedAt = r.now()
+	r.posts[saved.ID] = saved
+	*post = r.withUser(saved)
+	return nil
+}
+
+func (r *memoryPostRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.posts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.posts, id)
+	for commentID, c := range r.comments {
+		if c.PostID == id {
+			delete(r.comments, commentID)
+		}
+	}
+	return nil
+}
+
+type memoryCommentRepository struct {
+	*memoryStore
+}
+
+func (r *memoryCommentRepository) Create(comment *models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[comment.UserID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := r.posts[comment.PostID]; !ok {
+		return ErrNotFound
+	}
+	comment.ID = r.nextID("comments")
+	comment.CreatedAt = r.now()
+	comment.User = r.users[comment.UserID]
+	comment.Post = models.Post{}
+	r.comments[comment.ID] = *comment
+	return nil
+}
+
+func (r *memoryCommentRepository) ListByPost(postID uint, offset, limit int) ([]models.Comment, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	comments := r.commentsOf(postID)
+	return page(comments, offset, limit), int64(len(comments)), nil
+}
+
+type memoryTokenRepository struct {
+	*memoryStore
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.createRefreshToken(token)
+}
+
+// createRefreshToken 保存 refresh token，调用方持有写锁
+func (r *memoryTokenRepository) createRefreshToken(token *models.RefreshToken) error {
+	for _, t := range r.refresh {
+		if t.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	token.ID = r.nextID("refresh_tokens")
+	token.CreatedAt = r.now()
+	r.refresh[token.ID] = *token
+	return nil
+}
+
+func (r *memoryTokenRepository) FindRefreshToken(hash string) (*models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, token := range r.refresh {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTokenRepository) RotateRefreshToken(old, next *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved, ok := r.refresh[old.ID]
+	if !ok || saved.RevokedAt != nil {
+		return ErrRevoked
+	}
+	r.revoke(&saved)
+	return r.createRefreshToken(next)
+}
+
+// revoke 撤销 refresh token，调用方持有写锁
+func (r *memoryTokenRepository) revoke(token *models.RefreshToken) {
+	now := r.now()
+	token.RevokedAt = &now
+	r.refresh[token.ID] = *token
+}
+
+func (r *memoryTokenRepository) RevokeFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.refresh {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			r.revoke(&token)
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeUserTokens(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.refresh {
+		if token.UserID == userID && token.RevokedAt == nil {
+			r.revoke(&token)
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeAccessToken(jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.revoked[jti]; !ok {
+		r.revoked[jti] = models.RevokedToken{JTI: jti, ExpiresAt: expiresAt, CreatedAt: r.now()}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(jti, familyID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.revoked[jti]; ok {
+		return true, nil
+	}
+	now := r.now()
+	for _, token := range r.refresh {
+		if token.FamilyID == familyID && token.Active(now) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *memoryTokenRepository) DeleteExpired(before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, token := range r.refresh {
+		if token.ExpiresAt.Before(before) {
+			delete(r.refresh, id)
+		}
+	}
+	for jti, token := range r.revoked {
+		if token.ExpiresAt.Before(before) {
+			delete(r.revoked, jti)
+		}
+	}
+	return nil
+}
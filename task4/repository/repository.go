@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"task4/models"
+)
+
+var (
+	// ErrNotFound 记录不存在，创建时引用的作者或文章不存在也返回这个错误
+	ErrNotFound = errors.New("记录不存在")
+	// ErrDuplicate 违反唯一约束，例如用户名或邮箱已被使用
+	ErrDuplicate = errors.New("记录已存在")
+	// ErrRevoked refresh token 已被撤销，轮换时并发使用同一个 token 也返回这个错误
+	ErrRevoked = errors.New("令牌已被撤销")
+)
+
+// UserRepository 用户仓库
+type UserRepository interface {
+	// Create 创建用户，密码由 models.User 的 BeforeCreate 钩子加密；用户名或邮箱已存在时返回 ErrDuplicate
+	Create(user *models.User) error
+	// FindByID 按 ID 查找用户
+	FindByID(id uint) (*models.User, error)
+	// FindByUsername 按用户名查找用户
+	FindByUsername(username string) (*models.User, error)
+	// ExistsByUsernameOrEmail 返回用户名或邮箱是否已被使用
+	ExistsByUsernameOrEmail(username, email string) (bool, error)
+}
+
+// PostRepository 文章仓库，返回的文章都带有作者信息
+type PostRepository interface {
+	// Create 创建文章并填充作者信息
+	Create(post *models.Post) error
+	// FindByID 按 ID 查找文章
+	FindByID(id uint) (*models.Post, error)
+	// FindWithComments 按 ID 查找文章及其评论，评论按创建时间排列
+	FindWithComments(id uint) (*models.Post, error)
+	// List 按创建时间倒序分页列出文章，同时返回文章总数
+	List(offset, limit int) ([]models.Post, int64, error)
+	// Update 保存文章的标题和内容
+	Update(post *models.Post) error
+	// Delete 删除文章及其评论
+	Delete(id uint) error
+}
+
+// CommentRepository 评论仓库，返回的评论都带有作者信息
+type CommentRepository interface {
+	// Create 创建评论并填充作者信息
+	Create(comment *models.Comment) error
+	// ListByPost 按创建时间分页列出文章的评论，同时返回该文章的评论总数
+	ListByPost(postID uint, offset, limit int) ([]models.Comment, int64, error)
+}
+
+// TokenRepository refresh token 和 access token 撤销列表的仓库
+type TokenRepository interface {
+	// CreateRefreshToken 保存新的 refresh token
+	CreateRefreshToken(token *models.RefreshToken) error
+	// FindRefreshToken 按哈希查找 refresh token，包括已撤销和已过期的
+	FindRefreshToken(hash string) (*models.RefreshToken, error)
+	// RotateRefreshToken 撤销 old 并保存同一系列的 next；old 已被撤销时返回 ErrRevoked，不保存 next
+	RotateRefreshToken(old, next *models.RefreshToken) error
+	// RevokeFamily 撤销一个系列（登录会话）的所有 refresh token
+	RevokeFamily(familyID string) error
+	// RevokeUserTokens 撤销用户的所有 refresh token，即注销所有设备
+	RevokeUserTokens(userID uint) error
+	// RevokeAccessToken 把 access token 的 jti 加入撤销列表，expiresAt 为 token 的过期时间
+	RevokeAccessToken(jti string, expiresAt time.Time) error
+	// IsAccessTokenRevoked 返回 access token 是否已失效：jti 在撤销列表中，或者所属会话没有有效的 refresh token
+	IsAccessTokenRevoked(jti, familyID string) (bool, error)
+	// DeleteExpired 删除 before 之前过期的 refresh token 和撤销记录
+	DeleteExpired(before time.Time) error
+}
+
+// Repositories 控制器依赖的所有仓库
+type Repositories struct {
+	Users    UserRepository
+	Posts    PostRepository
+	Comments CommentRepository
+	Tokens   TokenRepository
+}
@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"task4/config"
+	"task4/internal/apitest/fixture"
+	"task4/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// implementations 返回各个仓库实现的构造函数，每次调用返回空的仓库
+func implementations(t *testing.T) map[string]func() Repositories {
+	return map[string]func() Repositories{
+		"memory": NewMemoryRepositories,
+		"gorm": func() Repositories {
+			db, err := config.OpenDB(config.MemoryDatabase(), false)
+			require.NoError(t, err)
+			require.NoError(t, models.Migrate(db))
+			return NewGormRepositories(db)
+		},
+	}
+}
+
+// TestRepositories 对所有实现运行同一组测试，保证内存实现和 GORM 实现的行为一致
+func TestRepositories(t *testing.T) {
+	for name, open := range implementations(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Run("用户", func(t *testing.T) { testUsers(t, open()) })
+			t.Run("文章", func(t *testing.T) { testPosts(t, open()) })
+			t.Run("评论", func(t *testing.T) { testComments(t, open()) })
+			t.Run("令牌", func(t *testing.T) { testTokens(t, open()) })
+		})
+	}
+}
+
+func testUsers(t *testing.T, repos Repositories) {
+	alice := fixture.User(t, repos.Users, "alice")
+	assert.Equal(t, uint(1), alice.ID)
+	assert.NotEqual(t, fixture.Password, alice.Password, "保存密码的哈希")
+	assert.False(t, alice.CreatedAt.IsZero())
+
+	found, err := repos.Users.FindByUsername("alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, found.ID)
+	assert.True(t, found.CheckPassword(fixture.Password))
+
+	found, err = repos.Users.FindByID(alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", found.Email)
+
+	_, err = repos.Users.FindByUsername("bob")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repos.Users.FindByID(42)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	tests := []struct {
+		username string
+		email    string
+		expected bool
+		desc     string
+	}{
+		{"alice", "new@example.com", true, "用户名已存在"},
+		{"new", "alice@example.com", true, "邮箱已存在"},
+		{"new", "new@example.com", false, "都不存在"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			exists, err := repos.Users.ExistsByUsernameOrEmail(test.username, test.email)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, exists)
+		})
+	}
+
+	err = repos.Users.Create(&models.User{Username: "alice", Password: "secret1", Email: "other@example.com"})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	err = repos.Users.Create(&models.User{Username: "other", Password: "secret1", Email: "alice@example.com"})
+	assert.ErrorIs(t, err, ErrDuplicate)
+}
+
+func testPosts(t *testing.T, repos Repositories) {
+	alice := fixture.User(t, repos.Users, "alice")
+
+	var ids []uint
+	for _, title := range []string{"第一篇", "第二篇", "第三篇"} {
+		post := &models.Post{Title: title, Content: "内容", UserID: alice.ID}
+		require.NoError(t, repos.Posts.Create(post))
+		assert.Equal(t, "alice", post.User.Username, "创建后填充作者")
+		ids = append(ids, post.ID)
+	}
+	assert.Equal(t, []uint{1, 2, 3}, ids)
+
+	err := repos.Posts.Create(&models.Post{Title: "标题", Content: "内容", UserID: 42})
+	assert.ErrorIs(t, err, ErrNotFound, "作者不存在")
+
+	tests := []struct {
+		offset   int
+		limit    int
+		expected []string
+		desc     string
+	}{
+		{0, 10, []string{"第三篇", "第二篇", "第一篇"}, "新的在前"},
+		{0, 2, []string{"第三篇", "第二篇"}, "第一页"},
+		{2, 2, []string{"第一篇"}, "最后一页"},
+		{4, 2, []string{}, "超出范围"},
+		{1, math.MaxInt, []string{"第二篇", "第一篇"}, "offset+limit 溢出"},
+		{math.MaxInt - 1, 2, []string{}, "offset 接近 int 上限"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			posts, total, err := repos.Posts.List(test.offset, test.limit)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), total)
+			titles := []string{}
+			for _, post := range posts {
+				titles = append(titles, post.Title)
+				assert.Equal(t, "alice", post.User.Username)
+			}
+			assert.Equal(t, test.expected, titles)
+		})
+	}
+
+	post, err := repos.Posts.FindByID(ids[0])
+	require.NoError(t, err)
+	post.Title, post.Content = "新标题", "新内容"
+	require.NoError(t, repos.Posts.Update(post))
+	assert.Equal(t, "alice", post.User.Username)
+
+	post, err = repos.Posts.FindByID(ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, "新标题", post.Title)
+	assert.Equal(t, "新内容", post.Content)
+	assert.Equal(t, alice.ID, post.User.ID)
+
+	assert.ErrorIs(t, repos.Posts.Update(&models.Post{ID: 42, Title: "标题", Content: "内容"}), ErrNotFound)
+
+	require.NoError(t, repos.Posts.Delete(ids[0]))
+	_, err = repos.Posts.FindByID(ids[0])
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, repos.Posts.Delete(ids[0]), ErrNotFound, "重复删除")
+	_, err = repos.Posts.FindWithComments(42)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func testComments(t *testing.T, repos Repositories) {
+	alice := fixture.User(t, repos.Users, "alice")
+	bob := fixture.User(t, repos.Users, "bob")
+	post := &models.Post{Title: "标题", Content: "内容", UserID: alice.ID}
+	require.NoError(t, repos.Posts.Create(post))
+	other := &models.Post{Title: "另一篇", Content: "内容", UserID: alice.ID}
+	require.NoError(t, repos.Posts.Create(other))
+
+	for i, author := range []*models.User{bob, alice, bob} {
+		comment := &models.Comment{Content: []string{"一", "二", "三"}[i], UserID: author.ID, PostID: post.ID}
+		require.NoError(t, repos.Comments.Create(comment))
+		assert.Equal(t, author.Username, comment.User.Username, "创建后填充作者")
+	}
+	require.NoError(t, repos.Comments.Create(&models.Comment{Content: "四", UserID: bob.ID, PostID: other.ID}))
+
+	err := repos.Comments.Create(&models.Comment{Content: "五", UserID: bob.ID, PostID: 42})
+	assert.ErrorIs(t, err, ErrNotFound, "文章不存在")
+
+	comments, total, err := repos.Comments.ListByPost(post.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, comments, 2)
+	assert.Equal(t, "二", comments[0].Content, "按创建时间排列")
+	assert.Equal(t, "alice", comments[0].User.Username)
+	assert.Equal(t, "三", comments[1].Content)
+
+	withComments, err := repos.Posts.FindWithComments(post.ID)
+	require.NoError(t, err)
+	require.Len(t, withComments.Comments, 3)
+	assert.Equal(t, "一", withComments.Comments[0].Content)
+	assert.Equal(t, "bob", withComments.Comments[0].User.Username)
+	assert.Equal(t, "alice", withComments.User.Username)
+
+	require.NoError(t, repos.Posts.Delete(post.ID))
+	comments, total, err = repos.Comments.ListByPost(post.ID, 0, 10)
+	require.NoError(t, err)
+	assert.Zero(t, total, "删除文章时删除评论")
+	assert.Empty(t, comments)
+
+	_, total, err = repos.Comments.ListByPost(other.ID, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total, "其他文章的评论不受影响")
+}
+
+// refreshToken 保存测试用的 refresh token
+func refreshToken(t *testing.T, repos Repositories, userID uint, family, hash string, ttl time.Duration) *models.RefreshToken {
+	t.Helper()
+	token := &models.RefreshToken{UserID: userID, FamilyID: family, TokenHash: hash, ExpiresAt: time.Now().Add(ttl)}
+	require.NoError(t, repos.Tokens.CreateRefreshToken(token))
+	return token
+}
+
+// revoked 返回 access token 是否已失效
+func revoked(t *testing.T, repos Repositories, jti, family string) bool {
+	t.Helper()
+	ok, err := repos.Tokens.IsAccessTokenRevoked(jti, family)
+	require.NoError(t, err)
+	return ok
+}
+
+func testTokens(t *testing.T, repos Repositories) {
+	alice := fixture.User(t, repos.Users, "alice")
+	bob := fixture.User(t, repos.Users, "bob")
+
+	phone := refreshToken(t, repos, alice.ID, "phone", "hash-1", time.Hour)
+	refreshToken(t, repos, alice.ID, "laptop", "hash-2", time.Hour)
+	refreshToken(t, repos, bob.ID, "bob", "hash-3", time.Hour)
+	refreshToken(t, repos, alice.ID, "old", "hash-4", -time.Hour)
+
+	assert.ErrorIs(t, repos.Tokens.CreateRefreshToken(&models.RefreshToken{UserID: alice.ID, FamilyID: "x", TokenHash: "hash-1", ExpiresAt: time.Now()}), ErrDuplicate)
+
+	found, err := repos.Tokens.FindRefreshToken("hash-1")
+	require.NoError(t, err)
+	assert.Equal(t, "phone", found.FamilyID)
+	assert.True(t, found.Active(time.Now()))
+	_, err = repos.Tokens.FindRefreshToken("missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.False(t, revoked(t, repos, "jti-1", "phone"), "会话有有效的 refresh token")
+	assert.True(t, revoked(t, repos, "jti-1", "old"), "会话的 refresh token 已过期")
+	assert.True(t, revoked(t, repos, "jti-1", "missing"), "会话不存在")
+
+	// 轮换：旧 token 撤销，新 token 属于同一会话
+	next := &models.RefreshToken{UserID: alice.ID, FamilyID: "phone", TokenHash: "hash-5", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, repos.Tokens.RotateRefreshToken(phone, next))
+	found, err = repos.Tokens.FindRefreshToken("hash-1")
+	require.NoError(t, err)
+	assert.NotNil(t, found.RevokedAt, "旧 token 已撤销")
+	assert.False(t, revoked(t, repos, "jti-1", "phone"), "会话仍然有效")
+	again := &models.RefreshToken{UserID: alice.ID, FamilyID: "phone", TokenHash: "hash-6", ExpiresAt: time.Now().Add(time.Hour)}
+	assert.ErrorIs(t, repos.Tokens.RotateRefreshToken(phone, again), ErrRevoked, "不能重复轮换")
+	_, err = repos.Tokens.FindRefreshToken("hash-6")
+	assert.ErrorIs(t, err, ErrNotFound, "轮换失败时不保存新 token")
+
+	// 撤销单个 access token
+	require.NoError(t, repos.Tokens.RevokeAccessToken("jti-1", time.Now().Add(time.Minute)))
+	require.NoError(t, repos.Tokens.RevokeAccessToken("jti-1", time.Now().Add(time.Minute)), "重复撤销")
+	assert.True(t, revoked(t, repos, "jti-1", "phone"))
+	assert.False(t, revoked(t, repos, "jti-2", "phone"), "同一会话的其他 token 不受影响")
+
+	// 撤销会话
+	require.NoError(t, repos.Tokens.RevokeFamily("phone"))
+	assert.True(t, revoked(t, repos, "jti-2", "phone"))
+	assert.False(t, revoked(t, repos, "jti-2", "laptop"), "其他会话不受影响")
+
+	// 撤销用户的所有 token
+	require.NoError(t, repos.Tokens.RevokeUserTokens(alice.ID))
+	assert.True(t, revoked(t, repos, "jti-2", "laptop"))
+	assert.False(t, revoked(t, repos, "jti-3", "bob"), "其他用户不受影响")
+
+	// 清理过期记录
+	require.NoError(t, repos.Tokens.RevokeAccessToken("jti-old", time.Now().Add(-time.Minute)))
+	require.NoError(t, repos.Tokens.DeleteExpired(time.Now()))
+	_, err = repos.Tokens.FindRefreshToken("hash-4")
+	assert.ErrorIs(t, err, ErrNotFound, "删除过期的 refresh token")
+	_, err = repos.Tokens.FindRefreshToken("hash-2")
+	assert.NoError(t, err, "保留未过期的 refresh token")
+	assert.True(t, revoked(t, repos, "jti-1", "bob"), "保留未过期的撤销记录")
+	assert.False(t, revoked(t, repos, "jti-old", "bob"), "删除过期的撤销记录")
+}
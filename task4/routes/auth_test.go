@@ -0,0 +1,181 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"task4/internal/apitest"
+	"task4/models"
+	"task4/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createPost 用 token 创建文章，返回响应
+func createPost(s *apitest.Server, token string) *apitest.Response {
+	return s.Do("POST", "/api/v1/posts", map[string]string{"title": "标题", "content": "内容"}, token)
+}
+
+// TestRefreshFlow 测试用 refresh token 续期，旧的 refresh token 被重复使用时整个会话失效
+func TestRefreshFlow(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	stolen := *alice
+
+	resp := s.Refresh(alice)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body)
+	assert.NotEqual(t, stolen.RefreshToken, alice.RefreshToken, "refresh token 每次刷新都会轮换")
+	assert.NotEqual(t, stolen.Token, alice.Token)
+	var body struct {
+		TokenType string `json:"token_type"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	resp.JSON(&body)
+	assert.Equal(t, "Bearer", body.TokenType)
+	assert.Equal(t, int(apitest.TokenTTL.Seconds()), body.ExpiresIn)
+
+	assert.Equal(t, http.StatusCreated, createPost(s, alice.Token).Code, "新的 access token 可用")
+	assert.Equal(t, http.StatusCreated, createPost(s, stolen.Token).Code, "会话有效时旧的 access token 在过期前仍可用")
+
+	// 旧的 refresh token 被再次使用（例如被盗），整个会话都被撤销
+	resp = s.Refresh(&stolen)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "刷新令牌已失效，请重新登录", resp.Error())
+
+	resp = createPost(s, alice.Token)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "Token has been revoked", resp.Error())
+	assert.Equal(t, http.StatusUnauthorized, s.Refresh(alice).Code, "轮换出的 refresh token 也失效")
+
+	// 重新登录开始新的会话
+	alice = s.Login("alice", apitest.Password)
+	assert.Equal(t, http.StatusCreated, createPost(s, alice.Token).Code)
+}
+
+// TestRefreshErrors 测试无效的刷新请求
+func TestRefreshErrors(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+
+	// 直接写入一个已过期的 refresh token
+	expired, hash, err := utils.NewRefreshToken()
+	require.NoError(t, err)
+	require.NoError(t, s.DB.Create(&models.RefreshToken{
+		UserID: alice.ID, FamilyID: "expired", TokenHash: hash, ExpiresAt: time.Now().Add(-time.Second),
+	}).Error)
+
+	tests := []struct {
+		body     interface{}
+		status   int
+		expected string
+		desc     string
+	}{
+		{map[string]string{"refresh_token": "unknown"}, http.StatusUnauthorized, "无效的刷新令牌", "未知的 token"},
+		{map[string]string{"refresh_token": alice.Token}, http.StatusUnauthorized, "无效的刷新令牌", "用 access token 刷新"},
+		{map[string]string{"refresh_token": expired}, http.StatusUnauthorized, "刷新令牌已过期，请重新登录", "过期的 token"},
+		{map[string]string{}, http.StatusBadRequest, "参数验证失败", "缺少 token"},
+		{`{"refresh_token":`, http.StatusBadRequest, "参数验证失败", "无效的 JSON"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			resp := s.Do("POST", "/api/v1/auth/refresh", test.body, "")
+			assert.Equal(t, test.status, resp.Code)
+			assert.Equal(t, test.expected, resp.Error())
+		})
+	}
+}
+
+// TestExpiredAccessToken 测试 access token 过期后用 refresh token 续期
+func TestExpiredAccessToken(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	claims, err := utils.ParseToken(alice.Token)
+	require.NoError(t, err)
+
+	// 同一会话中已过期的 access token
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Second))
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(apitest.Secret))
+	require.NoError(t, err)
+	resp := createPost(s, expired)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "Invalid token", resp.Error())
+
+	require.Equal(t, http.StatusOK, s.Refresh(alice).Code)
+	assert.Equal(t, http.StatusCreated, createPost(s, alice.Token).Code)
+}
+
+// TestLogoutFlow 测试注销当前会话，同一用户的其他会话不受影响
+func TestLogoutFlow(t *testing.T) {
+	s := apitest.NewServer(t)
+	phone := s.Register("alice")
+	laptop := s.Login("alice", apitest.Password)
+
+	resp := s.Do("POST", "/api/v1/auth/logout", nil, phone.Token)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body)
+
+	claims, err := utils.ParseToken(phone.Token)
+	require.NoError(t, err)
+	var revoked models.RevokedToken
+	require.NoError(t, s.DB.First(&revoked, "jti = ?", claims.ID).Error, "jti 写入撤销列表")
+	assert.WithinDuration(t, claims.ExpiresAt.Time, revoked.ExpiresAt, time.Second)
+
+	resp = createPost(s, phone.Token)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "Token has been revoked", resp.Error())
+	assert.Equal(t, http.StatusUnauthorized, s.Refresh(phone).Code, "会话的 refresh token 失效")
+	assert.Equal(t, http.StatusUnauthorized, s.Do("POST", "/api/v1/auth/logout", nil, phone.Token).Code, "不能重复注销")
+
+	assert.Equal(t, http.StatusCreated, createPost(s, laptop.Token).Code, "其他会话不受影响")
+	assert.Equal(t, http.StatusOK, s.Refresh(laptop).Code)
+
+	resp = s.Do("POST", "/api/v1/auth/logout", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "Authorization header is required", resp.Error())
+}
+
+// TestLogoutAllFlow 测试注销所有设备
+func TestLogoutAllFlow(t *testing.T) {
+	s := apitest.NewServer(t)
+	phone := s.Register("alice")
+	laptop := s.Login("alice", apitest.Password)
+	tablet := s.Login("alice", apitest.Password)
+	bob := s.Register("bob")
+
+	resp := s.Do("POST", "/api/v1/auth/logout-all", nil, phone.Token)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body)
+
+	for name, device := range map[string]*apitest.User{"phone": phone, "laptop": laptop, "tablet": tablet} {
+		assert.Equal(t, http.StatusUnauthorized, createPost(s, device.Token).Code, name)
+		assert.Equal(t, http.StatusUnauthorized, s.Refresh(device).Code, name)
+	}
+
+	var active int64
+	require.NoError(t, s.DB.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", phone.ID).Count(&active).Error)
+	assert.Zero(t, active, "用户的 refresh token 全部撤销")
+
+	assert.Equal(t, http.StatusCreated, createPost(s, bob.Token).Code, "其他用户不受影响")
+	assert.Equal(t, http.StatusOK, s.Refresh(bob).Code)
+
+	phone = s.Login("alice", apitest.Password)
+	assert.Equal(t, http.StatusCreated, createPost(s, phone.Token).Code, "重新登录后可用")
+}
+
+// TestRevokeStolenToken 测试按 jti 撤销单个被盗的 access token，同一会话的其他 token 不受影响
+func TestRevokeStolenToken(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	stolen := alice.Token
+	require.Equal(t, http.StatusOK, s.Refresh(alice).Code)
+
+	claims, err := utils.ParseToken(stolen)
+	require.NoError(t, err)
+	require.NoError(t, s.DB.Create(&models.RevokedToken{JTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time}).Error)
+
+	resp := createPost(s, stolen)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "Token has been revoked", resp.Error())
+	assert.Equal(t, http.StatusCreated, createPost(s, alice.Token).Code, "同一会话的新 token 可用")
+}
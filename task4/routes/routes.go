@@ -3,41 +3,39 @@ package routes
 import (
 	"task4/controllers"
 	"task4/middleware"
+	"task4/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes 设置路由
-func SetupRoutes() *gin.Engine {
+// SetupRoutes 设置路由，repos 为控制器使用的仓库，corsOrigins 为允许跨域访问的来源
+func SetupRoutes(repos repository.Repositories, corsOrigins []string) *gin.Engine {
 	r := gin.Default()
 
 	// 添加CORS中间件
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-
-		c.Next()
-	})
+	r.Use(middleware.CORSMiddleware(corsOrigins))
 
 	// 初始化控制器
-	authController := &controllers.AuthController{}
-	postController := &controllers.PostController{}
-	commentController := &controllers.CommentController{}
+	authController := controllers.NewAuthController(repos.Users, repos.Tokens)
+	postController := controllers.NewPostController(repos.Posts)
+	commentController := controllers.NewCommentController(repos.Comments, repos.Posts)
+
+	// 认证中间件，检查签名和撤销列表
+	authRequired := middleware.AuthMiddleware(repos.Tokens)
 
 	// API v1 路由组
 	v1 := r.Group("/api/v1")
 	{
-		// 认证相关路由（无需token）
+		// 认证相关路由
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/register", authController.Register)
 			auth.POST("/login", authController.Login)
+			auth.POST("/refresh", authController.Refresh) // 用 refresh token 换取新的 token
+
+			// 需要认证的路由
+			auth.POST("/logout", authRequired, authController.Logout)        // 注销当前会话
+			auth.POST("/logout-all", authRequired, authController.LogoutAll) // 注销所有设备
 		}
 
 		// 文章相关路由
@@ -48,9 +46,9 @@ func SetupRoutes() *gin.Engine {
 			posts.GET("/:id", postController.GetPost) // 获取单个文章
 
 			// 需要认证的路由
-			posts.POST("", middleware.AuthMiddleware(), postController.CreatePost)       // 创建文章
-			posts.PUT("/:id", middleware.AuthMiddleware(), postController.UpdatePost)    // 更新文章
-			posts.DELETE("/:id", middleware.AuthMiddleware(), postController.DeletePost) // 删除文章
+			posts.POST("", authRequired, postController.CreatePost)       // 创建文章
+			posts.PUT("/:id", authRequired, postController.UpdatePost)    // 更新文章
+			posts.DELETE("/:id", authRequired, postController.DeletePost) // 删除文章
 		}
 
 		// 评论相关路由
@@ -60,7 +58,7 @@ func SetupRoutes() *gin.Engine {
 			comments.GET("/post/:post_id", commentController.GetCommentsByPost) // 获取文章评论
 
 			// 需要认证的路由
-			comments.POST("", middleware.AuthMiddleware(), commentController.CreateComment) // 创建评论
+			comments.POST("", authRequired, commentController.CreateComment) // 创建评论
 		}
 	}
 
@@ -0,0 +1,391 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"task4/internal/apitest"
+	"task4/models"
+	"task4/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signToken 用指定的密钥和签名算法签发 token
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, userID uint, expiresAt time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, utils.Claims{
+		UserID:   userID,
+		Username: "alice",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+// TestHealth 测试健康检查
+func TestHealth(t *testing.T) {
+	s := apitest.NewServer(t)
+	resp := s.Do("GET", "/health", nil, "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"status":"ok","message":"Blog API is running"}`, resp.Body)
+}
+
+// TestAuthFlow 测试注册、登录并使用 token 访问需要认证的接口
+func TestAuthFlow(t *testing.T) {
+	s := apitest.NewServer(t)
+
+	resp := s.Do("POST", "/api/v1/auth/register", map[string]string{
+		"username": "alice",
+		"password": apitest.Password,
+		"email":    "alice@example.com",
+	}, "")
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body)
+	assert.NotContains(t, resp.Body, apitest.Password, "响应中不包含密码")
+
+	var stored models.User
+	require.NoError(t, s.DB.Where("username = ?", "alice").First(&stored).Error)
+	assert.NotEqual(t, apitest.Password, stored.Password, "数据库中保存密码的哈希")
+
+	alice := s.Login("alice", apitest.Password)
+	assert.Equal(t, stored.ID, alice.ID)
+	assert.Equal(t, "alice@example.com", alice.Email)
+
+	claims, err := utils.ParseToken(alice.Token)
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, claims.UserID)
+	assert.Equal(t, "alice", claims.Username)
+	assert.WithinDuration(t, time.Now().Add(apitest.TokenTTL), claims.ExpiresAt.Time, time.Minute)
+
+	// token 中的用户成为文章作者
+	postID := s.CreatePost(alice, "标题", "内容")
+	var post models.Post
+	require.NoError(t, s.DB.First(&post, postID).Error)
+	assert.Equal(t, alice.ID, post.UserID)
+
+	tests := []struct {
+		body     map[string]string
+		status   int
+		expected string
+		desc     string
+	}{
+		{map[string]string{"username": "alice", "password": "wrong-password"}, http.StatusUnauthorized, "用户名或密码错误", "密码错误"},
+		{map[string]string{"username": "nobody", "password": apitest.Password}, http.StatusUnauthorized, "用户名或密码错误", "用户不存在"},
+		{map[string]string{"username": "alice"}, http.StatusBadRequest, "参数验证失败", "缺少密码"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			resp := s.Do("POST", "/api/v1/auth/login", test.body, "")
+			assert.Equal(t, test.status, resp.Code)
+			assert.Equal(t, test.expected, resp.Error())
+		})
+	}
+}
+
+// TestRegisterErrors 测试注册的参数校验和重复注册
+func TestRegisterErrors(t *testing.T) {
+	s := apitest.NewServer(t)
+	s.Register("alice")
+
+	tests := []struct {
+		body     interface{}
+		status   int
+		expected string
+		desc     string
+	}{
+		{map[string]string{"username": "alice", "password": apitest.Password, "email": "new@example.com"}, http.StatusConflict, "用户名或邮箱已存在", "用户名已存在"},
+		{map[string]string{"username": "bob", "password": apitest.Password, "email": "alice@example.com"}, http.StatusConflict, "用户名或邮箱已存在", "邮箱已存在"},
+		{map[string]string{"username": "bo", "password": apitest.Password, "email": "bob@example.com"}, http.StatusBadRequest, "参数验证失败", "用户名太短"},
+		{map[string]string{"username": "bob", "password": "12345", "email": "bob@example.com"}, http.StatusBadRequest, "参数验证失败", "密码太短"},
+		{map[string]string{"username": "bob", "password": apitest.Password, "email": "bob"}, http.StatusBadRequest, "参数验证失败", "邮箱格式错误"},
+		{`{"username":`, http.StatusBadRequest, "参数验证失败", "无效的 JSON"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			resp := s.Do("POST", "/api/v1/auth/register", test.body, "")
+			assert.Equal(t, test.status, resp.Code)
+			assert.Equal(t, test.expected, resp.Error())
+		})
+	}
+}
+
+// TestAuthMiddleware 测试需要认证的接口拒绝缺失、格式错误、过期和伪造的 token
+func TestAuthMiddleware(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	body := map[string]string{"title": "标题", "content": "内容"}
+
+	tests := []struct {
+		header   string
+		status   int
+		expected string
+		desc     string
+	}{
+		{"Bearer " + alice.Token, http.StatusCreated, "", "有效的 token"},
+		{"", http.StatusUnauthorized, "Authorization header is required", "缺少认证头"},
+		{alice.Token, http.StatusUnauthorized, "Authorization header format must be Bearer {token}", "缺少 Bearer 前缀"},
+		{"Basic " + alice.Token, http.StatusUnauthorized, "Authorization header format must be Bearer {token}", "错误的认证方式"},
+		{"Bearer not-a-jwt", http.StatusUnauthorized, "Invalid token", "格式错误的 token"},
+		{"Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(apitest.Secret), alice.ID, time.Now().Add(-time.Minute)), http.StatusUnauthorized, "Invalid token", "过期的 token"},
+		{"Bearer " + signToken(t, jwt.SigningMethodHS256, []byte("another-secret"), alice.ID, time.Now().Add(time.Hour)), http.StatusUnauthorized, "Invalid token", "其他密钥签发的 token"},
+		{"Bearer " + signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, alice.ID, time.Now().Add(time.Hour)), http.StatusUnauthorized, "Invalid token", "未签名的 token"},
+		{"Bearer " + alice.Token + "x", http.StatusUnauthorized, "Invalid token", "篡改的签名"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			headers := map[string]string{}
+			if test.header != "" {
+				headers["Authorization"] = test.header
+			}
+			resp := s.DoWithHeaders("POST", "/api/v1/posts", body, headers)
+			assert.Equal(t, test.status, resp.Code, resp.Body)
+			if test.expected != "" {
+				assert.Equal(t, test.expected, resp.Error())
+			}
+		})
+	}
+}
+
+// TestPostCRUD 测试文章的增删改查，只有作者可以修改和删除
+func TestPostCRUD(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	bob := s.Register("bob")
+
+	postID := s.CreatePost(alice, "原标题", "原内容")
+	path := fmt.Sprintf("/api/v1/posts/%d", postID)
+
+	var got struct {
+		Post models.Post `json:"post"`
+	}
+	resp := s.Do("GET", path, nil, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	resp.JSON(&got)
+	assert.Equal(t, "原标题", got.Post.Title)
+	assert.Equal(t, "alice", got.Post.User.Username, "包含作者信息")
+
+	update := map[string]string{"title": "新标题", "content": "新内容"}
+	steps := []struct {
+		method   string
+		body     interface{}
+		user     *apitest.User
+		status   int
+		expected string
+		desc     string
+	}{
+		{"PUT", update, bob, http.StatusForbidden, "只有文章作者才能更新文章", "其他用户不能更新"},
+		{"PUT", update, nil, http.StatusUnauthorized, "Authorization header is required", "未登录不能更新"},
+		{"PUT", map[string]string{"title": "新标题"}, alice, http.StatusBadRequest, "参数验证失败", "缺少内容"},
+		{"PUT", update, alice, http.StatusOK, "", "作者更新"},
+		{"DELETE", nil, bob, http.StatusForbidden, "只有文章作者才能删除文章", "其他用户不能删除"},
+		{"DELETE", nil, nil, http.StatusUnauthorized, "Authorization header is required", "未登录不能删除"},
+		{"DELETE", nil, alice, http.StatusOK, "", "作者删除"},
+		{"DELETE", nil, alice, http.StatusNotFound, "文章不存在", "重复删除"},
+		{"PUT", update, alice, http.StatusNotFound, "文章不存在", "更新已删除的文章"},
+	}
+	for _, step := range steps {
+		token := ""
+		if step.user != nil {
+			token = step.user.Token
+		}
+		resp := s.Do(step.method, path, step.body, token)
+		require.Equal(t, step.status, resp.Code, "%s: %s", step.desc, resp.Body)
+		if step.expected != "" {
+			assert.Equal(t, step.expected, resp.Error(), step.desc)
+		}
+
+		if step.desc == "作者更新" {
+			resp = s.Do("GET", path, nil, "")
+			resp.JSON(&got)
+			assert.Equal(t, "新标题", got.Post.Title)
+			assert.Equal(t, "新内容", got.Post.Content)
+			assert.Equal(t, alice.ID, got.Post.UserID, "作者不变")
+		}
+	}
+
+	assert.Equal(t, http.StatusNotFound, s.Do("GET", path, nil, "").Code)
+}
+
+// TestComments 测试评论的创建和列表，删除文章时评论一起删除
+func TestComments(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	bob := s.Register("bob")
+	postID := s.CreatePost(alice, "标题", "内容")
+	otherID := s.CreatePost(bob, "另一篇", "内容")
+
+	s.CreateComment(bob, postID, "沙发")
+	s.CreateComment(alice, postID, "谢谢")
+	s.CreateComment(bob, otherID, "自己的文章")
+
+	tests := []struct {
+		body     interface{}
+		user     *apitest.User
+		status   int
+		expected string
+		desc     string
+	}{
+		{map[string]interface{}{"content": "评论", "post_id": 999}, bob, http.StatusNotFound, "文章不存在", "文章不存在"},
+		{map[string]interface{}{"content": "", "post_id": postID}, bob, http.StatusBadRequest, "参数验证失败", "内容为空"},
+		{map[string]interface{}{"content": "评论"}, bob, http.StatusBadRequest, "参数验证失败", "缺少文章"},
+		{map[string]interface{}{"content": "评论", "post_id": postID}, nil, http.StatusUnauthorized, "Authorization header is required", "未登录"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			token := ""
+			if test.user != nil {
+				token = test.user.Token
+			}
+			resp := s.Do("POST", "/api/v1/comments", test.body, token)
+			assert.Equal(t, test.status, resp.Code)
+			assert.Equal(t, test.expected, resp.Error())
+		})
+	}
+
+	var list struct {
+		Comments   []models.Comment `json:"comments"`
+		Pagination struct {
+			Total int64 `json:"total"`
+		} `json:"pagination"`
+	}
+	resp := s.Do("GET", fmt.Sprintf("/api/v1/comments/post/%d", postID), nil, "")
+	require.Equal(t, http.StatusOK, resp.Code)
+	resp.JSON(&list)
+	require.Len(t, list.Comments, 2)
+	assert.Equal(t, int64(2), list.Pagination.Total)
+	assert.Equal(t, "沙发", list.Comments[0].Content, "按时间排列")
+	assert.Equal(t, "bob", list.Comments[0].User.Username, "包含作者信息")
+	assert.Equal(t, "谢谢", list.Comments[1].Content)
+
+	var detail struct {
+		Post models.Post `json:"post"`
+	}
+	resp = s.Do("GET", fmt.Sprintf("/api/v1/posts/%d", postID), nil, "")
+	resp.JSON(&detail)
+	require.Len(t, detail.Post.Comments, 2, "文章详情包含评论")
+	assert.Equal(t, "bob", detail.Post.Comments[0].User.Username)
+
+	require.Equal(t, http.StatusOK, s.Do("DELETE", fmt.Sprintf("/api/v1/posts/%d", postID), nil, alice.Token).Code)
+	var count int64
+	require.NoError(t, s.DB.Model(&models.Comment{}).Where("post_id = ?", postID).Count(&count).Error)
+	assert.Zero(t, count, "删除文章时删除评论")
+	require.NoError(t, s.DB.Model(&models.Comment{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count, "其他文章的评论保留")
+	assert.Equal(t, http.StatusNotFound, s.Do("GET", fmt.Sprintf("/api/v1/comments/post/%d", postID), nil, "").Code)
+}
+
+// TestPagination 测试文章和评论列表的分页
+func TestPagination(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	ids := s.CreatePosts(alice, 25)
+	for i := 1; i <= 25; i++ {
+		s.CreateComment(alice, ids[0], fmt.Sprintf("评论 %d", i))
+	}
+
+	type page struct {
+		Posts      []models.Post    `json:"posts"`
+		Comments   []models.Comment `json:"comments"`
+		Pagination struct {
+			Page     int   `json:"page"`
+			PageSize int   `json:"page_size"`
+			Total    int64 `json:"total"`
+		} `json:"pagination"`
+	}
+	tests := []struct {
+		path     string
+		page     int
+		pageSize int
+		first    string
+		count    int
+		desc     string
+	}{
+		{"/api/v1/posts", 1, 10, "文章 25", 10, "文章默认每页 10 条，新的在前"},
+		{"/api/v1/posts?page=3", 3, 10, "文章 5", 5, "文章最后一页"},
+		{"/api/v1/posts?page=2&page_size=7", 2, 7, "文章 18", 7, "文章指定每页条数"},
+		{"/api/v1/posts?page=4&page_size=10", 4, 10, "", 0, "文章超出范围"},
+		{"/api/v1/posts?page=0&page_size=abc", 1, 10, "文章 25", 10, "无效的分页参数"},
+		{"/api/v1/posts?page_size=500", 1, 100, "文章 25", 25, "每页最多 100 条"},
+		{fmt.Sprintf("/api/v1/comments/post/%d", ids[0]), 1, 20, "评论 1", 20, "评论默认每页 20 条，按时间排列"},
+		{fmt.Sprintf("/api/v1/comments/post/%d?page=2", ids[0]), 2, 20, "评论 21", 5, "评论最后一页"},
+		{fmt.Sprintf("/api/v1/comments/post/%d?page=3&page_size=5", ids[0]), 3, 5, "评论 11", 5, "评论指定每页条数"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			resp := s.Do("GET", test.path, nil, "")
+			require.Equal(t, http.StatusOK, resp.Code, resp.Body)
+			var p page
+			resp.JSON(&p)
+			assert.Equal(t, test.page, p.Pagination.Page)
+			assert.Equal(t, test.pageSize, p.Pagination.PageSize)
+			assert.Equal(t, int64(25), p.Pagination.Total)
+
+			var first string
+			count := len(p.Posts) + len(p.Comments)
+			if len(p.Posts) > 0 {
+				first = p.Posts[0].Title
+			}
+			if len(p.Comments) > 0 {
+				first = p.Comments[0].Content
+			}
+			assert.Equal(t, test.count, count)
+			assert.Equal(t, test.first, first)
+		})
+	}
+}
+
+// TestErrorCodes 测试各接口的错误状态码和错误信息
+func TestErrorCodes(t *testing.T) {
+	s := apitest.NewServer(t)
+	alice := s.Register("alice")
+	postID := s.CreatePost(alice, "标题", "内容")
+	post := map[string]string{"title": "标题", "content": "内容"}
+
+	tests := []struct {
+		method   string
+		path     string
+		body     interface{}
+		token    string
+		status   int
+		expected string
+		desc     string
+	}{
+		{"GET", "/api/v1/posts/abc", nil, "", http.StatusBadRequest, "无效的文章ID", "文章 ID 不是数字"},
+		{"GET", "/api/v1/posts/-1", nil, "", http.StatusBadRequest, "无效的文章ID", "文章 ID 为负数"},
+		{"GET", "/api/v1/posts/999", nil, "", http.StatusNotFound, "文章不存在", "文章不存在"},
+		{"PUT", "/api/v1/posts/abc", post, alice.Token, http.StatusBadRequest, "无效的文章ID", "更新时文章 ID 无效"},
+		{"DELETE", "/api/v1/posts/abc", nil, alice.Token, http.StatusBadRequest, "无效的文章ID", "删除时文章 ID 无效"},
+		{"POST", "/api/v1/posts", `{"title":`, alice.Token, http.StatusBadRequest, "参数验证失败", "无效的 JSON"},
+		{"POST", "/api/v1/posts", map[string]string{"content": "内容"}, alice.Token, http.StatusBadRequest, "参数验证失败", "缺少标题"},
+		{"POST", "/api/v1/posts", post, "", http.StatusUnauthorized, "Authorization header is required", "创建文章需要登录"},
+		{"POST", "/api/v1/comments", map[string]interface{}{"content": "评论", "post_id": postID}, "", http.StatusUnauthorized, "Authorization header is required", "创建评论需要登录"},
+		{"GET", "/api/v1/comments/post/abc", nil, "", http.StatusBadRequest, "无效的文章ID", "评论列表的文章 ID 无效"},
+		{"GET", "/api/v1/comments/post/999", nil, "", http.StatusNotFound, "文章不存在", "评论列表的文章不存在"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			resp := s.Do(test.method, test.path, test.body, test.token)
+			assert.Equal(t, test.status, resp.Code, resp.Body)
+			assert.Equal(t, test.expected, resp.Error())
+		})
+	}
+
+	resp := s.Do("GET", "/api/v1/missing", nil, "")
+	assert.Equal(t, http.StatusNotFound, resp.Code, "未知的路径")
+}
+
+// TestCORS 测试跨域预检请求
+func TestCORS(t *testing.T) {
+	s := apitest.NewServer(t)
+	resp := s.DoWithHeaders("OPTIONS", "/api/v1/posts", nil, map[string]string{"Origin": "https://example.com"})
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "Authorization")
+}
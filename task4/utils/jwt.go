@@ -1,30 +1,69 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte("your-secret-key-here") // 在生产环境中应该使用环境变量
+var (
+	jwtSecret       []byte
+	tokenTTL        = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// InitJWT 设置签名密钥、access token 和 refresh token 的有效期，启动时由配置调用
+func InitJWT(secret string, ttl, refreshTTL time.Duration) {
+	jwtSecret = []byte(secret)
+	tokenTTL = ttl
+	refreshTokenTTL = refreshTTL
+}
+
+// TokenTTL 返回 access token 的有效期
+func TokenTTL() time.Duration {
+	return tokenTTL
+}
+
+// RefreshTokenTTL 返回 refresh token 的有效期
+func RefreshTokenTTL() time.Duration {
+	return refreshTokenTTL
+}
 
 // Claims JWT载荷
+//
+// RegisteredClaims.ID 为 token 的唯一标识（jti），用于撤销单个 token；
+// SessionID 为登录会话，即同一次登录轮换出的 refresh token 所属的系列，会话注销后其 access token 一并失效。
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID    uint   `json:"user_id"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID uint, username string) (string, error) {
+// GenerateToken 为登录会话生成 access token
+func GenerateToken(userID uint, username, sessionID string) (string, error) {
+	if len(jwtSecret) == 0 {
+		return "", errors.New("jwt secret is not configured")
+	}
+	jti, err := RandomID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:    userID,
+		Username:  username,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24小时过期
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
@@ -32,19 +71,44 @@ func GenerateToken(userID uint, username string) (string, error) {
 	return token.SignedString(jwtSecret)
 }
 
-// ParseToken 解析JWT token
+// ParseToken 解析JWT token，只接受 HS256 签名且带有 jti 和会话的 token
 func ParseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.ID != "" && claims.SessionID != "" {
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
+
+// RandomID 返回 128 位随机数的十六进制形式，用作 jti 和会话 ID
+func RandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewRefreshToken 生成 refresh token，返回交给客户端的 token 和保存在服务端的哈希
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken 返回 refresh token 的 SHA-256 哈希，数据库中只保存哈希，泄露后也无法直接使用
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateToken 测试签发的 access token 带有 jti 和会话，每次签发的 jti 不同
+func TestGenerateToken(t *testing.T) {
+	InitJWT("utils-test-secret", 15*time.Minute, time.Hour)
+	assert.Equal(t, 15*time.Minute, TokenTTL())
+	assert.Equal(t, time.Hour, RefreshTokenTTL())
+
+	first, err := GenerateToken(1, "alice", "session")
+	require.NoError(t, err)
+	second, err := GenerateToken(1, "alice", "session")
+	require.NoError(t, err)
+
+	a, err := ParseToken(first)
+	require.NoError(t, err)
+	b, err := ParseToken(second)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), a.UserID)
+	assert.Equal(t, "session", a.SessionID)
+	assert.Len(t, a.ID, 32)
+	assert.NotEqual(t, a.ID, b.ID)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), a.ExpiresAt.Time, time.Minute)
+}
+
+// TestParseTokenRejects 测试拒绝缺少 jti 或会话、签名算法不符的 token
+func TestParseTokenRejects(t *testing.T) {
+	InitJWT("utils-test-secret", 15*time.Minute, time.Hour)
+	sign := func(method jwt.SigningMethod, key interface{}, claims Claims) string {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return token
+	}
+	secret := []byte("utils-test-secret")
+
+	tests := []struct {
+		token string
+		desc  string
+	}{
+		{sign(jwt.SigningMethodHS256, secret, Claims{UserID: 1, RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}}), "缺少会话"},
+		{sign(jwt.SigningMethodHS256, secret, Claims{UserID: 1, SessionID: "session"}), "缺少 jti"},
+		{sign(jwt.SigningMethodHS512, secret, Claims{UserID: 1, SessionID: "session", RegisteredClaims: jwt.RegisteredClaims{ID: "jti"}}), "其他签名算法"},
+		{"not-a-jwt", "格式错误"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := ParseToken(test.token)
+			assert.Error(t, err)
+		})
+	}
+
+	InitJWT("", time.Minute, time.Hour)
+	_, err := GenerateToken(1, "alice", "session")
+	assert.EqualError(t, err, "jwt secret is not configured")
+}
+
+// TestNewRefreshToken 测试 refresh token 的生成和哈希
+func TestNewRefreshToken(t *testing.T) {
+	token, hash, err := NewRefreshToken()
+	require.NoError(t, err)
+	assert.Len(t, token, 43, "32 字节的 base64url 编码")
+	assert.Equal(t, hash, HashToken(token))
+	assert.Len(t, hash, 64)
+
+	other, otherHash, err := NewRefreshToken()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+	assert.NotEqual(t, hash, otherHash)
+}